@@ -0,0 +1,128 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The types of the actions recorded in an ActionLog.
+const (
+	// ActionNavigate is a navigation with WebDriver.Get.
+	ActionNavigate = "navigate"
+	// ActionClick is a click with WebElement.Click.
+	ActionClick = "click"
+	// ActionSendKeys is typing with WebElement.SendKeys.
+	ActionSendKeys = "sendKeys"
+)
+
+// ElementLocator is a step of the locator of an element: the element is the
+// one at Index among those found by the strategy By and Value.
+type ElementLocator struct {
+	By    string `json:"by"`
+	Value string `json:"value"`
+	Index int    `json:"index"`
+}
+
+// documentLocator is the locator of the document, within which elements are
+// found by WebDriver.FindElement. It is empty but, unlike an unknown locator,
+// not nil.
+var documentLocator = []ElementLocator{}
+
+// locate returns the locator of the element at index i among those found by
+// the strategy by and value within the element with the locator parent. It
+// returns nil if parent is nil, i.e. unknown.
+func locate(parent []ElementLocator, by, value string, i int) []ElementLocator {
+	if parent == nil || by == "" {
+		return nil
+	}
+	locator := make([]ElementLocator, len(parent), len(parent)+1)
+	copy(locator, parent)
+	return append(locator, ElementLocator{By: by, Value: value, Index: i})
+}
+
+// LoggedAction is an action recorded in an ActionLog.
+type LoggedAction struct {
+	// Type is one of ActionNavigate, ActionClick or ActionSendKeys.
+	Type string `json:"type"`
+	// URL is the URL navigated to, for ActionNavigate.
+	URL string `json:"url,omitempty"`
+	// Element is how the element interacted with was found, from the
+	// document of the current frame, as a chain of FindElement(s) calls. It
+	// is empty if the element was not found by a locator, e.g. because it was
+	// returned by a script, in which case the action cannot be replayed.
+	Element []ElementLocator `json:"element,omitempty"`
+	// Keys are the keys sent, for ActionSendKeys.
+	Keys string `json:"keys,omitempty"`
+}
+
+// ActionLog is a sequence of navigations and element interactions, recorded
+// with WebDriver.SetActionLog and re-executed with WebDriver.Replay. It can be
+// saved and loaded as JSON, e.g. to reproduce a bug report.
+type ActionLog []LoggedAction
+
+func (wd *remoteWD) SetActionLog(log *ActionLog) {
+	wd.actionLog = log
+}
+
+// logAction appends the action to the action log, if one is set.
+func (wd *remoteWD) logAction(a LoggedAction) {
+	if wd.actionLog != nil {
+		*wd.actionLog = append(*wd.actionLog, a)
+	}
+}
+
+// findLocated finds the element with the provided locator again.
+func (wd *remoteWD) findLocated(locator []ElementLocator) (WebElement, error) {
+	if len(locator) == 0 {
+		return nil, errors.New("the element was not found by a locator")
+	}
+	var elem WebElement
+	for _, l := range locator {
+		var elems []WebElement
+		var err error
+		if elem == nil {
+			elems, err = wd.FindElements(l.By, l.Value)
+		} else {
+			elems, err = elem.FindElements(l.By, l.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.Index >= len(elems) {
+			return nil, fmt.Errorf("found %d elements by %s %q, want at least %d", len(elems), l.By, l.Value, l.Index+1)
+		}
+		elem = elems[l.Index]
+	}
+	return elem, nil
+}
+
+func (wd *remoteWD) Replay(log ActionLog) error {
+	// Do not record the replayed actions.
+	saved := wd.actionLog
+	wd.actionLog = nil
+	defer func() { wd.actionLog = saved }()
+
+	for i, a := range log {
+		var err error
+		switch a.Type {
+		case ActionNavigate:
+			err = wd.Get(a.URL)
+		case ActionClick, ActionSendKeys:
+			var elem WebElement
+			if elem, err = wd.findLocated(a.Element); err != nil {
+				break
+			}
+			if a.Type == ActionClick {
+				err = elem.Click()
+			} else {
+				err = elem.SendKeys(a.Keys)
+			}
+		default:
+			err = fmt.Errorf("unknown action type %q", a.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("replaying action %d (%s): %v", i+1, a.Type, err)
+		}
+	}
+	return nil
+}
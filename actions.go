@@ -0,0 +1,171 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Origins of pointer moves other than an element, for Actions.PointerMove.
+const (
+	// ViewportOrigin makes the coordinates relative to the top-left corner of
+	// the viewport.
+	ViewportOrigin = "viewport"
+	// PointerOrigin makes the coordinates relative to the current position of
+	// the pointer.
+	PointerOrigin = "pointer"
+)
+
+// The IDs of the input sources used by Actions and the legacy mouse and
+// keyboard methods.
+const (
+	mouseSourceID    = "default mouse"
+	keyboardSourceID = "default keyboard"
+)
+
+// inputSource is an input source of the W3C actions API, with its sequence of
+// actions.
+type inputSource struct {
+	Type       string                   `json:"type"`
+	ID         string                   `json:"id"`
+	Parameters map[string]string        `json:"parameters,omitempty"`
+	Actions    []map[string]interface{} `json:"actions"`
+}
+
+// Actions builds a sequence of low-level input actions, with a mouse and a
+// keyboard, that are performed together by Perform, e.g. to drag and drop or
+// to click with a modifier key held down. Create one with
+// WebDriver.NewActions. Each action takes one tick, during which the other
+// input source pauses, so the actions are performed in the order they were
+// added.
+//
+// Actions are only supported by W3C-compatible sessions.
+type Actions struct {
+	wd      *remoteWD
+	sources []*inputSource
+	ticks   int
+	err     error
+}
+
+func (wd *remoteWD) NewActions() *Actions {
+	return &Actions{wd: wd}
+}
+
+// source returns the input source with the provided ID, adding it if needed.
+func (a *Actions) source(id string) *inputSource {
+	for _, s := range a.sources {
+		if s.ID == id {
+			return s
+		}
+	}
+	s := &inputSource{ID: id, Type: "key"}
+	if id == mouseSourceID {
+		s.Type = "pointer"
+		s.Parameters = map[string]string{"pointerType": "mouse"}
+	}
+	// Catch up with the ticks that happened before the source was used.
+	for i := 0; i < a.ticks; i++ {
+		s.Actions = append(s.Actions, map[string]interface{}{"type": "pause", "duration": 0})
+	}
+	a.sources = append(a.sources, s)
+	return s
+}
+
+// add adds a tick in which the source with the provided ID performs action
+// while the other sources pause.
+func (a *Actions) add(id string, action map[string]interface{}) *Actions {
+	src := a.source(id)
+	for _, s := range a.sources {
+		if s == src {
+			s.Actions = append(s.Actions, action)
+		} else {
+			s.Actions = append(s.Actions, map[string]interface{}{"type": "pause", "duration": 0})
+		}
+	}
+	a.ticks++
+	return a
+}
+
+// KeyDown presses the key, which is a single character or one of the key
+// constants such as ShiftKey, and holds it down.
+func (a *Actions) KeyDown(key string) *Actions {
+	return a.add(keyboardSourceID, map[string]interface{}{"type": "keyDown", "value": key})
+}
+
+// KeyUp releases the key.
+func (a *Actions) KeyUp(key string) *Actions {
+	return a.add(keyboardSourceID, map[string]interface{}{"type": "keyUp", "value": key})
+}
+
+// PointerMove moves the mouse to the coordinates (x, y), over the duration d.
+// The coordinates are relative to origin, which is ViewportOrigin,
+// PointerOrigin or a WebElement, in which case they are relative to the
+// center of the element.
+func (a *Actions) PointerMove(origin interface{}, x, y int, d time.Duration) *Actions {
+	switch origin.(type) {
+	case string, WebElement:
+	default:
+		if a.err == nil {
+			a.err = fmt.Errorf("invalid pointer origin of type %T", origin)
+		}
+		return a
+	}
+	return a.add(mouseSourceID, map[string]interface{}{
+		"type":     "pointerMove",
+		"duration": int(d / time.Millisecond),
+		"origin":   origin,
+		"x":        x,
+		"y":        y,
+	})
+}
+
+// PointerDown presses a mouse button, which is one of LeftButton,
+// MiddleButton or RightButton, and holds it down.
+func (a *Actions) PointerDown(button int) *Actions {
+	return a.add(mouseSourceID, map[string]interface{}{"type": "pointerDown", "button": button})
+}
+
+// PointerUp releases a mouse button.
+func (a *Actions) PointerUp(button int) *Actions {
+	return a.add(mouseSourceID, map[string]interface{}{"type": "pointerUp", "button": button})
+}
+
+// Pause waits for the duration d before the next action.
+func (a *Actions) Pause(d time.Duration) *Actions {
+	if len(a.sources) == 0 {
+		a.source(keyboardSourceID)
+	}
+	for _, s := range a.sources {
+		s.Actions = append(s.Actions, map[string]interface{}{"type": "pause", "duration": int(d / time.Millisecond)})
+	}
+	a.ticks++
+	return a
+}
+
+// Perform sends the actions to the browser, which performs them in order.
+// The keys and buttons that are still held down afterwards remain so until
+// they are released by later actions or WebDriver.ReleaseActions.
+func (a *Actions) Perform() error {
+	if a.err != nil {
+		return a.err
+	}
+	if !a.wd.w3cCompatible {
+		return errors.New("actions require a W3C-compatible session")
+	}
+	if len(a.sources) == 0 {
+		return nil
+	}
+	sources := make([]interface{}, len(a.sources))
+	for i, s := range a.sources {
+		sources[i] = s
+	}
+	return a.wd.performActions(sources...)
+}
+
+func (wd *remoteWD) ReleaseActions() error {
+	if !wd.w3cCompatible {
+		return errors.New("ReleaseActions requires a W3C-compatible session")
+	}
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s/actions", wd.id), nil)
+	return err
+}
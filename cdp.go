@@ -0,0 +1,645 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tebeka/selenium/log"
+)
+
+// This file contains methods that are only supported by Chrome, as they rely
+// on the Chrome DevTools Protocol (CDP).
+//
+// https://chromedevtools.github.io/devtools-protocol/
+
+// requireChrome returns an UnsupportedError for method if the current session
+// is not driving Chrome.
+func (wd *remoteWD) requireChrome(method string) error {
+	if wd.browser != "chrome" {
+		return &UnsupportedError{Method: method, Browser: wd.browser}
+	}
+	return nil
+}
+
+// executeCDP executes a DevTools command through ChromeDriver and, if result
+// is not nil, decodes the command's result into it.
+func (wd *remoteWD) executeCDP(cmd string, params map[string]interface{}, result interface{}) error {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"cmd":    cmd,
+		"params": params,
+	})
+	if err != nil {
+		return err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/goog/cdp/execute", wd.id), data)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	reply := new(struct{ Value json.RawMessage })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return err
+	}
+	return json.Unmarshal(reply.Value, result)
+}
+
+func (wd *remoteWD) ExecuteCDPCmd(command string, params map[string]interface{}) (map[string]interface{}, error) {
+	if err := wd.requireChrome("ExecuteCDPCmd"); err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := wd.executeCDP(command, params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// devToolsEvent is a Chrome DevTools Protocol event, as reported by
+// ChromeDriver through the performance log.
+type devToolsEvent struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// recordedEventMethods are the DevTools events that the methods reading the
+// events of the recording window use. Other events are not kept.
+var recordedEventMethods = map[string]bool{
+	"Network.requestWillBeSent": true,
+	"Network.responseReceived":  true,
+	"Network.loadingFinished":   true,
+	"Network.loadingFailed":     true,
+	"Page.downloadWillBegin":    true,
+	"Page.downloadProgress":     true,
+	"Browser.downloadWillBegin": true,
+	"Browser.downloadProgress":  true,
+}
+
+// maxRecordedEvents is the maximum number of events kept in a recording
+// window, beyond which the oldest ones are dropped.
+var maxRecordedEvents = 10000
+
+// recordEvents drains the performance log into the events of the current
+// recording window and returns all of them.
+func (wd *remoteWD) recordEvents() ([]devToolsEvent, error) {
+	msgs, err := wd.Log(log.Performance)
+	if err != nil {
+		return nil, fmt.Errorf("reading the performance log (is it enabled in the capabilities?): %v", err)
+	}
+	for _, m := range msgs {
+		entry := new(struct{ Message devToolsEvent })
+		if err := json.Unmarshal([]byte(m.Message), entry); err != nil {
+			return nil, fmt.Errorf("invalid performance log entry %q: %v", m.Message, err)
+		}
+		if recordedEventMethods[entry.Message.Method] {
+			wd.events = append(wd.events, entry.Message)
+		}
+	}
+	if n := len(wd.events) - maxRecordedEvents; n > 0 {
+		// Copy the events so that the dropped ones can be garbage collected.
+		wd.events = append([]devToolsEvent(nil), wd.events[n:]...)
+	}
+	return wd.events, nil
+}
+
+func (wd *remoteWD) StartEventRecording() error {
+	if err := wd.requireChrome("StartEventRecording"); err != nil {
+		return err
+	}
+	if _, err := wd.recordEvents(); err != nil {
+		return err
+	}
+	wd.events = nil
+	wd.waitedRequests = nil
+	return nil
+}
+
+// Download describes a file download performed by the browser.
+type Download struct {
+	// GUID uniquely identifies the download.
+	GUID string
+	// URL is the URL of the downloaded resource.
+	URL string
+	// Filename is the name the browser suggested for the downloaded file.
+	Filename string
+	// State is one of "inProgress", "completed" or "canceled".
+	State string
+	// TotalBytes is the expected size of the download, or zero if unknown.
+	TotalBytes int64
+	// ReceivedBytes is the number of bytes downloaded so far.
+	ReceivedBytes int64
+}
+
+func (wd *remoteWD) Downloads() ([]Download, error) {
+	if err := wd.requireChrome("Downloads"); err != nil {
+		return nil, err
+	}
+	events, err := wd.recordEvents()
+	if err != nil {
+		return nil, err
+	}
+
+	var downloads []Download
+	index := make(map[string]int)
+	for _, e := range events {
+		switch e.Method {
+		// Chrome moved these events from the Page domain to the Browser domain.
+		case "Page.downloadWillBegin", "Browser.downloadWillBegin":
+			p := new(struct {
+				GUID              string
+				URL               string
+				SuggestedFilename string
+			})
+			if err := json.Unmarshal(e.Params, p); err != nil {
+				return nil, err
+			}
+			index[p.GUID] = len(downloads)
+			downloads = append(downloads, Download{
+				GUID:     p.GUID,
+				URL:      p.URL,
+				Filename: p.SuggestedFilename,
+				State:    "inProgress",
+			})
+		case "Page.downloadProgress", "Browser.downloadProgress":
+			p := new(struct {
+				GUID          string
+				TotalBytes    float64
+				ReceivedBytes float64
+				State         string
+			})
+			if err := json.Unmarshal(e.Params, p); err != nil {
+				return nil, err
+			}
+			i, ok := index[p.GUID]
+			if !ok {
+				continue
+			}
+			downloads[i].State = p.State
+			downloads[i].TotalBytes = int64(p.TotalBytes)
+			downloads[i].ReceivedBytes = int64(p.ReceivedBytes)
+		}
+	}
+	return downloads, nil
+}
+
+// devToolsRequest is a network request sent by the browser, as reported by a
+// Network.requestWillBeSent event.
+type devToolsRequest struct {
+	RequestID string `json:"requestId"`
+	Request   struct {
+		URL    string `json:"url"`
+		Method string `json:"method"`
+	} `json:"request"`
+}
+
+// recordedRequests returns the network requests sent during the current
+// event recording window, in the order they were sent.
+func (wd *remoteWD) recordedRequests() ([]devToolsRequest, error) {
+	events, err := wd.recordEvents()
+	if err != nil {
+		return nil, err
+	}
+	var requests []devToolsRequest
+	for _, e := range events {
+		if e.Method != "Network.requestWillBeSent" {
+			continue
+		}
+		var r devToolsRequest
+		if err := json.Unmarshal(e.Params, &r); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
+
+func (wd *remoteWD) AssertNoRequestsMatching(pattern string) error {
+	if err := wd.requireChrome("AssertNoRequestsMatching"); err != nil {
+		return err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	requests, err := wd.recordedRequests()
+	if err != nil {
+		return err
+	}
+	var matched []string
+	for _, r := range requests {
+		if re.MatchString(r.Request.URL) {
+			matched = append(matched, r.Request.Method+" "+r.Request.URL)
+		}
+	}
+	if len(matched) > 0 {
+		return fmt.Errorf("%d requests matched %q: %s", len(matched), pattern, strings.Join(matched, ", "))
+	}
+	return nil
+}
+
+// nonSecurityBlockedReasons are the reasons for which Chrome blocks requests, as
+// reported by Network.loadingFailed events, that are not security issues.
+var nonSecurityBlockedReasons = map[string]bool{
+	"":                   true,
+	"other":              true,
+	"inspector":          true,
+	"subresource-filter": true,
+}
+
+// securityIssues returns a description of each request in events that was
+// mixed content or that was blocked for security reasons, in the order the
+// requests were sent.
+func securityIssues(events []devToolsEvent) ([]string, error) {
+	var order []string
+	issues := make(map[string][]string)
+	urls := make(map[string]string)
+	for _, e := range events {
+		switch e.Method {
+		case "Network.requestWillBeSent":
+			var r struct {
+				RequestID string `json:"requestId"`
+				Request   struct {
+					URL string `json:"url"`
+					// MixedContentType is "blockable" or "optionally-blockable" for
+					// insecure requests made by secure pages, and "none" otherwise.
+					MixedContentType string `json:"mixedContentType"`
+				} `json:"request"`
+			}
+			if err := json.Unmarshal(e.Params, &r); err != nil {
+				return nil, err
+			}
+			if _, ok := urls[r.RequestID]; !ok {
+				order = append(order, r.RequestID)
+			}
+			urls[r.RequestID] = r.Request.URL
+			if t := r.Request.MixedContentType; t != "" && t != "none" {
+				issues[r.RequestID] = append(issues[r.RequestID], t+" mixed content")
+			}
+		case "Network.loadingFailed":
+			p := new(struct {
+				RequestID     string `json:"requestId"`
+				BlockedReason string `json:"blockedReason"`
+			})
+			if err := json.Unmarshal(e.Params, p); err != nil {
+				return nil, err
+			}
+			if !nonSecurityBlockedReasons[p.BlockedReason] {
+				issues[p.RequestID] = append(issues[p.RequestID], fmt.Sprintf("blocked (%s)", p.BlockedReason))
+			}
+		}
+	}
+	var warnings []string
+	for _, id := range order {
+		if len(issues[id]) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", urls[id], strings.Join(issues[id], ", ")))
+		}
+	}
+	return warnings, nil
+}
+
+func (wd *remoteWD) MixedContentWarnings() ([]string, error) {
+	if err := wd.requireChrome("MixedContentWarnings"); err != nil {
+		return nil, err
+	}
+	events, err := wd.recordEvents()
+	if err != nil {
+		return nil, err
+	}
+	return securityIssues(events)
+}
+
+// NetworkRequest describes a network request that the browser completed.
+type NetworkRequest struct {
+	// URL is the URL of the request.
+	URL string
+	// Method is the HTTP method of the request, e.g. "GET".
+	Method string
+	// Status is the HTTP status code of the response, or zero if the request
+	// failed without a response.
+	Status int
+	// MIMEType is the MIME type of the response.
+	MIMEType string
+	// Failed reports whether the request failed, e.g. because the connection
+	// was refused or the request was canceled.
+	Failed bool
+	// ErrorText describes why the request failed.
+	ErrorText string
+}
+
+// completedRequest returns the first request of the current event recording
+// window whose URL matches re, that has completed and that was not returned by
+// WaitForRequest yet, if any, with its ID.
+func (wd *remoteWD) completedRequest(re *regexp.Regexp) (string, *NetworkRequest, error) {
+	events, err := wd.recordEvents()
+	if err != nil {
+		return "", nil, err
+	}
+	// Requests are matched by ID, in the order they were sent. Redirects reuse
+	// the ID of the original request with a new URL.
+	var order []string
+	requests := make(map[string]*NetworkRequest)
+	for _, e := range events {
+		p := new(struct {
+			devToolsRequest
+			Response struct {
+				Status   float64 `json:"status"`
+				MIMEType string  `json:"mimeType"`
+			} `json:"response"`
+			ErrorText string `json:"errorText"`
+		})
+		switch e.Method {
+		case "Network.requestWillBeSent", "Network.responseReceived",
+			"Network.loadingFinished", "Network.loadingFailed":
+			if err := json.Unmarshal(e.Params, p); err != nil {
+				return "", nil, err
+			}
+		default:
+			continue
+		}
+		if wd.waitedRequests[p.RequestID] {
+			continue
+		}
+		r, ok := requests[p.RequestID]
+		if e.Method == "Network.requestWillBeSent" {
+			if !ok {
+				order = append(order, p.RequestID)
+				r = new(NetworkRequest)
+				requests[p.RequestID] = r
+			}
+			r.URL, r.Method = p.Request.URL, p.Request.Method
+			continue
+		}
+		if !ok {
+			continue
+		}
+		switch e.Method {
+		case "Network.responseReceived":
+			r.Status, r.MIMEType = int(p.Response.Status), p.Response.MIMEType
+		case "Network.loadingFinished":
+			if re.MatchString(r.URL) {
+				return p.RequestID, r, nil
+			}
+		case "Network.loadingFailed":
+			if re.MatchString(r.URL) {
+				r.Failed, r.ErrorText = true, p.ErrorText
+				return p.RequestID, r, nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+func (wd *remoteWD) WaitForRequest(urlPattern string, timeout time.Duration) (NetworkRequest, error) {
+	if err := wd.requireChrome("WaitForRequest"); err != nil {
+		return NetworkRequest{}, err
+	}
+	re, err := regexp.Compile(urlPattern)
+	if err != nil {
+		return NetworkRequest{}, err
+	}
+	// The Network domain must be enabled for the response details to be
+	// reported.
+	if err := wd.executeCDP("Network.enable", nil, nil); err != nil {
+		return NetworkRequest{}, err
+	}
+	var (
+		id    string
+		found *NetworkRequest
+	)
+	err = wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		var err error
+		id, found, err = wd.completedRequest(re)
+		return found != nil, err
+	}, timeout)
+	if err != nil {
+		return NetworkRequest{}, fmt.Errorf("waiting for a request matching %q: %v", urlPattern, err)
+	}
+	// Each request is only returned once, so that waiting again waits for
+	// the next one.
+	if wd.waitedRequests == nil {
+		wd.waitedRequests = make(map[string]bool)
+	}
+	wd.waitedRequests[id] = true
+	return *found, nil
+}
+
+func (wd *remoteWD) CreateIncognitoContext() (string, error) {
+	if err := wd.requireChrome("CreateIncognitoContext"); err != nil {
+		return "", err
+	}
+	reply := new(struct{ BrowserContextID string })
+	if err := wd.executeCDP("Target.createBrowserContext", nil, reply); err != nil {
+		return "", err
+	}
+	return reply.BrowserContextID, nil
+}
+
+func (wd *remoteWD) NewWindowInContext(contextID, url string) (string, error) {
+	if err := wd.requireChrome("NewWindowInContext"); err != nil {
+		return "", err
+	}
+	if url == "" {
+		url = "about:blank"
+	}
+	reply := new(struct{ TargetID string })
+	if err := wd.executeCDP("Target.createTarget", map[string]interface{}{
+		"url":              url,
+		"browserContextId": contextID,
+	}, reply); err != nil {
+		return "", err
+	}
+
+	// ChromeDriver derives window handles from the DevTools target IDs, with a
+	// prefix in older versions.
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return "", err
+	}
+	for _, h := range handles {
+		if strings.HasSuffix(h, reply.TargetID) {
+			return h, nil
+		}
+	}
+	return "", fmt.Errorf("no window handle found for target %q", reply.TargetID)
+}
+
+func (wd *remoteWD) DisposeBrowserContext(contextID string) error {
+	if err := wd.requireChrome("DisposeBrowserContext"); err != nil {
+		return err
+	}
+	return wd.executeCDP("Target.disposeBrowserContext", map[string]interface{}{
+		"browserContextId": contextID,
+	}, nil)
+}
+
+func (wd *remoteWD) JSHeapSize() (used, total int64, err error) {
+	if err := wd.requireChrome("JSHeapSize"); err != nil {
+		return 0, 0, err
+	}
+	reply := new(struct{ UsedSize, TotalSize float64 })
+	if err := wd.executeCDP("Runtime.getHeapUsage", nil, reply); err != nil {
+		return 0, 0, err
+	}
+	return int64(reply.UsedSize), int64(reply.TotalSize), nil
+}
+
+func (wd *remoteWD) SetCacheDisabled(disabled bool) error {
+	if err := wd.requireChrome("SetCacheDisabled"); err != nil {
+		return err
+	}
+	// The Network domain must be enabled for the setting to take effect.
+	if err := wd.executeCDP("Network.enable", nil, nil); err != nil {
+		return err
+	}
+	return wd.executeCDP("Network.setCacheDisabled", map[string]interface{}{
+		"cacheDisabled": disabled,
+	}, nil)
+}
+
+func (wd *remoteWD) EmulatePrintMedia(enabled bool) error {
+	if err := wd.requireChrome("EmulatePrintMedia"); err != nil {
+		return err
+	}
+	media := ""
+	if enabled {
+		media = "print"
+	}
+	return wd.executeCDP("Emulation.setEmulatedMedia", map[string]interface{}{
+		"media": media,
+	}, nil)
+}
+
+func (wd *remoteWD) SetGeolocation(lat, lon, accuracy float64) error {
+	if err := wd.requireChrome("SetGeolocation"); err != nil {
+		return err
+	}
+	return wd.executeCDP("Emulation.setGeolocationOverride", map[string]interface{}{
+		"latitude":  lat,
+		"longitude": lon,
+		"accuracy":  accuracy,
+	}, nil)
+}
+
+func (wd *remoteWD) ClearGeolocation() error {
+	if err := wd.requireChrome("ClearGeolocation"); err != nil {
+		return err
+	}
+	return wd.executeCDP("Emulation.clearGeolocationOverride", nil, nil)
+}
+
+// devToolsCookie is a cookie as represented by the Network domain.
+type devToolsCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires,omitempty"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	Session  bool    `json:"session,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"`
+}
+
+// allCookies returns the cookies of all domains, unlike GetCookies which only
+// returns those visible to the current page.
+func (wd *remoteWD) allCookies() ([]Cookie, error) {
+	reply := new(struct{ Cookies []devToolsCookie })
+	if err := wd.executeCDP("Network.getAllCookies", nil, reply); err != nil {
+		return nil, err
+	}
+	cookies := make([]Cookie, len(reply.Cookies))
+	for i, c := range reply.Cookies {
+		cookies[i] = Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: SameSite(c.SameSite),
+		}
+		if !c.Session && c.Expires > 0 {
+			cookies[i].Expiry = uint(c.Expires)
+		}
+	}
+	return cookies, nil
+}
+
+// setCookies sets cookies for any domain without navigating.
+func (wd *remoteWD) setCookies(cookies []Cookie) error {
+	params := make([]devToolsCookie, len(cookies))
+	for i, c := range cookies {
+		params[i] = devToolsCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  float64(c.Expiry),
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		}
+	}
+	return wd.executeCDP("Network.setCookies", map[string]interface{}{"cookies": params}, nil)
+}
+
+// addInitScript evaluates script in the current page and arranges for it to
+// be evaluated in every new document before the page's own scripts.
+func (wd *remoteWD) addInitScript(script string) error {
+	if err := wd.executeCDP("Page.addScriptToEvaluateOnNewDocument", map[string]interface{}{
+		"source": script,
+	}, nil); err != nil {
+		return err
+	}
+	_, err := wd.ExecuteScript(script, nil)
+	return err
+}
+
+// createStyleSheet adds a style sheet with the provided rules to the main frame
+// of the current page and returns its ID.
+func (wd *remoteWD) createStyleSheet(css string) (string, error) {
+	tree := new(struct {
+		FrameTree struct {
+			Frame struct{ ID string }
+		}
+	})
+	if err := wd.executeCDP("Page.getFrameTree", nil, tree); err != nil {
+		return "", err
+	}
+	// The CSS domain requires the DOM domain to be enabled.
+	for _, cmd := range []string{"DOM.enable", "CSS.enable"} {
+		if err := wd.executeCDP(cmd, nil, nil); err != nil {
+			return "", err
+		}
+	}
+	reply := new(struct{ StyleSheetID string })
+	// Without force, Chrome returns the same inspector style sheet for each
+	// call, so that the sheets would overwrite each other.
+	if err := wd.executeCDP("CSS.createStyleSheet", map[string]interface{}{
+		"frameId": tree.FrameTree.Frame.ID,
+		"force":   true,
+	}, reply); err != nil {
+		return "", err
+	}
+	if err := wd.setStyleSheetText(reply.StyleSheetID, css); err != nil {
+		return "", err
+	}
+	return reply.StyleSheetID, nil
+}
+
+// setStyleSheetText replaces the rules of the style sheet with the provided
+// ID. The Chrome DevTools Protocol cannot remove a style sheet, so an empty
+// text is used instead.
+func (wd *remoteWD) setStyleSheetText(id, css string) error {
+	return wd.executeCDP("CSS.setStyleSheetText", map[string]interface{}{
+		"styleSheetId": id,
+		"text":         css,
+	}, nil)
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"io"
 	"os"
 
@@ -54,7 +55,15 @@ type Capabilities struct {
 	// ChromeDriver quits if the session was not terminated.
 	Detach *bool `json:"detach,omitempty"`
 	// DebuggerAddr is the TCP/IP address of a Chrome debugger server to connect
-	// to.
+	// to, e.g. "127.0.0.1:9222". This attaches ChromeDriver to an
+	// already-running Chrome, started with e.g.
+	//
+	//	chrome --remote-debugging-port=9222 --user-data-dir=/tmp/profile
+	//
+	// rather than having it launch one, so that the browser's flags and
+	// profile are preserved. Options that only apply at launch, such as Path,
+	// Args and Extensions, are ignored, and the browser is left running when
+	// the session ends.
 	DebuggerAddr string `json:"debuggerAddress,omitempty"`
 	// MinidumpPath specifies the directory in which to store Chrome minidumps.
 	// (This is only available on Linux).
@@ -63,6 +72,10 @@ type Capabilities struct {
 	MobileEmulation *MobileEmulation `json:"mobileEmulation,omitempty"`
 	// PerfLoggingPrefs specifies options for performance logging.
 	PerfLoggingPrefs *PerfLoggingPreferences `json:"perfLoggingPrefs,omitempty"`
+	// DevToolsEventsToLog are the names of the DevTools events, e.g.
+	// "Runtime.consoleAPICalled", that ChromeDriver records in the "devtools"
+	// log.
+	DevToolsEventsToLog []string `json:"devToolsEventsToLog,omitempty"`
 	// WindowTypes is a list of window types that will appear in the list of
 	// window handles. For access to <webview> elements, include "webview" in
 	// this list.
@@ -90,6 +103,21 @@ type MobileEmulation struct {
 	UserAgent string `json:"userAgent,omitempty"`
 }
 
+// Validate returns an error if the options cannot be used together, i.e. if
+// DeviceName is set along with DeviceMetrics or UserAgent.
+func (m *MobileEmulation) Validate() error {
+	if m.DeviceName == "" {
+		return nil
+	}
+	if m.DeviceMetrics != nil {
+		return errors.New("mobile emulation: DeviceName and DeviceMetrics are mutually exclusive")
+	}
+	if m.UserAgent != "" {
+		return errors.New("mobile emulation: UserAgent cannot be set with DeviceName, which implies it")
+	}
+	return nil
+}
+
 // DeviceMetrics specifies device attributes for emulation.
 type DeviceMetrics struct {
 	// Width is the width of the screen.
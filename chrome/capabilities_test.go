@@ -15,3 +15,78 @@ func TestEmptyCapabilities(t *testing.T) {
 		t.Fatalf("json.Marshal(Capabilities{}) = %q, want %q", got, want)
 	}
 }
+
+func TestDebuggerAddr(t *testing.T) {
+	data, err := json.Marshal(Capabilities{DebuggerAddr: "127.0.0.1:9222"})
+	if err != nil {
+		t.Fatalf("json.Marshal(Capabilities{}) return error: %v", err)
+	}
+	got, want := string(data), `{"debuggerAddress":"127.0.0.1:9222","w3c":false}`
+	if got != want {
+		t.Fatalf("json.Marshal(Capabilities{}) = %q, want %q", got, want)
+	}
+}
+
+func TestMobileEmulation(t *testing.T) {
+	touch := false
+	for _, tc := range []struct {
+		m       MobileEmulation
+		want    string
+		wantErr bool
+	}{
+		{
+			m:    MobileEmulation{DeviceName: "iPhone X"},
+			want: `{"deviceName":"iPhone X"}`,
+		},
+		{
+			m: MobileEmulation{
+				DeviceMetrics: &DeviceMetrics{Width: 375, Height: 812, PixelRatio: 3, Touch: &touch},
+				UserAgent:     "Mozilla/5.0 (iPhone)",
+			},
+			want: `{"deviceMetrics":{"width":375,"height":812,"pixelRatio":3,"touch":false},"userAgent":"Mozilla/5.0 (iPhone)"}`,
+		},
+		{
+			m:       MobileEmulation{DeviceName: "iPhone X", DeviceMetrics: &DeviceMetrics{Width: 375, Height: 812}},
+			wantErr: true,
+		},
+		{
+			m:       MobileEmulation{DeviceName: "iPhone X", UserAgent: "Mozilla/5.0 (iPhone)"},
+			wantErr: true,
+		},
+	} {
+		if err := tc.m.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%+v.Validate() returned error %v, want error: %t", tc.m, err, tc.wantErr)
+		}
+		if tc.wantErr {
+			continue
+		}
+		data, err := json.Marshal(Capabilities{MobileEmulation: &tc.m})
+		if err != nil {
+			t.Fatalf("json.Marshal(%+v) returned error: %v", tc.m, err)
+		}
+		if got, want := string(data), `{"mobileEmulation":`+tc.want+`,"w3c":false}`; got != want {
+			t.Errorf("json.Marshal(%+v) = %s, want %s", tc.m, got, want)
+		}
+	}
+}
+
+func TestProfileOptions(t *testing.T) {
+	data, err := json.Marshal(Capabilities{
+		Path:            "/opt/chrome/chrome",
+		ExcludeSwitches: []string{"enable-automation"},
+		Prefs: map[string]interface{}{
+			"download.default_directory": "/tmp/downloads",
+		},
+		LocalState: map[string]interface{}{
+			"browser.enabled_labs_experiments": []string{"some-flag@1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal(Capabilities{}) return error: %v", err)
+	}
+	got := string(data)
+	want := `{"binary":"/opt/chrome/chrome","excludeSwitches":["enable-automation"],"localState":{"browser.enabled_labs_experiments":["some-flag@1"]},"prefs":{"download.default_directory":"/tmp/downloads"},"w3c":false}`
+	if got != want {
+		t.Fatalf("json.Marshal(Capabilities{}) = %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package selenium
 import (
 	"log"
 	"net/url"
+	"time"
 )
 
 var debugFlag = false
@@ -12,6 +13,19 @@ func SetDebug(debug bool) {
 	debugFlag = debug
 }
 
+var (
+	highlightFlag     = false
+	highlightDuration = 500 * time.Millisecond
+)
+
+// SetHighlight sets whether elements are highlighted with
+// WebElement.Highlight before each Click and SendKeys, and for how long
+// Highlight outlines an element. This is meant for watching a test run.
+func SetHighlight(highlight bool, duration time.Duration) {
+	highlightFlag = highlight
+	highlightDuration = duration
+}
+
 func debugLog(format string, args ...interface{}) {
 	if !debugFlag {
 		return
@@ -0,0 +1,139 @@
+package selenium
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TitleIs returns a Condition that is satisfied when the current page's title
+// is equal to title.
+func TitleIs(title string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		t, err := wd.Title()
+		if err != nil {
+			return false, err
+		}
+		return t == title, nil
+	}
+}
+
+// TitleContains returns a Condition that is satisfied when the current page's
+// title contains substr.
+func TitleContains(substr string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		t, err := wd.Title()
+		if err != nil {
+			return false, err
+		}
+		return strings.Contains(t, substr), nil
+	}
+}
+
+// NumberOfWindowsToBe returns a Condition that is satisfied when the number of
+// open windows, as returned by WindowHandles, is n.
+func NumberOfWindowsToBe(n int) Condition {
+	return func(wd WebDriver) (bool, error) {
+		handles, err := wd.WindowHandles()
+		if err != nil {
+			return false, err
+		}
+		return len(handles) == n, nil
+	}
+}
+
+// URLContains returns a Condition that is satisfied when the current URL
+// contains substr.
+func URLContains(substr string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		u, err := wd.CurrentURL()
+		if err != nil {
+			return false, err
+		}
+		if !strings.Contains(u, substr) {
+			return false, waitState(fmt.Sprintf("current URL is %q", u))
+		}
+		return true, nil
+	}
+}
+
+// ElementIsPresent returns a Condition that is satisfied when an element can
+// be found in the current page's DOM by the given strategy and value.
+func ElementIsPresent(by, value string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		if _, err := wd.FindElement(by, value); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// ElementIsVisible returns a Condition that is satisfied when an element can
+// be found by the given strategy and value and is displayed.
+func ElementIsVisible(by, value string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		elem, err := wd.FindElement(by, value)
+		if err != nil {
+			return false, err
+		}
+		if displayed, err := elem.IsDisplayed(); err != nil || !displayed {
+			return false, notDisplayed(err)
+		}
+		return true, nil
+	}
+}
+
+// ElementIsClickable returns a Condition that is satisfied when an element
+// can be found by the given strategy and value and is both displayed and
+// enabled.
+func ElementIsClickable(by, value string) Condition {
+	return func(wd WebDriver) (bool, error) {
+		elem, err := wd.FindElement(by, value)
+		if err != nil {
+			return false, err
+		}
+		if displayed, err := elem.IsDisplayed(); err != nil || !displayed {
+			return false, notDisplayed(err)
+		}
+		enabled, err := elem.IsEnabled()
+		if err != nil {
+			return false, err
+		}
+		if !enabled {
+			return false, waitState("element found and displayed but not enabled")
+		}
+		return true, nil
+	}
+}
+
+// notDisplayed returns err, the error of IsDisplayed, or the state of an
+// element that is not displayed.
+func notDisplayed(err error) error {
+	if err != nil {
+		return err
+	}
+	return waitState("element found but not displayed")
+}
+
+// waitState is returned by the Conditions of this package to describe the
+// state they observed while they are not satisfied yet. It does not abort
+// waits and is reported if they time out.
+type waitState string
+
+func (s waitState) Error() string {
+	return string(s)
+}
+
+// AlertIsPresent returns a Condition that is satisfied when an alert, confirm
+// or prompt dialog is open.
+func AlertIsPresent() Condition {
+	return func(wd WebDriver) (bool, error) {
+		if _, err := wd.AlertText(); err != nil {
+			// Legacy servers report "no alert open".
+			if errorIs(err, "no such alert") || errorIs(err, "no alert open") {
+				return false, waitState("no alert is open")
+			}
+			return false, err
+		}
+		return true, nil
+	}
+}
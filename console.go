@@ -0,0 +1,307 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/tebeka/selenium/log"
+)
+
+// StackFrame is a frame of a JavaScript stack trace.
+type StackFrame struct {
+	// Function is the name of the function, or empty for anonymous functions
+	// and top-level code.
+	Function string `json:"function"`
+	// URL is the URL of the script.
+	URL string `json:"url"`
+	// Line and Column are the one-based position in the script.
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ConsoleMessage is a message logged by a page through the console API, or an
+// uncaught exception.
+type ConsoleMessage struct {
+	// Level is the kind of console method that was called, e.g. "log",
+	// "info", "warn", "error", "debug", "trace" or "assert", or "error" for
+	// uncaught exceptions.
+	Level string
+	// Text is the message, with the arguments converted to strings and joined
+	// by spaces. Objects are represented by their description, e.g. "Object"
+	// or "Array(3)".
+	Text string
+	// Timestamp is when the message was logged.
+	Timestamp time.Time
+	// StackTrace is the call stack of the console method call or of the
+	// exception, innermost frame first.
+	StackTrace []StackFrame
+}
+
+// ConsoleEvents are the Chrome DevTools events from which console messages
+// are read. They must be logged by ChromeDriver for StartConsoleCapture to
+// work, by setting them as the DevToolsEventsToLog of the chrome.Capabilities
+// of the session.
+var ConsoleEvents = []string{"Runtime.consoleAPICalled", "Runtime.exceptionThrown"}
+
+// devToolsRemoteObject is a JavaScript value as represented by the Runtime
+// domain.
+type devToolsRemoteObject struct {
+	Type                string          `json:"type"`
+	Value               json.RawMessage `json:"value"`
+	UnserializableValue string          `json:"unserializableValue"`
+	Description         string          `json:"description"`
+}
+
+// String returns the value as the console would print it.
+func (o devToolsRemoteObject) String() string {
+	if o.Type == "string" {
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+	}
+	switch {
+	case len(o.Value) > 0:
+		return string(o.Value)
+	case o.UnserializableValue != "":
+		return o.UnserializableValue
+	case o.Type == "undefined":
+		return "undefined"
+	}
+	return o.Description
+}
+
+// devToolsStackTrace is a JavaScript stack trace as represented by the Runtime
+// domain, with zero-based positions.
+type devToolsStackTrace struct {
+	CallFrames []struct {
+		FunctionName string `json:"functionName"`
+		URL          string `json:"url"`
+		LineNumber   int    `json:"lineNumber"`
+		ColumnNumber int    `json:"columnNumber"`
+	} `json:"callFrames"`
+}
+
+func (st *devToolsStackTrace) frames() []StackFrame {
+	if st == nil {
+		return nil
+	}
+	frames := make([]StackFrame, len(st.CallFrames))
+	for i, f := range st.CallFrames {
+		frames[i] = StackFrame{
+			Function: f.FunctionName,
+			URL:      f.URL,
+			Line:     f.LineNumber + 1,
+			Column:   f.ColumnNumber + 1,
+		}
+	}
+	return frames
+}
+
+// devToolsTimestamp converts a timestamp of the Runtime domain, in
+// milliseconds since the Epoch, to a time with a microsecond precision, as
+// nanoseconds would exceed that of a float64.
+func devToolsTimestamp(ms float64) time.Time {
+	return time.Unix(0, int64(math.Round(ms*1e3))*int64(time.Microsecond))
+}
+
+// consoleMessage converts a Runtime.consoleAPICalled or
+// Runtime.exceptionThrown event to a console message. It returns false for
+// other events.
+func consoleMessage(e devToolsEvent) (ConsoleMessage, bool, error) {
+	switch e.Method {
+	case "Runtime.consoleAPICalled":
+		params := new(struct {
+			Type       string                 `json:"type"`
+			Args       []devToolsRemoteObject `json:"args"`
+			Timestamp  float64                `json:"timestamp"`
+			StackTrace *devToolsStackTrace    `json:"stackTrace"`
+		})
+		if err := json.Unmarshal(e.Params, params); err != nil {
+			return ConsoleMessage{}, false, fmt.Errorf("invalid %s event: %v", e.Method, err)
+		}
+		level := params.Type
+		if level == "warning" {
+			level = "warn"
+		}
+		args := make([]string, len(params.Args))
+		for i, a := range params.Args {
+			args[i] = a.String()
+		}
+		return ConsoleMessage{
+			Level:      level,
+			Text:       strings.Join(args, " "),
+			Timestamp:  devToolsTimestamp(params.Timestamp),
+			StackTrace: params.StackTrace.frames(),
+		}, true, nil
+
+	case "Runtime.exceptionThrown":
+		params := new(struct {
+			Timestamp        float64 `json:"timestamp"`
+			ExceptionDetails struct {
+				Text         string                `json:"text"`
+				URL          string                `json:"url"`
+				LineNumber   int                   `json:"lineNumber"`
+				ColumnNumber int                   `json:"columnNumber"`
+				StackTrace   *devToolsStackTrace   `json:"stackTrace"`
+				Exception    *devToolsRemoteObject `json:"exception"`
+			} `json:"exceptionDetails"`
+		})
+		if err := json.Unmarshal(e.Params, params); err != nil {
+			return ConsoleMessage{}, false, fmt.Errorf("invalid %s event: %v", e.Method, err)
+		}
+		d := params.ExceptionDetails
+		text := d.Text
+		if d.Exception != nil {
+			// The description of errors includes their stack trace.
+			text += " " + strings.SplitN(d.Exception.String(), "\n", 2)[0]
+		}
+		stack := d.StackTrace.frames()
+		if len(stack) == 0 && d.URL != "" {
+			stack = []StackFrame{{URL: d.URL, Line: d.LineNumber + 1, Column: d.ColumnNumber + 1}}
+		}
+		return ConsoleMessage{
+			Level:      "error",
+			Text:       text,
+			Timestamp:  devToolsTimestamp(params.Timestamp),
+			StackTrace: stack,
+		}, true, nil
+	}
+	return ConsoleMessage{}, false, nil
+}
+
+// readDevToolsLog reads and clears the Chrome DevTools events logged by
+// ChromeDriver because they were listed in the DevToolsEventsToLog
+// capability.
+func (wd *remoteWD) readDevToolsLog() ([]devToolsEvent, error) {
+	msgs, err := wd.Log(log.DevTools)
+	if err != nil {
+		return nil, fmt.Errorf("reading the DevTools event log (are ConsoleEvents set as the DevToolsEventsToLog of the chrome.Capabilities?): %v", err)
+	}
+	events := make([]devToolsEvent, len(msgs))
+	for i, m := range msgs {
+		if err := json.Unmarshal([]byte(m.Message), &events[i]); err != nil {
+			return nil, fmt.Errorf("invalid DevTools event log entry %q: %v", m.Message, err)
+		}
+	}
+	return events, nil
+}
+
+func (wd *remoteWD) StartConsoleCapture() error {
+	if err := wd.requireChrome("StartConsoleCapture"); err != nil {
+		return err
+	}
+	_, err := wd.readDevToolsLog()
+	return err
+}
+
+func (wd *remoteWD) ConsoleMessages() ([]ConsoleMessage, error) {
+	if err := wd.requireChrome("ConsoleMessages"); err != nil {
+		return nil, err
+	}
+	events, err := wd.readDevToolsLog()
+	if err != nil {
+		return nil, err
+	}
+	var msgs []ConsoleMessage
+	for _, e := range events {
+		m, ok, err := consoleMessage(e)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs, nil
+}
+
+// consoleSeverities ranks the console levels from least to most severe. The
+// levels of the console methods that only print their arguments rank as
+// "log", and those of console.assert failures as "error".
+var consoleSeverities = map[string]int{
+	"debug":      0,
+	"clear":      0,
+	"endGroup":   0,
+	"profile":    0,
+	"profileEnd": 0,
+
+	"log":                 1,
+	"info":                1,
+	"dir":                 1,
+	"dirxml":              1,
+	"table":               1,
+	"trace":               1,
+	"startGroup":          1,
+	"startGroupCollapsed": 1,
+	"count":               1,
+	"timeEnd":             1,
+
+	"warn": 2,
+
+	"error":  3,
+	"assert": 3,
+}
+
+// consoleSeverity returns the rank of the console level in consoleSeverities.
+// Unknown levels rank as "log", so that they are reported unless only
+// warnings and errors are.
+func consoleSeverity(level string) int {
+	if s, ok := consoleSeverities[level]; ok {
+		return s
+	}
+	return consoleSeverities["log"]
+}
+
+// ConsoleMessagesError is returned by WebDriver.AssertNoConsoleErrors when
+// the page logged messages at or above the minimum severity.
+type ConsoleMessagesError struct {
+	// Messages are the offending messages, in the order they were logged.
+	Messages []ConsoleMessage
+}
+
+// Error implements the error interface.
+func (e *ConsoleMessagesError) Error() string {
+	lines := make([]string, len(e.Messages))
+	for i, m := range e.Messages {
+		lines[i] = fmt.Sprintf("[%s] %s", m.Level, m.Text)
+		if len(m.StackTrace) > 0 {
+			f := m.StackTrace[0]
+			lines[i] += fmt.Sprintf(" (%s:%d:%d)", f.URL, f.Line, f.Column)
+		}
+	}
+	return fmt.Sprintf("%d console messages logged: %s", len(e.Messages), strings.Join(lines, "; "))
+}
+
+// filterConsoleMessages returns the messages at or above minSeverity.
+func filterConsoleMessages(msgs []ConsoleMessage, minSeverity string) ([]ConsoleMessage, error) {
+	min, ok := consoleSeverities[minSeverity]
+	if !ok {
+		return nil, fmt.Errorf("unknown console severity %q; want one of debug, log, info, warn or error", minSeverity)
+	}
+	var matched []ConsoleMessage
+	for _, m := range msgs {
+		if consoleSeverity(m.Level) >= min {
+			matched = append(matched, m)
+		}
+	}
+	return matched, nil
+}
+
+func (wd *remoteWD) AssertNoConsoleErrors(minSeverity string) error {
+	msgs, err := wd.ConsoleMessages()
+	if err != nil {
+		return err
+	}
+	matched, err := filterConsoleMessages(msgs, minSeverity)
+	if err != nil {
+		return err
+	}
+	if len(matched) > 0 {
+		return &ConsoleMessagesError{Messages: matched}
+	}
+	return nil
+}
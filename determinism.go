@@ -0,0 +1,65 @@
+package selenium
+
+import (
+	"fmt"
+	"time"
+)
+
+// freezeTimeScript replaces Date so that it reports the time provided, in
+// milliseconds since the Unix epoch, as the first argument of the enclosing
+// function, and stops performance.now. Dates constructed with arguments are
+// not affected.
+const freezeTimeScript = `
+(function(now) {
+  var RealDate = Date;
+  var FrozenDate = function() {
+    if (!(this instanceof FrozenDate)) {
+      return new RealDate(now).toString();
+    }
+    if (arguments.length === 0) {
+      return new RealDate(now);
+    }
+    var args = [null].concat(Array.prototype.slice.call(arguments));
+    return new (Function.prototype.bind.apply(RealDate, args))();
+  };
+  FrozenDate.prototype = RealDate.prototype;
+  FrozenDate.now = function() { return now; };
+  FrozenDate.parse = RealDate.parse;
+  FrozenDate.UTC = RealDate.UTC;
+  window.Date = FrozenDate;
+  if (window.performance && performance.now) {
+    var start = performance.now();
+    performance.now = function() { return start; };
+  }
+})(%d);
+`
+
+func (wd *remoteWD) FreezeTime(t time.Time) error {
+	if err := wd.requireChrome("FreezeTime"); err != nil {
+		return err
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return wd.addInitScript(fmt.Sprintf(freezeTimeScript, ms))
+}
+
+// seedRandomScript replaces Math.random with a Mulberry32 generator seeded
+// with the first argument of the enclosing function.
+const seedRandomScript = `
+(function(state) {
+  Math.random = function() {
+    state = state + 0x6D2B79F5 | 0;
+    var t = Math.imul(state ^ state >>> 15, 1 | state);
+    t = t + Math.imul(t ^ t >>> 7, 61 | t) ^ t;
+    return ((t ^ t >>> 14) >>> 0) / 4294967296;
+  };
+})(%d);
+`
+
+func (wd *remoteWD) SeedRandom(seed int64) error {
+	if err := wd.requireChrome("SeedRandom"); err != nil {
+		return err
+	}
+	// The generator has a 32-bit state, so fold the seed into it.
+	state := int32(seed ^ seed>>32)
+	return wd.addInitScript(fmt.Sprintf(seedRandomScript, state))
+}
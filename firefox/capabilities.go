@@ -2,8 +2,13 @@
 package firefox
 
 import (
+	stdzip "archive/zip"
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
 
 	"github.com/tebeka/selenium/internal/zip"
 )
@@ -59,6 +64,120 @@ func (c *Capabilities) SetProfile(basePath string) error {
 	return nil
 }
 
+// AddExtension adds the extension at path, an .xpi file, to the profile, so
+// that Firefox installs it at startup. The extension is added to the profile
+// set by SetProfile, if any, so call SetProfile first. The extension's ID is
+// read from its manifest.json file.
+//
+// Release versions of Firefox only install signed extensions. Unsigned
+// extensions require Firefox Developer Edition or Nightly, with the
+// "xpinstall.signatures.required" preference set to false.
+func (c *Capabilities) AddExtension(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.addExtension(data)
+}
+
+// addExtension adds the extension data to the profile.
+func (c *Capabilities) addExtension(data []byte) error {
+	id, err := extensionID(data)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	w := stdzip.NewWriter(buf)
+	if c.Profile != "" {
+		// Copy the files of the current profile.
+		profile, err := base64.StdEncoding.DecodeString(c.Profile)
+		if err != nil {
+			return err
+		}
+		r, err := stdzip.NewReader(bytes.NewReader(profile), int64(len(profile)))
+		if err != nil {
+			return err
+		}
+		for _, f := range r.File {
+			if err := copyZipFile(w, f); err != nil {
+				return err
+			}
+		}
+	}
+	f, err := w.CreateHeader(&stdzip.FileHeader{
+		Name:   "extensions/" + id + ".xpi",
+		Method: stdzip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	c.Profile = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return nil
+}
+
+// copyZipFile copies the file f into w.
+func copyZipFile(w *stdzip.Writer, f *stdzip.File) error {
+	header := f.FileHeader
+	dst, err := w.CreateHeader(&header)
+	if err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// extensionID returns the ID of the extension in the .xpi file data.
+func extensionID(data []byte) (string, error) {
+	r, err := stdzip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	for _, f := range r.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		type settings struct {
+			Gecko struct {
+				ID string `json:"id"`
+			} `json:"gecko"`
+		}
+		manifest := new(struct {
+			BrowserSpecificSettings settings `json:"browser_specific_settings"`
+			// Applications is the former name of BrowserSpecificSettings.
+			Applications settings `json:"applications"`
+		})
+		if err := json.NewDecoder(rc).Decode(manifest); err != nil {
+			return "", err
+		}
+		if id := manifest.BrowserSpecificSettings.Gecko.ID; id != "" {
+			return id, nil
+		}
+		if id := manifest.Applications.Gecko.ID; id != "" {
+			return id, nil
+		}
+		return "", errors.New("the extension's manifest.json does not specify its ID in browser_specific_settings.gecko.id")
+	}
+	return "", errors.New("the extension has no manifest.json file")
+}
+
 // LogLevel is an enum that defines logging levels for Firefox.
 type LogLevel string
 
@@ -0,0 +1,92 @@
+package firefox
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newZip returns a zip file with the provided files.
+func newZip(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("w.Create(%q) returned error: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("Writing %q returned error: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close() returned error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAddExtension(t *testing.T) {
+	xpi := newZip(t, map[string]string{
+		"manifest.json": `{"manifest_version": 2, "browser_specific_settings": {"gecko": {"id": "test@example.com"}}}`,
+	})
+	c := Capabilities{
+		Profile: base64.StdEncoding.EncodeToString(newZip(t, map[string]string{
+			"user.js": `user_pref("browser.startup.homepage", "about:blank");`,
+		})),
+	}
+	if err := c.addExtension(xpi); err != nil {
+		t.Fatalf("c.addExtension() returned error: %v", err)
+	}
+
+	profile, err := base64.StdEncoding.DecodeString(c.Profile)
+	if err != nil {
+		t.Fatalf("Decoding the profile returned error: %v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(profile), int64(len(profile)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() returned error: %v", err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		if f.Name != "extensions/test@example.com.xpi" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Opening %q returned error: %v", f.Name, err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Reading %q returned error: %v", f.Name, err)
+		}
+		if !bytes.Equal(data, xpi) {
+			t.Errorf("The profile contains a different extension file")
+		}
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff([]string{"extensions/test@example.com.xpi", "user.js"}, names); diff != "" {
+		t.Errorf("The profile contains different files (-want/+got):\n%s", diff)
+	}
+}
+
+func TestAddExtensionWithoutID(t *testing.T) {
+	for _, files := range []map[string]string{
+		{"manifest.json": `{"manifest_version": 2}`},
+		{"install.rdf": ""},
+	} {
+		var c Capabilities
+		if err := c.addExtension(newZip(t, files)); err == nil {
+			t.Errorf("c.addExtension() of an extension with files %v returned nil error", files)
+		}
+		if c.Profile != "" {
+			t.Errorf("c.addExtension() of an extension without an ID set the profile")
+		}
+	}
+}
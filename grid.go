@@ -0,0 +1,106 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+)
+
+// GridInfo describes a remote end, as returned by ProbeGrid.
+type GridInfo struct {
+	// Status is the status reported by the remote end.
+	Status Status
+	// Nodes are the nodes of a Selenium 4 grid. It is empty for other remote
+	// ends, such as a driver or an older grid, which do not report them.
+	Nodes []GridNode
+}
+
+// GridNode is a node of a Selenium 4 grid.
+type GridNode struct {
+	// ID uniquely identifies the node in the grid.
+	ID string
+	// URI is the address of the node.
+	URI string
+	// Availability is one of "UP", "DRAINING" or "DOWN".
+	Availability string
+	// Version is the version of Selenium that the node runs.
+	Version string
+	// MaxSessions is the number of sessions that the node runs concurrently.
+	MaxSessions int
+	// Slots are the capabilities of the sessions that the node can create,
+	// one per slot.
+	Slots []Capabilities
+	// ActiveSessions is the number of slots that are in use.
+	ActiveSessions int
+}
+
+// SupportsCapability reports whether a node of the grid advertises the
+// capability name, e.g. "se:recordVideo", with a value other than false in
+// one of its slots.
+func (g GridInfo) SupportsCapability(name string) bool {
+	for _, n := range g.Nodes {
+		for _, slot := range n.Slots {
+			if v, ok := slot[name]; ok && v != false {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProbeGrid returns the status of the remote end at urlPrefix and, for a
+// Selenium 4 grid, the capabilities that its nodes offer, so that a session
+// can be requested with only the features that the grid supports.
+func ProbeGrid(urlPrefix string) (GridInfo, error) {
+	if urlPrefix == "" {
+		urlPrefix = DefaultURLPrefix
+	}
+	u, err := url.Parse(urlPrefix)
+	if err != nil {
+		return GridInfo{}, err
+	}
+	u.Path = path.Join(u.Path, "status")
+	response, err := executeCommand("GET", u.String(), nil)
+	if err != nil {
+		return GridInfo{}, err
+	}
+
+	reply := new(struct {
+		Value struct {
+			Status
+			Nodes []struct {
+				ID           string
+				URI          string
+				Availability string
+				Version      string
+				MaxSessions  int
+				Slots        []struct {
+					Session    json.RawMessage
+					Stereotype Capabilities
+				}
+			}
+		}
+	})
+	if err := json.Unmarshal(response, reply); err != nil {
+		return GridInfo{}, err
+	}
+
+	info := GridInfo{Status: reply.Value.Status}
+	for _, n := range reply.Value.Nodes {
+		node := GridNode{
+			ID:           n.ID,
+			URI:          n.URI,
+			Availability: n.Availability,
+			Version:      n.Version,
+			MaxSessions:  n.MaxSessions,
+		}
+		for _, s := range n.Slots {
+			node.Slots = append(node.Slots, s.Stereotype)
+			if len(s.Session) > 0 && string(s.Session) != "null" {
+				node.ActiveSessions++
+			}
+		}
+		info.Nodes = append(info.Nodes, node)
+	}
+	return info, nil
+}
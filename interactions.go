@@ -0,0 +1,185 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InteractionStep describes an element interaction saved by the interaction
+// recorder. See WebDriver.SetInteractionRecorder.
+type InteractionStep struct {
+	// Step is the one-based index of the interaction.
+	Step int `json:"step"`
+	// Time is when the interaction started.
+	Time time.Time `json:"time"`
+	// Action is the interaction, e.g. "click" or "sendKeys".
+	Action string `json:"action"`
+	// Keys are the keys sent to the element, for "sendKeys" actions.
+	Keys string `json:"keys,omitempty"`
+	// Before and After are the names of the screenshots taken before and after
+	// the interaction, relative to the recorder's directory. The element is
+	// outlined in the Before screenshot.
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Error is the error returned by the interaction, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// interactionManifest is the name of the file, in the recorder's directory,
+// that lists the recorded steps in JSON.
+const interactionManifest = "manifest.json"
+
+// interactionRecorder saves screenshots around element interactions.
+type interactionRecorder struct {
+	dir   string
+	steps []InteractionStep
+}
+
+func (wd *remoteWD) SetInteractionRecorder(dir string) error {
+	if dir == "" {
+		wd.interactions = nil
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	wd.interactions = &interactionRecorder{dir: dir}
+	return nil
+}
+
+// viewportRectScript returns the bounding rectangle of the element provided as
+// the first argument, in viewport coordinates, and the device pixel ratio.
+const viewportRectScript = `
+var r = arguments[0].getBoundingClientRect();
+return {X: r.left, Y: r.top, Width: r.width, Height: r.height, Ratio: window.devicePixelRatio || 1};
+`
+
+// recordInteraction performs the interaction and, if the interaction recorder
+// is enabled, saves screenshots before and after it. If automatic highlighting
+// is enabled, the element is highlighted first.
+func (wd *remoteWD) recordInteraction(elem *remoteWE, action, keys string, interact func() error) error {
+	if highlightFlag {
+		if err := elem.Highlight(); err != nil {
+			debugLog("highlighting element before %s: %v", action, err)
+		}
+	}
+
+	if wd.actionLog != nil {
+		perform := interact
+		interact = func() error {
+			if err := perform(); err != nil {
+				return err
+			}
+			wd.logAction(LoggedAction{Type: action, Element: elem.locator, Keys: keys})
+			return nil
+		}
+	}
+
+	r := wd.interactions
+	if r == nil {
+		return interact()
+	}
+
+	step := InteractionStep{
+		Step:   len(r.steps) + 1,
+		Time:   time.Now(),
+		Action: action,
+		Keys:   keys,
+		Before: fmt.Sprintf("%03d-%s-before.png", len(r.steps)+1, action),
+		After:  fmt.Sprintf("%03d-%s-after.png", len(r.steps)+1, action),
+	}
+	recordErr := wd.saveBeforeScreenshot(elem, filepath.Join(r.dir, step.Before))
+
+	err := interact()
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	if afterErr := wd.saveScreenshot(filepath.Join(r.dir, step.After)); recordErr == nil {
+		recordErr = afterErr
+	}
+	r.steps = append(r.steps, step)
+	if manifestErr := r.writeManifest(); recordErr == nil {
+		recordErr = manifestErr
+	}
+
+	if err != nil {
+		return err
+	}
+	if recordErr != nil {
+		return fmt.Errorf("recording %s interaction: %v", action, recordErr)
+	}
+	return nil
+}
+
+func (wd *remoteWD) saveScreenshot(path string) error {
+	data, err := wd.Screenshot()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// saveBeforeScreenshot saves a screenshot in which the element is outlined.
+func (wd *remoteWD) saveBeforeScreenshot(elem *remoteWE, path string) error {
+	r := new(struct{ X, Y, Width, Height, Ratio float64 })
+	if err := wd.execScriptInto(viewportRectScript, []interface{}{elem}, r); err != nil {
+		return err
+	}
+	data, err := wd.Screenshot()
+	if err != nil {
+		return err
+	}
+	outline := image.Rect(
+		round(r.X*r.Ratio), round(r.Y*r.Ratio),
+		round((r.X+r.Width)*r.Ratio), round((r.Y+r.Height)*r.Ratio))
+	annotated, err := outlinePNG(data, outline)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, annotated, 0644)
+}
+
+// outlinePNG draws a red outline around the rectangle r in the PNG image data.
+func outlinePNG(data []byte, r image.Rectangle) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	const width = 3
+	red := image.NewUniform(color.RGBA{R: 255, A: 255})
+	for _, edge := range []image.Rectangle{
+		image.Rect(r.Min.X-width, r.Min.Y-width, r.Max.X+width, r.Min.Y),
+		image.Rect(r.Min.X-width, r.Max.Y, r.Max.X+width, r.Max.Y+width),
+		image.Rect(r.Min.X-width, r.Min.Y, r.Min.X, r.Max.Y),
+		image.Rect(r.Max.X, r.Min.Y, r.Max.X+width, r.Max.Y),
+	} {
+		draw.Draw(img, edge.Intersect(img.Bounds()), red, image.ZP, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *interactionRecorder) writeManifest() error {
+	data, err := json.MarshalIndent(r.steps, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(r.dir, interactionManifest), data, 0644)
+}
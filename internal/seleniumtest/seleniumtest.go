@@ -4,8 +4,15 @@
 package seleniumtest
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
 	"math"
 	"net"
 	"net/http"
@@ -18,6 +25,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -138,14 +146,46 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("PageSource", runTest(testPageSource, c))
 	t.Run("FindElement", runTest(testFindElement, c))
 	t.Run("FindElements", runTest(testFindElements, c))
+	t.Run("FindElementsText", runTest(testFindElementsText, c))
 	t.Run("SendKeys", runTest(testSendKeys, c))
 	t.Run("Click", runTest(testClick, c))
+	t.Run("ClickNoScroll", runTest(testClickNoScroll, c))
 	t.Run("GetCookies", runTest(testGetCookies, c))
 	t.Run("GetCookie", runTest(testGetCookie, c))
 	t.Run("AddCookie", runTest(testAddCookie, c))
 	t.Run("DeleteCookie", runTest(testDeleteCookie, c))
+	t.Run("ExportImportCookies", runTest(testExportImportCookies, c))
+	t.Run("Storage", runTest(testStorage, c))
 	t.Run("Location", runTest(testLocation, c))
 	t.Run("LocationInView", runTest(testLocationInView, c))
+	t.Run("IsInViewport", runTest(testIsInViewport, c))
+	t.Run("VisibilityDetails", runTest(testVisibilityDetails, c))
+	t.Run("ValidityState", runTest(testValidityState, c))
+	t.Run("LabelText", runTest(testLabelText, c))
+	t.Run("Center", runTest(testCenter, c))
+	t.Run("Highlight", runTest(testHighlight, c))
+	t.Run("Select", runTest(testSelect, c))
+	t.Run("SetValueJS", runTest(testSetValueJS, c))
+	t.Run("SetDateTime", runTest(testSetDateTime, c))
+	t.Run("ReplaceText", runTest(testReplaceText, c))
+	t.Run("ClickIfPresent", runTest(testClickIfPresent, c))
+	t.Run("HorizontalOverflow", runTest(testHorizontalOverflow, c))
+	t.Run("ScrollBy", runTest(testScrollBy, c))
+	t.Run("ViewportSize", runTest(testViewportSize, c))
+	t.Run("CSSVariable", runTest(testCSSVariable, c))
+	t.Run("StyleSheet", runTest(testStyleSheet, c))
+	t.Run("DocumentEncoding", runTest(testDocumentEncoding, c))
+	t.Run("SelectedText", runTest(testSelectedText, c))
+	t.Run("ElementOrder", runTest(testElementOrder, c))
+	t.Run("TabOrder", runTest(testTabOrder, c))
+	t.Run("RelativeLocators", runTest(testRelativeLocators, c))
+	t.Run("ScreenshotRegion", runTest(testScreenshotRegion, c))
+	t.Run("FullPageScreenshot", runTest(testFullPageScreenshot, c))
+	t.Run("Print", runTest(testPrint, c))
+	t.Run("NestedShadowRoots", runTest(testNestedShadowRoots, c))
+	t.Run("WaitUntilStable", runTest(testWaitUntilStable, c))
+	t.Run("WaitForAnimations", runTest(testWaitForAnimations, c))
+	t.Run("DropFile", runTest(testDropFile, c))
 	t.Run("Size", runTest(testSize, c))
 	t.Run("ExecuteScript", runTest(testExecuteScript, c))
 	t.Run("ExecuteScriptOnElement", runTest(testExecuteScriptOnElement, c))
@@ -158,13 +198,25 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("GetProperty", runTest(testGetProperty, c))
 	t.Run("GetPropertyNotFound", runTest(testGetPropertyNotFound, c))
 	t.Run("KeyDownUp", runTest(testKeyDownUp, c))
+	t.Run("Actions", runTest(testActions, c))
 	t.Run("CSSProperty", runTest(testCSSProperty, c))
 	if !c.SkipProxy {
 		t.Run("Proxy", runTest(testProxy, c))
 	}
 	t.Run("SwitchFrame", runTest(testSwitchFrame, c))
+	t.Run("SwitchToParentFrame", runTest(testSwitchToParentFrame, c))
+	t.Run("WithinFrame", runTest(testWithinFrame, c))
 	t.Run("Wait", runTest(testWait, c))
+	t.Run("WaitForTitle", runTest(testWaitForTitle, c))
+	t.Run("NumberOfWindowsToBe", runTest(testNumberOfWindowsToBe, c))
+	t.Run("NewWindow", runTest(testNewWindow, c))
+	t.Run("ElementConditions", runTest(testElementConditions, c))
+	t.Run("GetElement", runTest(testGetElement, c))
+	t.Run("MarshalSession", runTest(testMarshalSession, c))
+	t.Run("WaitForImages", runTest(testWaitForImages, c))
+	t.Run("InteractionRecorder", runTest(testInteractionRecorder, c))
 	t.Run("ActiveElement", runTest(testActiveElement, c))
+	t.Run("DeepActiveElement", runTest(testDeepActiveElement, c))
 	t.Run("AcceptAlert", runTest(testAcceptAlert, c))
 	t.Run("DismissAlert", runTest(testDismissAlert, c))
 }
@@ -392,6 +444,35 @@ func testWindows(t *testing.T, c Config) {
 		}
 	})
 
+	t.Run("WindowRect", func(t *testing.T) {
+		want := selenium.Rect{X: 30, Y: 40, Width: 600, Height: 400}
+		got, err := wd.SetWindowRect(want)
+		if err != nil {
+			t.Fatalf("wd.SetWindowRect(%+v) returned error: %v", want, err)
+		}
+		current, err := wd.GetWindowRect()
+		if err != nil {
+			t.Fatalf("wd.GetWindowRect() returned error: %v", err)
+		}
+		if current.Width != got.Width || current.Height != got.Height {
+			t.Errorf("wd.GetWindowRect() = %+v, want the size returned by wd.SetWindowRect(), %+v", current, got)
+		}
+	})
+
+	t.Run("SetWindowRectStable", func(t *testing.T) {
+		if err := wd.MaximizeWindow(""); err != nil {
+			t.Fatalf("wd.MaximizeWindow() returned error: %v", err)
+		}
+		want := selenium.Rect{X: 10, Y: 20, Width: 640, Height: 480}
+		got, err := wd.SetWindowRectStable(want)
+		if err != nil {
+			t.Fatalf("wd.SetWindowRectStable(%+v) returned error: %v", want, err)
+		}
+		if math.Abs(float64(got.Width-want.Width)) > 2 || math.Abs(float64(got.Height-want.Height)) > 2 {
+			t.Fatalf("wd.SetWindowRectStable(%+v) = %+v, want the requested size", want, got)
+		}
+	})
+
 	t.Run("CloseWindow", func(t *testing.T) {
 		if err := wd.CloseWindow(otherHandle); err != nil {
 			t.Fatalf("wd.CloseWindow(otherHandle) returned error: %v", err)
@@ -567,6 +648,36 @@ func testFindElements(t *testing.T, c Config) {
 	evaluateElement(t, wd, elems[0])
 }
 
+func testFindElementsText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const tag = "a"
+	got, err := wd.FindElementsText(selenium.ByTagName, tag)
+	if err != nil {
+		t.Fatalf("wd.FindElementsText(%q, %q) returned error: %v", selenium.ByTagName, tag, err)
+	}
+
+	elems, err := wd.FindElements(selenium.ByTagName, tag)
+	if err != nil {
+		t.Fatalf("wd.FindElements(%q, %q) returned error: %v", selenium.ByTagName, tag, err)
+	}
+	var want []string
+	for _, elem := range elems {
+		text, err := elem.Text()
+		if err != nil {
+			t.Fatalf("elem.Text() returned error: %v", err)
+		}
+		want = append(want, text)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("wd.FindElementsText(%q, %q) returned diff (-want/+got):\n%s", selenium.ByTagName, tag, diff)
+	}
+}
+
 func testSendKeys(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -666,6 +777,33 @@ func testClick(t *testing.T, c Config) {
 	}
 }
 
+func testClickNoScroll(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support the W3C actions API")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const checkboxID = "chuk"
+	checkbox, err := wd.FindElement(selenium.ByID, checkboxID)
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, checkboxID, err)
+	}
+	if err := checkbox.ClickNoScroll(); err != nil {
+		t.Fatalf("checkbox.ClickNoScroll() returned error: %v", err)
+	}
+	selected, err := checkbox.IsSelected()
+	if err != nil {
+		t.Fatalf("checkbox.IsSelected() returned error: %v", err)
+	}
+	if !selected {
+		t.Fatalf("checkbox.IsSelected() = false after ClickNoScroll, want true")
+	}
+}
+
 func testGetCookie(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -693,6 +831,13 @@ func testGetCookie(t *testing.T, c Config) {
 	if !reflect.DeepEqual(got, cookies[0]) {
 		t.Fatalf("wd.GetCookie(%q) = %+v, want %+v", cookies[0].Name, cookies[0], got)
 	}
+
+	const missing = "missing-cookie"
+	if _, err := wd.GetCookie(missing); err == nil {
+		t.Fatalf("wd.GetCookie(%q) returned nil error", missing)
+	} else if _, ok := err.(*selenium.NoSuchCookieError); !ok {
+		t.Errorf("wd.GetCookie(%q) returned error %v of type %T, want *selenium.NoSuchCookieError", missing, err, err)
+	}
 }
 
 func testGetCookies(t *testing.T, c Config) {
@@ -802,6 +947,102 @@ func testDeleteCookie(t *testing.T, c Config) {
 	}
 }
 
+func testStorage(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	for _, tc := range []struct {
+		name    string
+		storage selenium.Storage
+	}{
+		{"LocalStorage", wd.LocalStorage()},
+		{"SessionStorage", wd.SessionStorage()},
+	} {
+		s := tc.storage
+		if err := s.Clear(); err != nil {
+			t.Fatalf("%s().Clear() returned error: %v", tc.name, err)
+		}
+		for _, kv := range [][2]string{{"a", "1"}, {"b", "2"}} {
+			if err := s.SetItem(kv[0], kv[1]); err != nil {
+				t.Fatalf("%s().SetItem(%q, %q) returned error: %v", tc.name, kv[0], kv[1], err)
+			}
+		}
+		if v, ok, err := s.GetItem("a"); err != nil || !ok || v != "1" {
+			t.Errorf(`%s().GetItem("a") = %q, %t, %v, want "1", true, nil`, tc.name, v, ok, err)
+		}
+		if err := s.RemoveItem("a"); err != nil {
+			t.Fatalf(`%s().RemoveItem("a") returned error: %v`, tc.name, err)
+		}
+		if _, ok, err := s.GetItem("a"); err != nil || ok {
+			t.Errorf(`%s().GetItem("a") after RemoveItem returned ok = %t, error %v, want false, nil`, tc.name, ok, err)
+		}
+		if keys, err := s.Keys(); err != nil || !cmp.Equal(keys, []string{"b"}) {
+			t.Errorf("%s().Keys() = %v, %v, want [b], nil", tc.name, keys, err)
+		}
+		if n, err := s.Len(); err != nil || n != 1 {
+			t.Errorf("%s().Len() = %d, %v, want 1, nil", tc.name, n, err)
+		}
+	}
+}
+
+func testExportImportCookies(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	want := []*selenium.Cookie{
+		{Name: "session", Value: "s3cr3t", Domain: "127.0.0.1", Path: "/", Expiry: math.MaxUint32},
+		{Name: "expired", Value: "stale", Domain: "127.0.0.1", Path: "/", Expiry: math.MaxUint32},
+	}
+	for _, cookie := range want {
+		if err := wd.AddCookie(cookie); err != nil {
+			t.Fatalf("wd.AddCookie(%+v) returned error: %v", cookie, err)
+		}
+	}
+
+	data, err := wd.ExportCookies()
+	if err != nil {
+		t.Fatalf("wd.ExportCookies() returned error: %v", err)
+	}
+	var exported []selenium.Cookie
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("json.Unmarshal(%s) returned error: %v", data, err)
+	}
+	// Mark one cookie as expired, which should not be imported.
+	for i := range exported {
+		if exported[i].Name == "expired" {
+			exported[i].Expiry = 1
+		}
+	}
+	if data, err = json.Marshal(exported); err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+
+	if err := wd.DeleteAllCookies(); err != nil {
+		t.Fatalf("wd.DeleteAllCookies() returned error: %v", err)
+	}
+	if err := wd.ImportCookies(data); err != nil {
+		t.Fatalf("wd.ImportCookies(%s) returned error: %v", data, err)
+	}
+
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, cookie := range cookies {
+		got[cookie.Name] = cookie.Value
+	}
+	if diff := cmp.Diff(map[string]string{"session": "s3cr3t"}, got); diff != "" {
+		t.Fatalf("wd.GetCookies() after wd.ImportCookies() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
 func testLocation(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -831,6 +1072,12 @@ func testLocationInView(t *testing.T, c Config) {
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
+	// Push the button below the fold, so that its location in the viewport
+	// differs from its location in the document.
+	const spacer = `document.body.insertAdjacentHTML('afterbegin', '<div style="height: 3000px"></div>');`
+	if _, err := wd.ExecuteScript(spacer, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", spacer, err)
+	}
 	button, err := wd.FindElement(selenium.ByID, "submit")
 	if err != nil {
 		t.Fatal(err)
@@ -844,113 +1091,260 @@ func testLocationInView(t *testing.T, c Config) {
 	if loc.X == 0 || loc.Y == 0 {
 		t.Fatalf("Bad location: %v\n", loc)
 	}
+	height, err := wd.ExecuteScript("return window.innerHeight;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if float64(loc.Y) >= height.(float64) {
+		t.Fatalf("button.LocationInView() = %+v, which is outside of the viewport of height %v", loc, height)
+	}
 }
 
-func testSize(t *testing.T, c Config) {
+func testIsInViewport(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
+	const spacer = `document.body.insertAdjacentHTML('afterbegin', '<div style="height: 3000px"></div>');`
+	if _, err := wd.ExecuteScript(spacer, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", spacer, err)
+	}
 	button, err := wd.FindElement(selenium.ByID, "submit")
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "submit", err)
 	}
 
-	size, err := button.Size()
+	in, err := button.IsInViewport()
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("button.IsInViewport() returned error: %v", err)
+	}
+	if in {
+		t.Fatalf("button.IsInViewport() = true below the fold, want false")
 	}
 
-	if size.Width == 0 || size.Height == 0 {
-		t.Fatalf("Bad size: %v\n", size)
+	if _, err := button.LocationInView(); err != nil {
+		t.Fatalf("button.LocationInView() returned error: %v", err)
+	}
+	in, err = button.IsInViewport()
+	if err != nil {
+		t.Fatalf("button.IsInViewport() returned error: %v", err)
+	}
+	if !in {
+		t.Fatalf("button.IsInViewport() = false after scrolling it into view, want true")
 	}
 }
 
-func testExecuteScript(t *testing.T, c Config) {
+func testCenter(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	script := "return arguments[0] + arguments[1]"
-	args := []interface{}{1, 2}
-	reply, err := wd.ExecuteScript(script, args)
+	const box = `document.body.insertAdjacentHTML('afterbegin', '<div id="box" style="position: fixed; left: 10px; top: 20px; width: 101px; height: 51px"></div>');`
+	if _, err := wd.ExecuteScript(box, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", box, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "box")
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "box", err)
 	}
-
-	result, ok := reply.(float64)
-	if !ok {
-		t.Fatal("Not an int reply")
+	got, err := elem.Center()
+	if err != nil {
+		t.Fatalf("elem.Center() returned error: %v", err)
 	}
-
-	if result != 3 {
-		t.Fatalf("Bad result %d (expected 3)", int(result))
+	// 10 + 101/2 = 60.5 and 20 + 51/2 = 45.5 are rounded up.
+	if want := (&selenium.Point{X: 61, Y: 46}); *got != *want {
+		t.Fatalf("elem.Center() = %+v, want %+v", got, want)
 	}
 }
 
-func testExecuteScriptWithNilArgs(t *testing.T, c Config) {
+func testHighlight(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
+	input, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
+	}
+	const outline = "blue dotted 1px"
+	if _, err := wd.ExecuteScript("arguments[0].style.outline = arguments[1];", []interface{}{input, outline}); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	const getOutline = "return arguments[0].style.outline;"
+	want, err := wd.ExecuteScript(getOutline, []interface{}{input})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", getOutline, err)
+	}
 
-	script := "return document.readyState"
-	if _, err := wd.ExecuteScript(script, nil); err != nil {
-		t.Fatal(err)
+	// Exercise both the explicit and automatic highlighting.
+	selenium.SetHighlight(true, 10*time.Millisecond)
+	defer selenium.SetHighlight(false, 500*time.Millisecond)
+	if err := input.Highlight(); err != nil {
+		t.Fatalf("input.Highlight() returned error: %v", err)
+	}
+	if err := input.SendKeys(""); err != nil {
+		t.Fatalf("input.SendKeys() returned error: %v", err)
+	}
+
+	got, err := wd.ExecuteScript(getOutline, []interface{}{input})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", getOutline, err)
+	}
+	if got != want {
+		t.Fatalf("The outline after highlighting is %q, want it restored to %q", got, want)
 	}
 }
 
-func testExecuteScriptOnElement(t *testing.T, c Config) {
+func testSelect(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
+	optionValues := func(s *selenium.Select) []string {
+		t.Helper()
+		opts, err := s.SelectedOptions()
+		if err != nil {
+			t.Fatalf("SelectedOptions() returned error: %v", err)
+		}
+		var values []string
+		for _, opt := range opts {
+			v, err := opt.GetAttribute("value")
+			if err != nil {
+				t.Fatalf("GetAttribute(%q) returned error: %v", "value", err)
+			}
+			values = append(values, v)
+		}
+		return values
+	}
 
-	input, err := wd.FindElement(selenium.ByName, "q")
+	elem, err := wd.FindElement(selenium.ByName, "s")
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "s", err)
 	}
-
-	const query = "golang"
-	if err := input.SendKeys(query); err != nil {
-		t.Fatal(err)
+	s, err := wd.NewSelect(elem)
+	if err != nil {
+		t.Fatalf("wd.NewSelect() returned error: %v", err)
 	}
-
-	we, err := wd.FindElement(selenium.ByXPATH, "//input[@type='submit']")
+	if s.IsMultiple() {
+		t.Errorf("IsMultiple() = true for a single select")
+	}
+	opts, err := s.Options()
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("Options() returned error: %v", err)
+	}
+	if len(opts) != 2 {
+		t.Fatalf("Options() returned %d options, want 2", len(opts))
+	}
+	if err := s.SelectByVisibleText("Second Value"); err != nil {
+		t.Fatalf("SelectByVisibleText() returned error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"second_value"}, optionValues(s)); diff != "" {
+		t.Errorf("SelectedOptions() after SelectByVisibleText returned diff (-want/+got):\n%s", diff)
+	}
+	if err := s.SelectByValue("first_value"); err != nil {
+		t.Fatalf("SelectByValue() returned error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"first_value"}, optionValues(s)); diff != "" {
+		t.Errorf("SelectedOptions() after SelectByValue returned diff (-want/+got):\n%s", diff)
+	}
+	if err := s.SelectByValue("no such value"); err == nil {
+		t.Errorf("SelectByValue() with a missing value returned nil, want an error")
+	}
+	if err := s.DeselectAll(); err == nil {
+		t.Errorf("DeselectAll() on a single select returned nil, want an error")
 	}
 
-	script := "arguments[0].click()"
-	args := []interface{}{we}
+	const multi = `document.body.insertAdjacentHTML('beforeend', '<select id="multi" multiple><option value="a">A</option><option value="b">B</option><option value="c">C</option></select>');`
+	if _, err := wd.ExecuteScript(multi, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", multi, err)
+	}
+	if elem, err = wd.FindElement(selenium.ByID, "multi"); err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "multi", err)
+	}
+	if s, err = wd.NewSelect(elem); err != nil {
+		t.Fatalf("wd.NewSelect() returned error: %v", err)
+	}
+	if !s.IsMultiple() {
+		t.Errorf("IsMultiple() = false for a multiple select")
+	}
+	for _, i := range []int{0, 2} {
+		if err := s.SelectByIndex(i); err != nil {
+			t.Fatalf("SelectByIndex(%d) returned error: %v", i, err)
+		}
+	}
+	if diff := cmp.Diff([]string{"a", "c"}, optionValues(s)); diff != "" {
+		t.Errorf("SelectedOptions() after SelectByIndex returned diff (-want/+got):\n%s", diff)
+	}
+	if err := s.DeselectByValue("a"); err != nil {
+		t.Fatalf("DeselectByValue() returned error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"c"}, optionValues(s)); diff != "" {
+		t.Errorf("SelectedOptions() after DeselectByValue returned diff (-want/+got):\n%s", diff)
+	}
+	if err := s.DeselectAll(); err != nil {
+		t.Fatalf("DeselectAll() returned error: %v", err)
+	}
+	if got := optionValues(s); len(got) != 0 {
+		t.Errorf("SelectedOptions() after DeselectAll returned %v, want none", got)
+	}
 
-	if _, err = wd.ExecuteScript(script, args); err != nil {
-		t.Fatal(err)
+	if _, err := wd.NewSelect(opts[0]); err == nil {
+		t.Errorf("wd.NewSelect(<option>) returned nil, want an error")
 	}
+}
 
-	time.Sleep(500 * time.Millisecond)
+func testSetValueJS(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
 
-	source, err := wd.PageSource()
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	input, err := wd.FindElement(selenium.ByName, "q")
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
+	}
+	const listen = `
+window.events = [];
+['input', 'change'].forEach(function(type) {
+  document.addEventListener(type, function(e) { window.events.push(e.type + ':' + e.target.value); });
+});`
+	if _, err := wd.ExecuteScript(listen, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", listen, err)
 	}
 
-	if !strings.Contains(source, searchContents) {
-		t.Fatalf("Can't find %q on page after searching for %q", searchContents, query)
+	const value = "set by script"
+	if err := input.SetValueJS(value); err != nil {
+		t.Fatalf("input.SetValueJS(%q) returned error: %v", value, err)
+	}
+	got, err := input.GetProperty("value")
+	if err != nil {
+		t.Fatalf("input.GetProperty(%q) returned error: %v", "value", err)
+	}
+	if got != value {
+		t.Errorf("input value = %q, want %q", got, value)
+	}
+	events, err := wd.ExecuteScript("return window.events;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	want := []interface{}{"input:" + value, "change:" + value}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("Dispatched events returned diff (-want/+got):\n%s", diff)
 	}
 }
 
-func testScreenshot(t *testing.T, c Config) {
+func testSetDateTime(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
-		t.Skip("Skipping on htmlunit")
+		t.Skip("HTMLUnit does not support date and time inputs")
 	}
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -958,55 +1352,1220 @@ func testScreenshot(t *testing.T, c Config) {
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	data, err := wd.Screenshot()
+	const page = `document.body.insertAdjacentHTML('afterbegin',
+  '<input id="date" type="date"><input id="time" type="time"><input id="text" value="unchanged">');
+document.getElementById('text').addEventListener('input', function() {
+  window.textInputEvents = (window.textInputEvents || 0) + 1;
+});`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+	date, err := wd.FindElement(selenium.ByID, "date")
 	if err != nil {
-		t.Fatal(err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "date", err)
+	}
+	tm, err := wd.FindElement(selenium.ByID, "time")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "time", err)
 	}
 
-	if len(data) == 0 {
-		t.Fatal("Empty reply")
+	when := time.Date(2021, time.March, 4, 9, 5, 0, 0, time.UTC)
+	if err := date.SetDate(when); err != nil {
+		t.Fatalf("date.SetDate(%v) returned error: %v", when, err)
+	}
+	if got, err := date.GetProperty("value"); err != nil {
+		t.Fatalf("date.GetProperty(%q) returned error: %v", "value", err)
+	} else if want := "2021-03-04"; got != want {
+		t.Errorf("date value = %q, want %q", got, want)
+	}
+	if err := tm.SetTime(when); err != nil {
+		t.Fatalf("tm.SetTime(%v) returned error: %v", when, err)
+	}
+	if got, err := tm.GetProperty("value"); err != nil {
+		t.Fatalf("tm.GetProperty(%q) returned error: %v", "value", err)
+	} else if want := "09:05"; got != want {
+		t.Errorf("time value = %q, want %q", got, want)
 	}
-}
 
-func testLog(t *testing.T, c Config) {
-	switch {
-	case c.Browser == "htmlunit":
-		t.Skip("Skipping on htmlunit")
-	case c.Browser == "firefox" && (c.SeleniumVersion.Major == 3 || c.SeleniumVersion.Major == 0):
-		// Log is not supported on Firefox with Selenium 3.
-		// https://github.com/w3c/webdriver/issues/406
-		// https://github.com/mozilla/geckodriver/issues/284
-		t.Skip("The log interface is not supported on Firefox, since it is not yet part of the W3C spec.")
+	if err := tm.SetDate(when); err == nil {
+		t.Errorf("tm.SetDate(%v) on a time input returned nil error", when)
 	}
-	caps := newTestCapabilities(t, c)
-	caps.SetLogLevel(log.Browser, log.All)
-	if c.Browser == "chrome" {
-		caps.SetLogLevel(log.Performance, log.All)
+	if got, err := tm.GetProperty("value"); err != nil {
+		t.Fatalf("tm.GetProperty(%q) returned error: %v", "value", err)
+	} else if want := "09:05"; got != want {
+		t.Errorf("After tm.SetDate(%v) failed, time value = %q, want %q", when, got, want)
 	}
 
-	wd := newRemote(t, caps, c)
+	text, err := wd.FindElement(selenium.ByID, "text")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "text", err)
+	}
+	if err := text.SetDate(when); err == nil {
+		t.Errorf("text.SetDate(%v) on a text input returned nil error", when)
+	}
+	if got, err := text.GetProperty("value"); err != nil {
+		t.Fatalf("text.GetProperty(%q) returned error: %v", "value", err)
+	} else if want := "unchanged"; got != want {
+		t.Errorf("After text.SetDate(%v) failed, text value = %q, want %q", when, got, want)
+	}
+	const events = "return window.textInputEvents || 0;"
+	if n, err := wd.ExecuteScript(events, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", events, err)
+	} else if n != float64(0) {
+		t.Errorf("text.SetDate(%v) on a text input dispatched %v input events, want none", when, n)
+	}
+}
+
+func testReplaceText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
-	url := c.ServerURL + "/log"
-	if err := wd.Get(url); err != nil {
-		t.Fatalf("wd.Get(%q) returned error: %v", url, err)
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	logs, err := wd.Log(log.Browser)
+	input, err := wd.FindElement(selenium.ByName, "q")
 	if err != nil {
-		t.Fatalf("wd.Log(Browser) returned error: %v", err)
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
 	}
-	if len(logs) == 0 {
-		t.Fatalf("empty reply from wd.Log(Browser)")
-	} else {
-		for _, l := range logs {
-			if len(l.Level) == 0 || l.Timestamp.Unix() == 0 || len(l.Message) == 0 {
-				t.Errorf("wd.Log(Browser) returned malformed message: %+v", l)
-			}
-		}
+	if err := input.SendKeys("old text"); err != nil {
+		t.Fatalf("input.SendKeys() returned error: %v", err)
+	}
+	const listen = `
+window.values = [];
+arguments[0].addEventListener('input', function(e) { window.values.push(e.target.value); });`
+	if _, err := wd.ExecuteScript(listen, []interface{}{input}); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", listen, err)
 	}
 
-	if c.Browser == "chrome" {
-		logs, err = wd.Log(log.Performance)
+	const text = "new"
+	if err := input.ReplaceText(text); err != nil {
+		t.Fatalf("input.ReplaceText(%q) returned error: %v", text, err)
+	}
+	got, err := input.GetProperty("value")
+	if err != nil {
+		t.Fatalf("input.GetProperty(%q) returned error: %v", "value", err)
+	}
+	if got != text {
+		t.Errorf("input value = %q, want %q", got, text)
+	}
+	values, err := wd.ExecuteScript("return window.values;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	for _, v := range values.([]interface{}) {
+		if v == "" {
+			t.Fatalf("The input was empty during ReplaceText; values: %v", values)
+		}
+	}
+}
+
+func testClickIfPresent(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const banner = `document.body.insertAdjacentHTML('afterbegin',
+  '<button id="hidden" style="display: none">Hidden</button>' +
+  '<button id="accept" onclick="this.remove()">Accept</button>');`
+	if _, err := wd.ExecuteScript(banner, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", banner, err)
+	}
+
+	for _, tc := range []struct {
+		by, value string
+		want      bool
+	}{
+		{selenium.ByID, "missing", false},
+		{selenium.ByID, "hidden", false},
+		{selenium.ByID, "accept", true},
+		// The button removed itself when clicked.
+		{selenium.ByID, "accept", false},
+	} {
+		got, err := wd.ClickIfPresent(tc.by, tc.value)
+		if err != nil {
+			t.Fatalf("wd.ClickIfPresent(%q, %q) returned error: %v", tc.by, tc.value, err)
+		}
+		if got != tc.want {
+			t.Errorf("wd.ClickIfPresent(%q, %q) = %t, want %t", tc.by, tc.value, got, tc.want)
+		}
+	}
+}
+
+func testHorizontalOverflow(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	scroll, err := wd.HasHorizontalScroll()
+	if err != nil {
+		t.Fatalf("wd.HasHorizontalScroll() returned error: %v", err)
+	}
+	if scroll {
+		t.Fatalf("wd.HasHorizontalScroll() = true before adding a wide element, want false")
+	}
+
+	const wide = `document.body.insertAdjacentHTML('beforeend',
+  '<div id="wide" style="width: 5000px"><span>child</span></div>' +
+  '<div style="overflow: hidden; width: 100px"><div style="width: 5000px">clipped</div></div>');`
+	if _, err := wd.ExecuteScript(wide, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", wide, err)
+	}
+	if scroll, err = wd.HasHorizontalScroll(); err != nil {
+		t.Fatalf("wd.HasHorizontalScroll() returned error: %v", err)
+	}
+	if !scroll {
+		t.Fatalf("wd.HasHorizontalScroll() = false after adding a wide element, want true")
+	}
+
+	elems, err := wd.OverflowingElements()
+	if err != nil {
+		t.Fatalf("wd.OverflowingElements() returned error: %v", err)
+	}
+	var ids []string
+	for _, e := range elems {
+		id, err := e.GetAttribute("id")
+		if err != nil {
+			t.Fatalf("GetAttribute(%q) returned error: %v", "id", err)
+		}
+		ids = append(ids, id)
+	}
+	if diff := cmp.Diff([]string{"wide"}, ids); diff != "" {
+		t.Fatalf("wd.OverflowingElements() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func testSelectedText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const page = `document.body.insertAdjacentHTML('beforeend',
+  '<p id="text">Hello <b id="selected">selected</b> world</p><input id="field" value="abcdef">');`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+
+	for _, tc := range []struct {
+		desc, script, want string
+	}{
+		{
+			desc:   "nothing selected",
+			script: "window.getSelection().removeAllRanges();",
+			want:   "",
+		},
+		{
+			desc: "text in the document",
+			script: `var range = document.createRange();
+range.selectNodeContents(document.getElementById('selected'));
+window.getSelection().removeAllRanges();
+window.getSelection().addRange(range);`,
+			want: "selected",
+		},
+		{
+			desc: "text in a field",
+			script: `var field = document.getElementById('field');
+field.focus();
+field.setSelectionRange(1, 4);`,
+			want: "bcd",
+		},
+	} {
+		if _, err := wd.ExecuteScript(tc.script, nil); err != nil {
+			t.Fatalf("%s: wd.ExecuteScript(%q) returned error: %v", tc.desc, tc.script, err)
+		}
+		got, err := wd.SelectedText()
+		if err != nil {
+			t.Fatalf("%s: wd.SelectedText() returned error: %v", tc.desc, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: wd.SelectedText() = %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func testDocumentEncoding(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	charset, err := wd.DocumentCharset()
+	if err != nil {
+		t.Fatalf("wd.DocumentCharset() returned error: %v", err)
+	}
+	if !strings.EqualFold(charset, "utf-8") {
+		t.Errorf("wd.DocumentCharset() = %q, want %q", charset, "UTF-8")
+	}
+	contentType, err := wd.ContentType()
+	if err != nil {
+		t.Fatalf("wd.ContentType() returned error: %v", err)
+	}
+	if contentType != "text/html" {
+		t.Errorf("wd.ContentType() = %q, want %q", contentType, "text/html")
+	}
+}
+
+func testStyleSheet(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	elem, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
+	}
+	marginLeft := func() string {
+		t.Helper()
+		v, err := elem.CSSProperty("margin-left")
+		if err != nil {
+			t.Fatalf("elem.CSSProperty(%q) returned error: %v", "margin-left", err)
+		}
+		return v
+	}
+
+	const css = "input[name=q] { margin-left: 17px !important; }"
+	id, err := wd.AddStyleSheet(css)
+	if err != nil {
+		t.Fatalf("wd.AddStyleSheet(%q) returned error: %v", css, err)
+	}
+	if got := marginLeft(); got != "17px" {
+		t.Errorf("After wd.AddStyleSheet(%q), margin-left = %q, want %q", css, got, "17px")
+	}
+	if err := wd.RemoveStyleSheet(id); err != nil {
+		t.Fatalf("wd.RemoveStyleSheet(%q) returned error: %v", id, err)
+	}
+	if got := marginLeft(); got == "17px" {
+		t.Errorf("After wd.RemoveStyleSheet(%q), margin-left = %q, want the page's style", id, got)
+	}
+
+	// Removing a style sheet leaves the others in place.
+	const first = "input[name=q] { margin-left: 11px !important; }"
+	firstID, err := wd.AddStyleSheet(first)
+	if err != nil {
+		t.Fatalf("wd.AddStyleSheet(%q) returned error: %v", first, err)
+	}
+	const second = "input[name=q] { margin-left: 13px !important; }"
+	secondID, err := wd.AddStyleSheet(second)
+	if err != nil {
+		t.Fatalf("wd.AddStyleSheet(%q) returned error: %v", second, err)
+	}
+	if firstID == secondID {
+		t.Errorf("wd.AddStyleSheet() returned the ID %q twice", firstID)
+	}
+	if got := marginLeft(); got != "13px" {
+		t.Errorf("After adding two style sheets, margin-left = %q, want %q", got, "13px")
+	}
+	if err := wd.RemoveStyleSheet(secondID); err != nil {
+		t.Fatalf("wd.RemoveStyleSheet(%q) returned error: %v", secondID, err)
+	}
+	if got := marginLeft(); got != "11px" {
+		t.Errorf("After removing the second style sheet, margin-left = %q, want %q from the first", got, "11px")
+	}
+}
+
+func testVisibilityDetails(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support elementFromPoint")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const page = `document.body.insertAdjacentHTML('afterbegin',
+  '<div id="visible">Visible</div>' +
+  '<div style="opacity: 0.5"><div id="faded" style="opacity: 0.5">Faded</div></div>' +
+  '<div style="display: none"><div id="hidden">Hidden</div></div>' +
+  '<div id="invisible" style="visibility: hidden">Invisible</div>' +
+  '<div style="position: relative">' +
+  '<div id="covered">Covered</div>' +
+  '<div style="position: absolute; top: 0; left: 0; width: 100%; height: 100%; background: white"></div>' +
+  '</div>' +
+  '<div style="height: 5000px"></div>' +
+  '<div id="below">Below the fold</div>');`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want selenium.VisibilityInfo
+	}{
+		{"visible", selenium.VisibilityInfo{Opacity: 1, InViewport: true}},
+		{"faded", selenium.VisibilityInfo{Opacity: 0.25, InViewport: true}},
+		{"hidden", selenium.VisibilityInfo{Opacity: 1, DisplayNone: true}},
+		{"invisible", selenium.VisibilityInfo{Opacity: 1, InViewport: true, VisibilityHidden: true}},
+		{"covered", selenium.VisibilityInfo{Opacity: 1, InViewport: true, Occluded: true}},
+		{"below", selenium.VisibilityInfo{Opacity: 1}},
+	} {
+		elem, err := wd.FindElement(selenium.ByID, tc.id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, tc.id, err)
+		}
+		got, err := elem.VisibilityDetails()
+		if err != nil {
+			t.Fatalf("elem.VisibilityDetails() for %q returned error: %v", tc.id, err)
+		}
+		if got != tc.want {
+			t.Errorf("elem.VisibilityDetails() for %q = %+v, want %+v", tc.id, got, tc.want)
+		}
+	}
+}
+
+func testValidityState(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support constraint validation")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const page = `document.body.insertAdjacentHTML('afterbegin',
+  '<input id="required" required>' +
+  '<input id="email" type="email" value="not an email">' +
+  '<input id="pattern" pattern="[0-9]+" value="abc">' +
+  '<input id="number" type="number" min="1" max="5" value="7">' +
+  '<input id="valid" value="ok">' +
+  '<div id="div"></div>');`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+
+	for _, tc := range []struct {
+		id   string
+		want selenium.ValidityState
+	}{
+		{"required", selenium.ValidityState{ValueMissing: true}},
+		{"email", selenium.ValidityState{TypeMismatch: true}},
+		{"pattern", selenium.ValidityState{PatternMismatch: true}},
+		{"number", selenium.ValidityState{RangeOverflow: true}},
+		{"valid", selenium.ValidityState{Valid: true}},
+	} {
+		elem, err := wd.FindElement(selenium.ByID, tc.id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, tc.id, err)
+		}
+		got, err := elem.ValidityState()
+		if err != nil {
+			t.Fatalf("elem.ValidityState() for %q returned error: %v", tc.id, err)
+		}
+		if got != tc.want {
+			t.Errorf("elem.ValidityState() for %q = %+v, want %+v", tc.id, got, tc.want)
+		}
+		msg, err := elem.ValidationMessage()
+		if err != nil {
+			t.Fatalf("elem.ValidationMessage() for %q returned error: %v", tc.id, err)
+		}
+		if (msg == "") != tc.want.Valid {
+			t.Errorf("elem.ValidationMessage() for %q = %q, want a message only for invalid fields", tc.id, msg)
+		}
+	}
+
+	elem, err := wd.FindElement(selenium.ByID, "div")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "div", err)
+	}
+	if _, err := elem.ValidityState(); err == nil {
+		t.Errorf("elem.ValidityState() for a div returned nil error")
+	}
+}
+
+func testLabelText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const page = `document.body.insertAdjacentHTML('afterbegin',
+  '<label for="for">  Full\n name </label><input id="for">' +
+  '<label>Email <input id="wrapped"></label>' +
+  '<input id="aria" aria-label="Search">' +
+  '<span id="first">Billing</span><span id="second">address</span>' +
+  '<label for="labelledby">Ignored</label><input id="labelledby" aria-labelledby="first second">' +
+  '<input id="none">');`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+
+	for _, tc := range []struct {
+		id, want string
+	}{
+		{"for", "Full name"},
+		{"wrapped", "Email"},
+		{"aria", "Search"},
+		{"labelledby", "Billing address"},
+		{"none", ""},
+	} {
+		elem, err := wd.FindElement(selenium.ByID, tc.id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, tc.id, err)
+		}
+		got, err := elem.LabelText()
+		if err != nil {
+			t.Fatalf("elem.LabelText() for %q returned error: %v", tc.id, err)
+		}
+		if got != tc.want {
+			t.Errorf("elem.LabelText() for %q = %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
+
+func testCSSVariable(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support CSS custom properties")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const theme = `document.body.insertAdjacentHTML('beforeend',
+  '<div style="--primary-color: #336699"><span id="themed" style="color: var(--primary-color)">Themed</span></div>');`
+	if _, err := wd.ExecuteScript(theme, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", theme, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "themed")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "themed", err)
+	}
+	for _, name := range []string{"--primary-color", "primary-color"} {
+		got, err := elem.CSSVariable(name)
+		if err != nil {
+			t.Fatalf("elem.CSSVariable(%q) returned error: %v", name, err)
+		}
+		if got != "#336699" {
+			t.Errorf("elem.CSSVariable(%q) = %q, want %q", name, got, "#336699")
+		}
+	}
+	if got, err := elem.CSSVariable("unset"); err != nil || got != "" {
+		t.Errorf("elem.CSSVariable(%q) = %q, %v, want an empty value", "unset", got, err)
+	}
+}
+
+func testViewportSize(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	width, height, err := wd.ViewportSize()
+	if err != nil {
+		t.Fatalf("wd.ViewportSize() returned error: %v", err)
+	}
+	if width <= 0 || height <= 0 {
+		t.Fatalf("wd.ViewportSize() = %d, %d, want a positive size", width, height)
+	}
+	query := fmt.Sprintf("(width: %dpx) and (height: %dpx)", width, height)
+	matches, err := wd.ExecuteScript("return window.matchMedia(arguments[0]).matches;", []interface{}{query})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if matches != true {
+		t.Fatalf("The media query %q does not match the viewport size returned by wd.ViewportSize()", query)
+	}
+}
+
+func testRelativeLocators(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// A 3x3 grid of 50px cells, 20px apart, named after compass points.
+	const grid = `
+var names = [['nw', 'n', 'ne'], ['w', 'c', 'e'], ['sw', 's', 'se']];
+for (var row = 0; row < 3; row++) {
+  for (var col = 0; col < 3; col++) {
+    document.body.insertAdjacentHTML('beforeend', '<div class="cell" id="' + names[row][col] +
+      '" style="position: absolute; width: 50px; height: 50px; left: ' + (100 + col * 70) +
+      'px; top: ' + (100 + row * 70) + 'px">' + names[row][col] + '</div>');
+  }
+}`
+	if _, err := wd.ExecuteScript(grid, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", grid, err)
+	}
+	center, err := wd.FindElement(selenium.ByID, "c")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "c", err)
+	}
+	north, err := wd.FindElement(selenium.ByID, "n")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "n", err)
+	}
+
+	ids := func(l *selenium.RelativeLocator) []string {
+		t.Helper()
+		elems, err := wd.FindRelativeElements(l)
+		if err != nil {
+			t.Fatalf("wd.FindRelativeElements(%s) returned error: %v", l, err)
+		}
+		var ids []string
+		for _, e := range elems {
+			id, err := e.GetAttribute("id")
+			if err != nil {
+				t.Fatalf("GetAttribute(%q) returned error: %v", "id", err)
+			}
+			ids = append(ids, id)
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		l    *selenium.RelativeLocator
+		want []string
+	}{
+		{selenium.RelativeBy(selenium.ByCSSSelector, ".cell").Below(center), []string{"s", "sw", "se"}},
+		{selenium.RelativeBy(selenium.ByCSSSelector, ".cell").Above(center).ToLeftOf(center), []string{"nw"}},
+		{selenium.RelativeBy(selenium.ByCSSSelector, ".cell").ToRightOf(center).Below(north), []string{"e", "se"}},
+		{selenium.RelativeBy(selenium.ByCSSSelector, ".cell").Near(center, 25), []string{"n", "w", "e", "s"}},
+	} {
+		if diff := cmp.Diff(tc.want, ids(tc.l)); diff != "" {
+			t.Errorf("wd.FindRelativeElements(%s) returned diff (-want/+got):\n%s", tc.l, diff)
+		}
+	}
+
+	l := selenium.RelativeBy(selenium.ByCSSSelector, ".cell").Below(center)
+	elem, err := wd.FindRelativeElement(l)
+	if err != nil {
+		t.Fatalf("wd.FindRelativeElement(%s) returned error: %v", l, err)
+	}
+	if id, err := elem.GetAttribute("id"); err != nil || id != "s" {
+		t.Errorf("wd.FindRelativeElement(%s) returned element with id %q, %v, want %q", l, id, err, "s")
+	}
+
+	const remove = "arguments[0].parentNode.removeChild(arguments[0]);"
+	if _, err := wd.ExecuteScript(remove, []interface{}{center}); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", remove, err)
+	}
+	if _, err := wd.FindRelativeElements(l); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("wd.FindRelativeElements(%s) with a stale reference element returned error %v, want an error about staleness", l, err)
+	}
+}
+
+func testTabOrder(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not move the focus with the Tab key")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const page = `document.body.insertAdjacentHTML('beforeend',
+  '<input id="before">' +
+  '<form id="form">' +
+  '<input id="first">' +
+  '<input id="skipped" tabindex="-1">' +
+  '<button id="second" type="button">Button</button>' +
+  '<input id="disabled" disabled>' +
+  '<a id="third" href="#">Link</a>' +
+  '<input id="positive" tabindex="1">' +
+  '</form>' +
+  '<input id="after">');`
+	if _, err := wd.ExecuteScript(page, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", page, err)
+	}
+	form, err := wd.FindElement(selenium.ByID, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "form", err)
+	}
+
+	elems, err := wd.TabOrder(form)
+	if err != nil {
+		t.Fatalf("wd.TabOrder() returned error: %v", err)
+	}
+	var got []string
+	for _, e := range elems {
+		id, err := e.GetAttribute("id")
+		if err != nil {
+			t.Fatalf("e.GetAttribute(%q) returned error: %v", "id", err)
+		}
+		got = append(got, id)
+	}
+	// Elements with a positive tabindex come first.
+	want := []string{"positive", "first", "second", "third"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wd.TabOrder() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func testElementOrder(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// "second" is rendered before "first", and "child" is inside "first".
+	const list = `document.body.insertAdjacentHTML('beforeend',
+  '<div style="display: flex; flex-direction: column-reverse">' +
+  '<div id="first">First <span id="child">child</span></div>' +
+  '<div id="second">Second</div></div>');`
+	if _, err := wd.ExecuteScript(list, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", list, err)
+	}
+	elems := make(map[string]selenium.WebElement)
+	for _, id := range []string{"first", "child", "second"} {
+		e, err := wd.FindElement(selenium.ByID, id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, id, err)
+		}
+		elems[id] = e
+	}
+
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"first", "second", true},
+		{"second", "first", false},
+		{"first", "child", true},
+		{"child", "first", false},
+		{"first", "first", false},
+	} {
+		got, err := wd.ElementsInDocumentOrder(elems[tc.a], elems[tc.b])
+		if err != nil {
+			t.Fatalf("wd.ElementsInDocumentOrder(%s, %s) returned error: %v", tc.a, tc.b, err)
+		}
+		if got != tc.want {
+			t.Errorf("wd.ElementsInDocumentOrder(%s, %s) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"second", "first", true},
+		{"first", "second", false},
+	} {
+		got, err := wd.ElementsInVisualOrder(elems[tc.a], elems[tc.b])
+		if err != nil {
+			t.Fatalf("wd.ElementsInVisualOrder(%s, %s) returned error: %v", tc.a, tc.b, err)
+		}
+		if got != tc.want {
+			t.Errorf("wd.ElementsInVisualOrder(%s, %s) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func testPrint(t *testing.T, c Config) {
+	if !c.Headless || (c.Browser != "chrome" && c.Browser != "firefox") {
+		t.Skip("Printing is only supported by headless Chrome and Firefox")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	cm := func(v float64) *float64 { return &v }
+	opts := selenium.PrintOptions{
+		Orientation: selenium.PrintLandscape,
+		Background:  true,
+		Margin:      &selenium.PrintMargin{Top: cm(1), Bottom: cm(1), Left: cm(2), Right: cm(2)},
+	}
+	data, err := wd.Print(opts)
+	if err != nil {
+		t.Fatalf("wd.Print(%+v) returned error: %v", opts, err)
+	}
+
+	f, err := ioutil.TempFile("", "print*.pdf")
+	if err != nil {
+		t.Fatalf("ioutil.TempFile() returned error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Writing the PDF returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Closing the PDF returned error: %v", err)
+	}
+	written, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile(%q) returned error: %v", f.Name(), err)
+	}
+	if !bytes.HasPrefix(written, []byte("%PDF-")) {
+		t.Fatalf("wd.Print() returned %d bytes that do not start with the PDF header", len(written))
+	}
+}
+
+func testFullPageScreenshot(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// A red box at the bottom of a page taller than the viewport.
+	const tall = `document.body.insertAdjacentHTML('beforeend',
+  '<div style="height: 3000px"></div><div style="height: 50px; background: red"></div>');
+return [document.documentElement.scrollHeight, window.devicePixelRatio || 1];`
+	v, err := wd.ExecuteScript(tall, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", tall, err)
+	}
+	height, ratio := v.([]interface{})[0].(float64), v.([]interface{})[1].(float64)
+
+	data, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("wd.FullPageScreenshot() returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	b := img.Bounds()
+	if want := int(height * ratio); b.Dy() < want-1 || b.Dy() > want+1 {
+		t.Fatalf("The screenshot is %d pixels high, want %d", b.Dy(), want)
+	}
+	// The box ends 8px, the default margin of the body, above the bottom.
+	p := image.Pt(b.Min.X+b.Dx()/2, b.Max.Y-int(20*ratio))
+	if r, g, bl, _ := img.At(p.X, p.Y).RGBA(); r>>8 < 250 || g>>8 > 5 || bl>>8 > 5 {
+		t.Errorf("The screenshot pixel at %v is %v, want red", p, img.At(p.X, p.Y))
+	}
+
+	y, err := wd.ExecuteScript("return window.pageYOffset;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if y != float64(0) {
+		t.Errorf("After wd.FullPageScreenshot(), the page is scrolled to %v, want 0", y)
+	}
+}
+
+func testScreenshotRegion(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const box = `document.body.insertAdjacentHTML('beforeend',
+  '<div style="position: absolute; left: 20px; top: 30px; width: 100px; height: 50px; background: red"></div>' +
+  '<div style="position: absolute; left: 20px; top: 3000px; width: 100px; height: 50px; background: red"></div>');
+return window.devicePixelRatio || 1;`
+	v, err := wd.ExecuteScript(box, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", box, err)
+	}
+	ratio := v.(float64)
+
+	// The second box is outside of the viewport.
+	for _, rect := range []selenium.Rect{
+		{X: 20, Y: 30, Width: 100, Height: 50},
+		{X: 20, Y: 3000, Width: 100, Height: 50},
+	} {
+		data, err := wd.ScreenshotRegion(rect)
+		if err != nil {
+			t.Fatalf("wd.ScreenshotRegion(%+v) returned error: %v", rect, err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("png.Decode() returned error: %v", err)
+		}
+		b := img.Bounds()
+		if want := int(100 * ratio); b.Dx() != want {
+			t.Errorf("The screenshot of %+v is %d pixels wide, want %d", rect, b.Dx(), want)
+		}
+		if want := int(50 * ratio); b.Dy() != want {
+			t.Errorf("The screenshot of %+v is %d pixels high, want %d", rect, b.Dy(), want)
+		}
+		for _, p := range []image.Point{b.Min, {b.Max.X - 1, b.Max.Y - 1}, {(b.Min.X + b.Max.X) / 2, (b.Min.Y + b.Max.Y) / 2}} {
+			if r, g, bl, _ := img.At(p.X, p.Y).RGBA(); r>>8 < 250 || g>>8 > 5 || bl>>8 > 5 {
+				t.Errorf("The screenshot pixel of %+v at %v is %v, want red", rect, p, img.At(p.X, p.Y))
+			}
+		}
+	}
+}
+
+func testScrollBy(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const list = `document.body.insertAdjacentHTML('beforeend',
+  '<div id="list" style="overflow: auto; width: 100px; height: 100px">' +
+  '<div style="width: 300px; height: 1000px"></div></div>');`
+	if _, err := wd.ExecuteScript(list, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", list, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "list")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "list", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := elem.ScrollBy(20, 150); err != nil {
+			t.Fatalf("elem.ScrollBy() returned error: %v", err)
+		}
+	}
+	got, err := wd.ExecuteScript("return [arguments[0].scrollLeft, arguments[0].scrollTop];", []interface{}{elem})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if diff := cmp.Diff([]interface{}{float64(40), float64(300)}, got); diff != "" {
+		t.Fatalf("Scroll position returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func testNestedShadowRoots(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support shadow roots")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// Each level's shadow root contains the host of the next one.
+	const hosts = `
+var parent = document.body;
+for (var i = 0; i < 3; i++) {
+  var host = document.createElement('div');
+  host.id = 'host';
+  parent.appendChild(host);
+  parent = host.attachShadow({mode: 'open'});
+}
+parent.innerHTML = '<span id="deep">Deep</span>';`
+	if _, err := wd.ExecuteScript(hosts, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", hosts, err)
+	}
+
+	host, err := wd.FindElement(selenium.ByID, "host")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "host", err)
+	}
+	var root selenium.ShadowRoot
+	for i := 0; i < 3; i++ {
+		if root, err = host.GetShadowRoot(); err != nil {
+			t.Fatalf("Level %d: host.GetShadowRoot() returned error: %v", i, err)
+		}
+		if i == 2 {
+			break
+		}
+		if host, err = root.FindElement(selenium.ByCSSSelector, "#host"); err != nil {
+			t.Fatalf("Level %d: root.FindElement(%q, %q) returned error: %v", i, selenium.ByCSSSelector, "#host", err)
+		}
+	}
+	elem, err := root.FindElement(selenium.ByCSSSelector, "#deep")
+	if err != nil {
+		t.Fatalf("root.FindElement(%q, %q) returned error: %v", selenium.ByCSSSelector, "#deep", err)
+	}
+	if text, err := elem.Text(); err != nil || text != "Deep" {
+		t.Fatalf("elem.Text() = %q, %v, want %q", text, err, "Deep")
+	}
+
+	if _, err := elem.GetShadowRoot(); err == nil {
+		t.Fatalf("elem.GetShadowRoot() of an element without a shadow root returned no error")
+	} else if _, ok := err.(*selenium.NoSuchShadowRootError); !ok {
+		t.Errorf("elem.GetShadowRoot() of an element without a shadow root returned error %v of type %T, want *selenium.NoSuchShadowRootError", err, err)
+	}
+}
+
+func testWaitUntilStable(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// The box slides 300px to the right over one second.
+	const box = `
+document.body.insertAdjacentHTML('beforeend',
+  '<div id="box" style="position: absolute; left: 0; top: 0; width: 50px; height: 50px; transition: left 1s linear">Box</div>');
+var box = document.getElementById('box');
+box.getBoundingClientRect();
+box.style.left = '300px';`
+	if _, err := wd.ExecuteScript(box, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", box, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "box")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "box", err)
+	}
+	if err := elem.WaitUntilStable(10 * time.Second); err != nil {
+		t.Fatalf("elem.WaitUntilStable() returned error: %v", err)
+	}
+	loc, err := elem.Location()
+	if err != nil {
+		t.Fatalf("elem.Location() returned error: %v", err)
+	}
+	if loc.X != 300 {
+		t.Fatalf("elem.Location().X = %d after WaitUntilStable, want 300", loc.X)
+	}
+}
+
+func testWaitForAnimations(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support the Web Animations API")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// The inner box fades in over one second without moving.
+	const box = `
+document.body.insertAdjacentHTML('beforeend',
+  '<div id="box"><div id="inner" style="opacity: 0; transition: opacity 1s linear">Box</div></div>');
+var inner = document.getElementById('inner');
+inner.getBoundingClientRect();
+inner.style.opacity = '1';`
+	if _, err := wd.ExecuteScript(box, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", box, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "box")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "box", err)
+	}
+	if err := elem.WaitForAnimations(10 * time.Second); err != nil {
+		t.Fatalf("elem.WaitForAnimations() returned error: %v", err)
+	}
+	const script = "return window.getComputedStyle(document.getElementById('inner')).opacity;"
+	opacity, err := wd.ExecuteScript(script, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	if opacity != "1" {
+		t.Fatalf("opacity = %v after WaitForAnimations, want 1", opacity)
+	}
+
+	const infinite = `document.getElementById('inner').animate([{opacity: 0}, {opacity: 1}], {duration: 1000, iterations: Infinity});`
+	if _, err := wd.ExecuteScript(infinite, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", infinite, err)
+	}
+	if err := elem.WaitForAnimations(time.Second); err == nil {
+		t.Errorf("elem.WaitForAnimations() with an infinite animation returned nil error")
+	}
+}
+
+func testDropFile(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	dir, err := ioutil.TempDir("", "dropfile")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "upload.txt")
+	const contents = "dropped contents"
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile() returned error: %v", err)
+	}
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const zone = `
+document.body.insertAdjacentHTML('beforeend', '<div id="zone" style="width: 100px; height: 100px">Drop here</div>');
+var zone = document.getElementById('zone');
+zone.addEventListener('dragover', function(e) { e.preventDefault(); });
+zone.addEventListener('drop', function(e) {
+  e.preventDefault();
+  var f = e.dataTransfer.files[0];
+  window.dropped = [f.name, f.type, String(f.size)];
+});`
+	if _, err := wd.ExecuteScript(zone, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", zone, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "zone")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "zone", err)
+	}
+	if err := elem.DropFile(path); err != nil {
+		t.Fatalf("elem.DropFile(%q) returned error: %v", path, err)
+	}
+	got, err := wd.ExecuteScript("return window.dropped;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	want := []interface{}{"upload.txt", "text/plain", strconv.Itoa(len(contents))}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("The dropped file returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func testSize(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	button, err := wd.FindElement(selenium.ByID, "submit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := button.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size.Width == 0 || size.Height == 0 {
+		t.Fatalf("Bad size: %v\n", size)
+	}
+}
+
+func testExecuteScript(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	script := "return arguments[0] + arguments[1]"
+	args := []interface{}{1, 2}
+	reply, err := wd.ExecuteScript(script, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, ok := reply.(float64)
+	if !ok {
+		t.Fatal("Not an int reply")
+	}
+
+	if result != 3 {
+		t.Fatalf("Bad result %d (expected 3)", int(result))
+	}
+}
+
+func testExecuteScriptWithNilArgs(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	script := "return document.readyState"
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testExecuteScriptOnElement(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	input, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const query = "golang"
+	if err := input.SendKeys(query); err != nil {
+		t.Fatal(err)
+	}
+
+	we, err := wd.FindElement(selenium.ByXPATH, "//input[@type='submit']")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := "arguments[0].click()"
+	args := []interface{}{we}
+
+	if _, err = wd.ExecuteScript(script, args); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	source, err := wd.PageSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(source, searchContents) {
+		t.Fatalf("Can't find %q on page after searching for %q", searchContents, query)
+	}
+}
+
+func testScreenshot(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("Skipping on htmlunit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	data, err := wd.Screenshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) == 0 {
+		t.Fatal("Empty reply")
+	}
+}
+
+func testLog(t *testing.T, c Config) {
+	switch {
+	case c.Browser == "htmlunit":
+		t.Skip("Skipping on htmlunit")
+	case c.Browser == "firefox" && (c.SeleniumVersion.Major == 3 || c.SeleniumVersion.Major == 0):
+		// Log is not supported on Firefox with Selenium 3.
+		// https://github.com/w3c/webdriver/issues/406
+		// https://github.com/mozilla/geckodriver/issues/284
+		t.Skip("The log interface is not supported on Firefox, since it is not yet part of the W3C spec.")
+	}
+	caps := newTestCapabilities(t, c)
+	caps.SetLogLevel(log.Browser, log.All)
+	if c.Browser == "chrome" {
+		caps.SetLogLevel(log.Performance, log.All)
+	}
+
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	url := c.ServerURL + "/log"
+	if err := wd.Get(url); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", url, err)
+	}
+	logs, err := wd.Log(log.Browser)
+	if err != nil {
+		t.Fatalf("wd.Log(Browser) returned error: %v", err)
+	}
+	if len(logs) == 0 {
+		t.Fatalf("empty reply from wd.Log(Browser)")
+	} else {
+		for _, l := range logs {
+			if len(l.Level) == 0 || l.Timestamp.Unix() == 0 || len(l.Message) == 0 {
+				t.Errorf("wd.Log(Browser) returned malformed message: %+v", l)
+			}
+		}
+	}
+
+	if c.Browser == "chrome" {
+		logs, err = wd.Log(log.Performance)
 		if err != nil {
 			t.Fatalf("wd.Log(Performance) returned error: %v", err)
 		}
@@ -1150,26 +2709,69 @@ func testGetPropertyNotFound(t *testing.T, c Config) {
 
 func testActiveElement(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
-		// TODO(minusnine): figure out why ActiveElement doesn't work in HTMLUnit.
+		// TODO(minusnine): figure out why ActiveElement doesn't work in HTMLUnit.
+		t.Skip("ActiveElement does not work in HTMLUnit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	e, err := wd.ActiveElement()
+	if err != nil {
+		t.Fatalf("wd.ActiveElement() returned error: %v", err)
+	}
+	name, err := e.GetAttribute("name")
+	if err != nil {
+		t.Fatalf("wd.ActiveElement().GetAttribute() returned error: %v", err)
+	}
+	if name != "q" {
+		t.Fatalf("wd.ActiveElement().GetAttribute() returned element with name = %q, expected name = 'q'", name)
+	}
+}
+
+func testDeepActiveElement(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
 		t.Skip("ActiveElement does not work in HTMLUnit")
 	}
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
-	if err := wd.Get(c.ServerURL); err != nil {
-		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	if err := wd.Get(c.ServerURL + "/frame"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/frame", err)
+	}
+	if err := wd.SwitchFrame("iframeID"); err != nil {
+		t.Fatalf("wd.SwitchFrame(%q) returned error: %v", "iframeID", err)
+	}
+	const focus = "document.getElementById('chuk').focus();"
+	if _, err := wd.ExecuteScript(focus, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", focus, err)
+	}
+	if err := wd.SwitchFrame(nil); err != nil {
+		t.Fatalf("wd.SwitchFrame(nil) returned error: %v", err)
 	}
 
-	e, err := wd.ActiveElement()
+	elem, frames, err := wd.DeepActiveElement()
 	if err != nil {
-		t.Fatalf("wd.ActiveElement() returned error: %v", err)
+		t.Fatalf("wd.DeepActiveElement() returned error: %v", err)
 	}
-	name, err := e.GetAttribute("name")
+	id, err := elem.GetAttribute("id")
 	if err != nil {
-		t.Fatalf("wd.ActiveElement().GetAttribute() returned error: %v", err)
+		t.Fatalf("elem.GetAttribute(%q) returned error: %v", "id", err)
 	}
-	if name != "q" {
-		t.Fatalf("wd.ActiveElement().GetAttribute() returned element with name = %q, expected name = 'q'", name)
+	if id != "chuk" {
+		t.Errorf("wd.DeepActiveElement() returned element with id = %q, want %q", id, "chuk")
+	}
+	if len(frames) != 1 {
+		t.Fatalf("wd.DeepActiveElement() returned %d frames, want 1", len(frames))
+	}
+	if err := wd.SwitchFrame(nil); err != nil {
+		t.Fatalf("wd.SwitchFrame(nil) returned error: %v", err)
+	}
+	if id, err := frames[0].GetAttribute("id"); err != nil || id != "iframeID" {
+		t.Errorf("frames[0].GetAttribute(%q) = %q, %v, want %q", "id", id, err, "iframeID")
 	}
 }
 
@@ -1197,6 +2799,54 @@ func testKeyDownUp(t *testing.T, c Config) {
 	}
 }
 
+func testActions(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support the W3C actions API")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const target = `
+document.body.insertAdjacentHTML('beforeend', '<div id="target" style="width: 100px; height: 100px">Target</div>');
+window.__clicks = [];
+document.getElementById('target').addEventListener('click', function(e) {
+  window.__clicks.push({shift: e.shiftKey, x: e.offsetX, y: e.offsetY});
+});`
+	if _, err := wd.ExecuteScript(target, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", target, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "target")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "target", err)
+	}
+
+	err = wd.NewActions().
+		KeyDown(selenium.ShiftKey).
+		PointerMove(elem, 10, 0, 0).
+		PointerDown(selenium.LeftButton).
+		PointerUp(selenium.LeftButton).
+		KeyUp(selenium.ShiftKey).
+		Perform()
+	if err != nil {
+		t.Fatalf("Perform() returned error: %v", err)
+	}
+	if err := wd.ReleaseActions(); err != nil {
+		t.Fatalf("wd.ReleaseActions() returned error: %v", err)
+	}
+
+	clicks, err := wd.ExecuteScript("return window.__clicks;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	want := []interface{}{map[string]interface{}{"shift": true, "x": float64(60), "y": float64(50)}}
+	if diff := cmp.Diff(want, clicks); diff != "" {
+		t.Fatalf("The clicks returned diff (-want/+got):\n%s", diff)
+	}
+}
+
 func testCSSProperty(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
 		t.Skip("Skipping on htmlunit")
@@ -1420,6 +3070,86 @@ func testSwitchFrame(t *testing.T, c Config) {
 	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err != nil {
 		t.Fatalf(`After switching frames using "", wd.FindElement(selenium.ByID, %q) returned error: %v`, outsideDivID, err)
 	}
+
+	// Test with indices, through the nested frames.
+	if err := wd.Get(c.ServerURL + "/nested-frame"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/nested-frame", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := wd.SwitchFrame(0); err != nil {
+			t.Fatalf("wd.SwitchFrame(0) returned error: %v", err)
+		}
+	}
+	if _, err := wd.FindElement(selenium.ByID, insideFrameID); err != nil {
+		t.Fatalf("After switching frames using indices, wd.FindElement(selenium.ByID, %q) returned error: %v", insideFrameID, err)
+	}
+	if err := wd.SwitchFrame(nil); err != nil {
+		t.Fatalf("wd.SwitchToFrame(nil) returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outerFrame"); err != nil {
+		t.Fatalf("After switching frames using nil, wd.FindElement(selenium.ByID, %q) returned error: %v", "outerFrame", err)
+	}
+}
+
+func testSwitchToParentFrame(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL + "/nested-frame"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/nested-frame", err)
+	}
+	for _, id := range []string{"outerFrame", "iframeID"} {
+		if err := wd.SwitchFrame(id); err != nil {
+			t.Fatalf("wd.SwitchFrame(%q) returned error: %v", id, err)
+		}
+	}
+	if _, err := wd.FindElement(selenium.ByID, "chuk"); err != nil {
+		t.Fatalf("In the inner frame, wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "chuk", err)
+	}
+
+	if err := wd.SwitchToParentFrame(); err != nil {
+		t.Fatalf("wd.SwitchToParentFrame() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrame"); err != nil {
+		t.Fatalf("In the outer frame, wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "outsideOfFrame", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "chuk"); err == nil {
+		t.Fatalf("In the outer frame, wd.FindElement(%q, %q) returned nil, expected an error", selenium.ByID, "chuk")
+	}
+
+	if err := wd.SwitchToParentFrame(); err != nil {
+		t.Fatalf("wd.SwitchToParentFrame() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outerFrame"); err != nil {
+		t.Fatalf("In the top-level context, wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "outerFrame", err)
+	}
+}
+
+func testWithinFrame(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL + "/frame"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/frame", err)
+	}
+	frame, err := wd.FindElement(selenium.ByID, "iframeID")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "iframeID", err)
+	}
+
+	wantErr := errors.New("inside the frame")
+	err = wd.WithinFrame(frame, func() error {
+		if _, err := wd.FindElement(selenium.ByID, "chuk"); err != nil {
+			t.Errorf("Within the frame, wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "chuk", err)
+		}
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("wd.WithinFrame() returned error %v, want %v", err, wantErr)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrame"); err != nil {
+		t.Errorf("After wd.WithinFrame(), wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "outsideOfFrame", err)
+	}
 }
 
 func testWait(t *testing.T, c Config) {
@@ -1458,6 +3188,292 @@ func testWait(t *testing.T, c Config) {
 	}
 }
 
+func testWaitForTitle(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	titleURL := c.ServerURL + "/title"
+	if err := wd.Get(titleURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", titleURL, err)
+	}
+
+	const newTitle = "Title changed."
+	if err := wd.WaitForTitle(newTitle, 5*time.Second); err != nil {
+		t.Fatalf("wd.WaitForTitle(%q) returned error: %v", newTitle, err)
+	}
+	if err := wd.WaitForTitleContains("changed", time.Second); err != nil {
+		t.Fatalf("wd.WaitForTitleContains(%q) returned error: %v", "changed", err)
+	}
+
+	const wrongTitle = "Never the title"
+	err := wd.WaitForTitle(wrongTitle, 500*time.Millisecond)
+	if err == nil {
+		t.Fatalf("wd.WaitForTitle(%q) returned nil, expected an error", wrongTitle)
+	}
+	if !strings.Contains(err.Error(), newTitle) {
+		t.Fatalf("wd.WaitForTitle(%q) returned error %q, which does not contain the actual title %q", wrongTitle, err, newTitle)
+	}
+}
+
+func testMarshalSession(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	data, err := wd.MarshalSession()
+	if err != nil {
+		t.Fatalf("wd.MarshalSession() returned error: %v", err)
+	}
+	resumed, err := selenium.UnmarshalSession(data)
+	if err != nil {
+		t.Fatalf("selenium.UnmarshalSession(%s) returned error: %v", data, err)
+	}
+	if got, want := resumed.SessionID(), wd.SessionID(); got != want {
+		t.Errorf("resumed.SessionID() = %q, want %q", got, want)
+	}
+	title, err := resumed.Title()
+	if err != nil {
+		t.Fatalf("resumed.Title() returned error: %v", err)
+	}
+	if title != "Go Selenium Test Suite" {
+		t.Errorf("resumed.Title() = %q, want %q", title, "Go Selenium Test Suite")
+	}
+}
+
+func testGetElement(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// The element is rendered twice, as a framework might do.
+	const render = `
+function render(text) {
+  var old = document.getElementById('late');
+  if (old) {
+    old.parentNode.removeChild(old);
+  }
+  document.body.insertAdjacentHTML('beforeend', '<div id="late">' + text + '</div>');
+}
+setTimeout(function() { render('first'); }, 200);
+setTimeout(function() { render('second'); }, 400);`
+	if _, err := wd.ExecuteScript(render, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", render, err)
+	}
+	if _, err := wd.GetElement(selenium.ByID, "missing", 100*time.Millisecond); err == nil {
+		t.Errorf("wd.GetElement(%q, %q) returned no error for a missing element", selenium.ByID, "missing")
+	}
+	elem, err := wd.GetElement(selenium.ByID, "late", 5*time.Second)
+	if err != nil {
+		t.Fatalf("wd.GetElement(%q, %q) returned error: %v", selenium.ByID, "late", err)
+	}
+	if _, err := elem.Text(); err != nil {
+		// The element may have gone stale if it was found before the second
+		// rendering, which GetElement cannot predict; only other errors fail.
+		if !selenium.IsStaleElement(err) {
+			t.Fatalf("elem.Text() returned error: %v", err)
+		}
+	}
+}
+
+func testNumberOfWindowsToBe(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// Open the popup asynchronously, like a click handler might.
+	const script = "setTimeout(function() { window.open('about:blank', '_blank'); }, 200);"
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	if err := wd.WaitWithTimeout(selenium.NumberOfWindowsToBe(2), 5*time.Second); err != nil {
+		t.Fatalf("Waiting for 2 windows returned error: %v", err)
+	}
+	if err := wd.WaitWithTimeout(selenium.NumberOfWindowsToBe(3), 500*time.Millisecond); err == nil {
+		t.Fatalf("Waiting for 3 windows returned nil, expected a timeout")
+	}
+}
+
+func testNewWindow(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support the New Window command")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	before, err := wd.WindowHandles()
+	if err != nil {
+		t.Fatalf("wd.WindowHandles() returned error: %v", err)
+	}
+	handle, err := wd.NewWindow("tab")
+	if err != nil {
+		t.Fatalf("wd.NewWindow(%q) returned error: %v", "tab", err)
+	}
+	after, err := wd.WindowHandles()
+	if err != nil {
+		t.Fatalf("wd.WindowHandles() returned error: %v", err)
+	}
+	if len(after) != len(before)+1 {
+		t.Fatalf("len(wd.WindowHandles()) = %d after wd.NewWindow(), want %d", len(after), len(before)+1)
+	}
+
+	if err := wd.SwitchWindow(handle); err != nil {
+		t.Fatalf("wd.SwitchWindow(%q) returned error: %v", handle, err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if got, err := wd.CurrentWindowHandle(); err != nil {
+		t.Fatalf("wd.CurrentWindowHandle() returned error: %v", err)
+	} else if got != handle {
+		t.Errorf("wd.CurrentWindowHandle() = %q, want %q", got, handle)
+	}
+	if u, err := wd.CurrentURL(); err != nil {
+		t.Fatalf("wd.CurrentURL() returned error: %v", err)
+	} else if !strings.HasPrefix(u, c.ServerURL) {
+		t.Errorf("wd.CurrentURL() = %q in the new window, want %q", u, c.ServerURL)
+	}
+}
+
+func testElementConditions(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// The button is added hidden and disabled, then shown, then enabled.
+	const script = `
+setTimeout(function() {
+  document.body.insertAdjacentHTML('beforeend', '<button id="later" style="display: none" disabled>Later</button>');
+}, 100);
+setTimeout(function() { document.getElementById('later').style.display = ''; }, 300);
+setTimeout(function() { document.getElementById('later').disabled = false; }, 500);`
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	for _, tc := range []struct {
+		name      string
+		condition selenium.Condition
+	}{
+		{"ElementIsPresent", selenium.ElementIsPresent(selenium.ByID, "later")},
+		{"ElementIsVisible", selenium.ElementIsVisible(selenium.ByID, "later")},
+		{"ElementIsClickable", selenium.ElementIsClickable(selenium.ByID, "later")},
+		{"URLContains", selenium.URLContains(c.ServerURL)},
+	} {
+		if err := wd.WaitWithTimeout(tc.condition, 5*time.Second); err != nil {
+			t.Fatalf("Waiting for %s returned error: %v", tc.name, err)
+		}
+	}
+
+	if err := wd.WaitWithTimeout(selenium.AlertIsPresent(), 500*time.Millisecond); err == nil {
+		t.Fatalf("Waiting for AlertIsPresent without an alert returned nil, expected a timeout")
+	} else if !strings.Contains(err.Error(), "no alert is open") {
+		t.Errorf("Waiting for AlertIsPresent without an alert returned %q, want it to report that no alert is open", err)
+	}
+	const alert = "setTimeout(function() { alert('Hello'); }, 100);"
+	if _, err := wd.ExecuteScript(alert, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", alert, err)
+	}
+	if err := wd.WaitWithTimeout(selenium.AlertIsPresent(), 5*time.Second); err != nil {
+		t.Fatalf("Waiting for AlertIsPresent returned error: %v", err)
+	}
+	if err := wd.AcceptAlert(); err != nil {
+		t.Fatalf("wd.AcceptAlert() returned error: %v", err)
+	}
+}
+
+func testWaitForImages(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	imagesURL := c.ServerURL + "/images"
+	if err := wd.Get(imagesURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", imagesURL, err)
+	}
+	if err := wd.WaitForImages(5 * time.Second); err != nil {
+		t.Fatalf("wd.WaitForImages() returned error: %v", err)
+	}
+
+	const brokenImage = "/missing.png"
+	script := fmt.Sprintf("var img = document.createElement('img'); img.src = %q; document.body.appendChild(img);", brokenImage)
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	err := wd.WaitForImages(500 * time.Millisecond)
+	if err == nil {
+		t.Fatalf("wd.WaitForImages() returned nil with a broken image, expected an error")
+	}
+	if !strings.Contains(err.Error(), brokenImage) {
+		t.Fatalf("wd.WaitForImages() returned error %q, which does not mention %q", err, brokenImage)
+	}
+}
+
+func testInteractionRecorder(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	dir, err := ioutil.TempDir("", "interactions")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := wd.SetInteractionRecorder(dir); err != nil {
+		t.Fatalf("wd.SetInteractionRecorder(%q) returned error: %v", dir, err)
+	}
+	input, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "q", err)
+	}
+	if err := input.Click(); err != nil {
+		t.Fatalf("input.Click() returned error: %v", err)
+	}
+	const query = "golang"
+	if err := input.SendKeys(query); err != nil {
+		t.Fatalf("input.SendKeys(%q) returned error: %v", query, err)
+	}
+	if err := wd.SetInteractionRecorder(""); err != nil {
+		t.Fatalf("wd.SetInteractionRecorder(\"\") returned error: %v", err)
+	}
+	if err := input.Clear(); err != nil {
+		t.Fatalf("input.Clear() returned error: %v", err)
+	}
+	if err := input.Click(); err != nil {
+		t.Fatalf("input.Click() returned error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Reading the manifest returned error: %v", err)
+	}
+	var steps []selenium.InteractionStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		t.Fatalf("json.Unmarshal(%q) returned error: %v", data, err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("The manifest has %d steps, want 2: %s", len(steps), data)
+	}
+	if steps[0].Action != "click" || steps[1].Action != "sendKeys" || steps[1].Keys != query {
+		t.Errorf("The manifest has unexpected steps: %s", data)
+	}
+	for _, step := range steps {
+		for _, name := range []string{step.Before, step.After} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("Screenshot for step %d: %v", step.Step, err)
+			}
+		}
+	}
+}
+
 func testAcceptAlert(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -1578,6 +3594,33 @@ var framePage = `
 </html>
 `
 
+var nestedFramePage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Nested Frame Page</title>
+</head>
+<body>
+	This page contains a frame that contains a frame.
+
+	<iframe id="outerFrame" src="/frame"></iframe>
+</body>
+</html>
+`
+
+var imagesPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Images Page</title>
+</head>
+<body>
+	This page contains images.
+
+	<img id="first" src="/image.png">
+	<img id="second" src="/image.png?second">
+</body>
+</html>
+`
+
 var titleChangePage = `
 <html>
 <head>
@@ -1608,16 +3651,44 @@ var alertPage = `
 </html>
 `
 
+const downloadContents = "The contents of a downloaded file."
+
+// pixelPNG is a PNG image of a single transparent pixel.
+var pixelPNG, _ = base64.StdEncoding.DecodeString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII=")
+
+// cachedRequests counts the requests for "/cached.txt", whose response is the
+// count and may be cached by the browser.
+var cachedRequests int32
+
 var Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
+	if path == "/cached.txt" {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, atomic.AddInt32(&cachedRequests, 1))
+		return
+	}
+	if path == "/image.png" {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pixelPNG)
+		return
+	}
+	if path == "/download.txt" {
+		w.Header().Set("Content-Disposition", `attachment; filename="download.txt"`)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, downloadContents)
+		return
+	}
 	page, ok := map[string]string{
-		"/":       homePage,
-		"/other":  otherPage,
-		"/search": searchPage,
-		"/log":    logPage,
-		"/frame":  framePage,
-		"/title":  titleChangePage,
-		"/alert":  alertPage,
+		"/":             homePage,
+		"/other":        otherPage,
+		"/search":       searchPage,
+		"/log":          logPage,
+		"/frame":        framePage,
+		"/nested-frame": nestedFramePage,
+		"/images":       imagesPage,
+		"/title":        titleChangePage,
+		"/alert":        alertPage,
 	}[path]
 	if !ok {
 		http.NotFound(w, r)
@@ -1644,6 +3715,21 @@ func RunFirefoxTests(t *testing.T, c Config) {
 	// Firefox-specific tests.
 	t.Run("Preferences", runTest(testFirefoxPreferences, c))
 	t.Run("Profile", runTest(testFirefoxProfile, c))
+	t.Run("MozProcessID", runTest(testFirefoxMozProcessID, c))
+}
+
+func testFirefoxMozProcessID(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	caps, err := wd.Capabilities()
+	if err != nil {
+		t.Fatalf("wd.Capabilities() returned error: %v", err)
+	}
+	pid, ok := caps.MozProcessID()
+	if !ok || pid <= 0 {
+		t.Fatalf("caps.MozProcessID() = %d, %t, want a process ID", pid, ok)
+	}
 }
 
 func testFirefoxPreferences(t *testing.T, c Config) {
@@ -1753,4 +3839,512 @@ func testChromeExtension(t *testing.T, c Config) {
 func RunChromeTests(t *testing.T, c Config) {
 	// Chrome-specific tests.
 	t.Run("Extension", runTest(testChromeExtension, c))
+	t.Run("Downloads", runTest(testChromeDownloads, c))
+	t.Run("IncognitoContext", runTest(testChromeIncognitoContext, c))
+	t.Run("UserAgent", runTest(testChromeUserAgent, c))
+	t.Run("ConsoleCapture", runTest(testChromeConsoleCapture, c))
+	t.Run("CumulativeLayoutShift", runTest(testChromeCumulativeLayoutShift, c))
+	t.Run("FreezeTime", runTest(testChromeFreezeTime, c))
+	t.Run("SeedRandom", runTest(testChromeSeedRandom, c))
+	t.Run("JSHeapSize", runTest(testChromeJSHeapSize, c))
+	t.Run("SetCacheDisabled", runTest(testChromeSetCacheDisabled, c))
+	t.Run("SetGeolocation", runTest(testChromeSetGeolocation, c))
+	t.Run("EmulatePrintMedia", runTest(testChromeEmulatePrintMedia, c))
+	t.Run("AssertNoRequestsMatching", runTest(testChromeAssertNoRequestsMatching, c))
+	t.Run("WaitForRequest", runTest(testChromeWaitForRequest, c))
+}
+
+func testChromeUserAgent(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	co := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	const userAgent = "selenium-test-agent/1.0"
+	co.Args = append(co.Args, "--user-agent="+userAgent)
+	caps[chrome.CapabilitiesKey] = co
+
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	got, err := wd.UserAgent()
+	if err != nil {
+		t.Fatalf("wd.UserAgent() returned error: %v", err)
+	}
+	if got != userAgent {
+		t.Fatalf("wd.UserAgent() = %q, want %q", got, userAgent)
+	}
+}
+
+func testChromeCumulativeLayoutShift(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if _, err := wd.CumulativeLayoutShift(); err == nil {
+		t.Fatalf("wd.CumulativeLayoutShift() before wd.StartLayoutShiftCapture() returned nil error")
+	}
+
+	if err := wd.StartLayoutShiftCapture(); err != nil {
+		t.Fatalf("wd.StartLayoutShiftCapture() returned error: %v", err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	before, err := wd.CumulativeLayoutShift()
+	if err != nil {
+		t.Fatalf("wd.CumulativeLayoutShift() returned error: %v", err)
+	}
+
+	// Insert a banner above the content of the page, which pushes it down.
+	const script = `
+var done = arguments[0];
+document.body.insertAdjacentHTML('beforeend', '<p style="font-size: 40px">Content</p>');
+requestAnimationFrame(function() {
+  setTimeout(function() {
+    document.body.insertAdjacentHTML('afterbegin', '<div style="height: 200px">Banner</div>');
+    requestAnimationFrame(function() { setTimeout(done, 100); });
+  }, 100);
+});`
+	if _, err := wd.ExecuteScriptAsync(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScriptAsync(%q) returned error: %v", script, err)
+	}
+	after, err := wd.CumulativeLayoutShift()
+	if err != nil {
+		t.Fatalf("wd.CumulativeLayoutShift() returned error: %v", err)
+	}
+	if after <= before {
+		t.Errorf("wd.CumulativeLayoutShift() = %v after a layout shift, want more than %v", after, before)
+	}
+}
+
+func testChromeFreezeTime(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	frozen := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := wd.FreezeTime(frozen); err != nil {
+		t.Fatalf("wd.FreezeTime() returned error: %v", err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	const script = "return [Date.now(), new Date().toISOString(), new Date(0).getTime(), new Date() instanceof Date];"
+	got, err := wd.ExecuteScript(script, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	want := []interface{}{float64(frozen.UnixNano() / int64(time.Millisecond)), "2020-01-02T03:04:05.000Z", float64(0), true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wd.ExecuteScript(%q) returned diff (-want/+got):\n%s", script, diff)
+	}
+}
+
+func testChromeSeedRandom(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	const script = "return [Math.random(), Math.random(), Math.random()];"
+	sequence := func() []interface{} {
+		if err := wd.Get(c.ServerURL); err != nil {
+			t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+		}
+		v, err := wd.ExecuteScript(script, nil)
+		if err != nil {
+			t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+		}
+		return v.([]interface{})
+	}
+
+	if err := wd.SeedRandom(42); err != nil {
+		t.Fatalf("wd.SeedRandom(42) returned error: %v", err)
+	}
+	first := sequence()
+	if diff := cmp.Diff(first, sequence()); diff != "" {
+		t.Errorf("Math.random() returned different numbers after reloading the page (-first/+second):\n%s", diff)
+	}
+	if err := wd.SeedRandom(7); err != nil {
+		t.Fatalf("wd.SeedRandom(7) returned error: %v", err)
+	}
+	if other := sequence(); cmp.Equal(first, other) {
+		t.Errorf("Math.random() returned the same numbers %v with different seeds", other)
+	}
+}
+
+func testChromeConsoleCapture(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	co := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	co.DevToolsEventsToLog = selenium.ConsoleEvents
+	caps[chrome.CapabilitiesKey] = co
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.StartConsoleCapture(); err != nil {
+		t.Fatalf("wd.StartConsoleCapture() returned error: %v", err)
+	}
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const script = "function explode() { console.error('boom', {code: 42}); } explode();"
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+
+	msgs, err := wd.ConsoleMessages()
+	if err != nil {
+		t.Fatalf("wd.ConsoleMessages() returned error: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("wd.ConsoleMessages() returned %d messages, want 1: %+v", len(msgs), msgs)
+	}
+	m := msgs[0]
+	if m.Level != "error" || m.Text != "boom Object" {
+		t.Errorf("wd.ConsoleMessages() = %+v, want an error with text %q", m, "boom Object")
+	}
+	if len(m.StackTrace) == 0 || m.StackTrace[0].Function != "explode" || m.StackTrace[0].Line == 0 {
+		t.Errorf("wd.ConsoleMessages() returned stack trace %+v, want it to start in explode()", m.StackTrace)
+	}
+
+	if msgs, err = wd.ConsoleMessages(); err != nil || len(msgs) != 0 {
+		t.Errorf("wd.ConsoleMessages() a second time returned (%+v, %v), want no messages", msgs, err)
+	}
+
+	// Messages are kept across navigations, and uncaught exceptions are
+	// recorded.
+	const throw = `console.log('before'); var s = document.createElement('script');
+s.textContent = "throw new Error('kaboom')"; document.body.appendChild(s);`
+	if _, err := wd.ExecuteScript(throw, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", throw, err)
+	}
+	if err := wd.Get(c.ServerURL + "/other"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/other", err)
+	}
+	msgs, err = wd.ConsoleMessages()
+	if err != nil {
+		t.Fatalf("wd.ConsoleMessages() returned error: %v", err)
+	}
+	var got []string
+	for _, m := range msgs {
+		got = append(got, m.Level+": "+m.Text)
+	}
+	if diff := cmp.Diff([]string{"log: before", "error: Uncaught Error: kaboom"}, got); diff != "" {
+		t.Errorf("wd.ConsoleMessages() after a navigation returned diff (-want +got):\n%s", diff)
+	}
+
+	const warn = "console.warn('careful'); console.error('broken');"
+	if _, err := wd.ExecuteScript(warn, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", warn, err)
+	}
+	err = wd.AssertNoConsoleErrors("error")
+	if e, ok := err.(*selenium.ConsoleMessagesError); !ok {
+		t.Fatalf("wd.AssertNoConsoleErrors(%q) returned error %v of type %T, want *selenium.ConsoleMessagesError", "error", err, err)
+	} else if len(e.Messages) != 1 || e.Messages[0].Text != "broken" {
+		t.Errorf("wd.AssertNoConsoleErrors(%q) reported messages %+v, want only %q", "error", e.Messages, "broken")
+	}
+	if err := wd.AssertNoConsoleErrors("error"); err != nil {
+		t.Errorf("wd.AssertNoConsoleErrors(%q) without new messages returned error: %v", "error", err)
+	}
+
+	// An uncaught exception in a page that was navigated away from is
+	// reported.
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if _, err := wd.ExecuteScript(throw, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", throw, err)
+	}
+	if err := wd.Get(c.ServerURL + "/other"); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL+"/other", err)
+	}
+	err = wd.AssertNoConsoleErrors("error")
+	if e, ok := err.(*selenium.ConsoleMessagesError); !ok {
+		t.Fatalf("wd.AssertNoConsoleErrors(%q) after an uncaught exception returned error %v of type %T, want *selenium.ConsoleMessagesError", "error", err, err)
+	} else if len(e.Messages) != 1 || e.Messages[0].Text != "Uncaught Error: kaboom" {
+		t.Errorf("wd.AssertNoConsoleErrors(%q) reported messages %+v, want only the uncaught exception", "error", e.Messages)
+	}
+}
+
+func testChromeJSHeapSize(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	used, total, err := wd.JSHeapSize()
+	if err != nil {
+		t.Fatalf("wd.JSHeapSize() returned error: %v", err)
+	}
+	if used <= 0 || total < used {
+		t.Fatalf("wd.JSHeapSize() = (%d, %d), want 0 < used <= total", used, total)
+	}
+
+	const allocate = "window.retained = new Array(1e6).fill('x');"
+	if _, err := wd.ExecuteScript(allocate, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", allocate, err)
+	}
+	after, _, err := wd.JSHeapSize()
+	if err != nil {
+		t.Fatalf("wd.JSHeapSize() returned error: %v", err)
+	}
+	if after <= used {
+		t.Fatalf("wd.JSHeapSize() returned %d bytes used after allocating, want more than %d", after, used)
+	}
+}
+
+func testChromeEmulatePrintMedia(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	for _, enabled := range []bool{true, false} {
+		if err := wd.EmulatePrintMedia(enabled); err != nil {
+			t.Fatalf("wd.EmulatePrintMedia(%t) returned error: %v", enabled, err)
+		}
+		print, err := wd.ExecuteScript("return window.matchMedia('print').matches;", nil)
+		if err != nil {
+			t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+		}
+		if print != enabled {
+			t.Errorf("After wd.EmulatePrintMedia(%t), the print media query matches = %v", enabled, print)
+		}
+	}
+}
+
+func testChromeSetCacheDisabled(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	// Each response of /cached.txt is distinct, so a repeated response comes
+	// from the cache.
+	fetch := func() string {
+		t.Helper()
+		const script = `
+var done = arguments[0];
+fetch('/cached.txt').then(function(r) { return r.text(); }).then(done);`
+		v, err := wd.ExecuteScriptAsync(script, nil)
+		if err != nil {
+			t.Fatalf("wd.ExecuteScriptAsync(%q) returned error: %v", script, err)
+		}
+		return v.(string)
+	}
+
+	if first, second := fetch(), fetch(); first != second {
+		t.Fatalf("Fetching a cacheable resource twice returned %q and %q, want a cached response", first, second)
+	}
+
+	if err := wd.SetCacheDisabled(true); err != nil {
+		t.Fatalf("wd.SetCacheDisabled(true) returned error: %v", err)
+	}
+	if first, second := fetch(), fetch(); first == second {
+		t.Fatalf("Fetching a cacheable resource twice with the cache disabled returned %q both times, want distinct responses", first)
+	}
+	if err := wd.SetCacheDisabled(false); err != nil {
+		t.Fatalf("wd.SetCacheDisabled(false) returned error: %v", err)
+	}
+}
+
+func testChromeSetGeolocation(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if _, err := wd.(selenium.ChromeWebDriver).ExecuteCDPCmd("Browser.grantPermissions", map[string]interface{}{
+		"permissions": []string{"geolocation"},
+	}); err != nil {
+		t.Fatalf("Granting the geolocation permission returned error: %v", err)
+	}
+	if err := wd.SetGeolocation(48.8584, 2.2945, 10); err != nil {
+		t.Fatalf("wd.SetGeolocation() returned error: %v", err)
+	}
+	defer func() {
+		if err := wd.ClearGeolocation(); err != nil {
+			t.Errorf("wd.ClearGeolocation() returned error: %v", err)
+		}
+	}()
+
+	const script = `
+var done = arguments[0];
+navigator.geolocation.getCurrentPosition(function(p) {
+  done([p.coords.latitude, p.coords.longitude, p.coords.accuracy]);
+}, function(e) {
+  done(e.message);
+});`
+	v, err := wd.ExecuteScriptAsync(script, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScriptAsync(%q) returned error: %v", script, err)
+	}
+	want := []interface{}{48.8584, 2.2945, float64(10)}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("navigator.geolocation returned a different position (-want +got):\n%s", diff)
+	}
+}
+
+func testChromeAssertNoRequestsMatching(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	caps.SetLogLevel(log.Performance, log.All)
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := wd.StartEventRecording(); err != nil {
+		t.Fatalf("wd.StartEventRecording() returned error: %v", err)
+	}
+	const script = `
+var done = arguments[0];
+fetch('/other').then(function() { done(); });`
+	if _, err := wd.ExecuteScriptAsync(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScriptAsync(%q) returned error: %v", script, err)
+	}
+
+	if err := wd.AssertNoRequestsMatching(`/image\.png$`); err != nil {
+		t.Errorf("wd.AssertNoRequestsMatching(%q) returned error: %v", `/image\.png$`, err)
+	}
+	err := wd.AssertNoRequestsMatching(`/other$`)
+	if err == nil || !strings.Contains(err.Error(), c.ServerURL+"/other") {
+		t.Errorf("wd.AssertNoRequestsMatching(%q) returned error %v, want an error that lists %q", `/other$`, err, c.ServerURL+"/other")
+	}
+}
+
+func testChromeWaitForRequest(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	caps.SetLogLevel(log.Performance, log.All)
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := wd.StartEventRecording(); err != nil {
+		t.Fatalf("wd.StartEventRecording() returned error: %v", err)
+	}
+	const script = `setTimeout(function() { fetch('/other'); }, 500);`
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+
+	r, err := wd.WaitForRequest(`/other$`, 10*time.Second)
+	if err != nil {
+		t.Fatalf("wd.WaitForRequest(%q) returned error: %v", `/other$`, err)
+	}
+	if want := c.ServerURL + "/other"; r.URL != want || r.Method != "GET" || r.Status != 200 || r.Failed {
+		t.Errorf("wd.WaitForRequest(%q) returned %+v, want a successful GET request of %q", `/other$`, r, want)
+	}
+
+	if _, err := wd.WaitForRequest(`/never$`, time.Second); err == nil {
+		t.Errorf("wd.WaitForRequest(%q) returned nil error, want a timeout", `/never$`)
+	}
+}
+
+func testChromeIncognitoContext(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const cookieName = "default-context"
+	if err := wd.AddCookie(&selenium.Cookie{Name: cookieName, Value: "1"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+
+	id, err := wd.CreateIncognitoContext()
+	if err != nil {
+		t.Fatalf("wd.CreateIncognitoContext() returned error: %v", err)
+	}
+	handle, err := wd.NewWindowInContext(id, c.ServerURL)
+	if err != nil {
+		t.Fatalf("wd.NewWindowInContext(%q, %q) returned error: %v", id, c.ServerURL, err)
+	}
+	if err := wd.SwitchWindow(handle); err != nil {
+		t.Fatalf("wd.SwitchWindow(%q) returned error: %v", handle, err)
+	}
+
+	// The cookies of the default context must not be visible in the
+	// incognito context.
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	}
+	for _, cookie := range cookies {
+		if cookie.Name == cookieName {
+			t.Fatalf("wd.GetCookies() in the incognito context returned the cookie %q of the default context", cookieName)
+		}
+	}
+
+	if err := wd.DisposeBrowserContext(id); err != nil {
+		t.Fatalf("wd.DisposeBrowserContext(%q) returned error: %v", id, err)
+	}
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		t.Fatalf("wd.WindowHandles() returned error: %v", err)
+	}
+	if len(handles) != 1 {
+		t.Fatalf("len(wd.WindowHandles()) = %d after disposing the context, want 1", len(handles))
+	}
+}
+
+func testChromeDownloads(t *testing.T, c Config) {
+	if c.Headless {
+		t.Skip("Headless Chrome does not download files by default.")
+	}
+	caps := newTestCapabilities(t, c)
+	caps.SetLogLevel(log.Performance, log.All)
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	dir, err := ioutil.TempDir("", "downloads")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// Recent versions of Chrome only report the progress of downloads once
+	// download events are enabled.
+	if _, err := wd.(selenium.ChromeWebDriver).ExecuteCDPCmd("Browser.setDownloadBehavior", map[string]interface{}{
+		"behavior":      "allow",
+		"downloadPath":  dir,
+		"eventsEnabled": true,
+	}); err != nil {
+		t.Fatalf("Enabling download events returned error: %v", err)
+	}
+	if err := wd.StartEventRecording(); err != nil {
+		t.Fatalf("wd.StartEventRecording() returned error: %v", err)
+	}
+	link := c.ServerURL + "/download.txt"
+	if _, err := wd.ExecuteScript(fmt.Sprintf("window.location = %q", link), nil); err != nil {
+		t.Fatalf("navigating to %q returned error: %v", link, err)
+	}
+
+	var downloads []selenium.Download
+	err = wd.WaitWithTimeout(func(wd selenium.WebDriver) (bool, error) {
+		var err error
+		downloads, err = wd.Downloads()
+		if err != nil {
+			return false, err
+		}
+		return len(downloads) == 1 && downloads[0].State == "completed", nil
+	}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("waiting for the download to complete returned error: %v; downloads: %+v", err, downloads)
+	}
+	d := downloads[0]
+	if d.URL != link || d.Filename != "download.txt" || d.ReceivedBytes != int64(len(downloadContents)) {
+		t.Fatalf("wd.Downloads() = %+v, want a download of %q named %q with %d bytes", downloads, link, "download.txt", len(downloadContents))
+	}
 }
@@ -0,0 +1,60 @@
+package selenium
+
+import "errors"
+
+// layoutShiftCaptureScript installs a PerformanceObserver that sums the scores
+// of the layout shifts of the page that were not caused by recent user input,
+// as the Cumulative Layout Shift metric does.
+const layoutShiftCaptureScript = `
+(function() {
+  if (window.__seleniumLayoutShift || typeof PerformanceObserver === 'undefined') {
+    return;
+  }
+  var state = window.__seleniumLayoutShift = {value: 0};
+  var add = function(entries) {
+    entries.forEach(function(e) {
+      if (!e.hadRecentInput) {
+        state.value += e.value;
+      }
+    });
+  };
+  state.observer = new PerformanceObserver(function(list) {
+    add(list.getEntries());
+  });
+  state.add = add;
+  state.observer.observe({type: 'layout-shift', buffered: true});
+})();
+`
+
+// layoutShiftScript returns the sum of the layout shift scores recorded by
+// layoutShiftCaptureScript, or null if it is not installed.
+const layoutShiftScript = `
+var state = window.__seleniumLayoutShift;
+if (!state) {
+  return null;
+}
+// Include the entries that were not delivered to the observer yet.
+state.add(state.observer.takeRecords());
+return state.value;
+`
+
+func (wd *remoteWD) StartLayoutShiftCapture() error {
+	if err := wd.requireChrome("StartLayoutShiftCapture"); err != nil {
+		return err
+	}
+	return wd.addInitScript(layoutShiftCaptureScript)
+}
+
+func (wd *remoteWD) CumulativeLayoutShift() (float64, error) {
+	if err := wd.requireChrome("CumulativeLayoutShift"); err != nil {
+		return 0, err
+	}
+	var cls *float64
+	if err := wd.execScriptInto(layoutShiftScript, nil, &cls); err != nil {
+		return 0, err
+	}
+	if cls == nil {
+		return 0, errors.New("layout shifts are not captured in the current page; call StartLayoutShiftCapture before loading it")
+	}
+	return *cls, nil
+}
@@ -14,6 +14,9 @@ const (
 	Driver      Type = "driver"
 	Performance Type = "performance"
 	Profiler    Type = "profiler"
+	// DevTools is the log of the Chrome DevTools events listed in the
+	// DevToolsEventsToLog field of chrome.Capabilities.
+	DevTools Type = "devtools"
 )
 
 // Level represents a logging level of different components in the browser,
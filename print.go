@@ -0,0 +1,72 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// PrintOrientation is the orientation of printed pages.
+type PrintOrientation string
+
+// The page orientations defined by the W3C specification.
+const (
+	PrintPortrait  PrintOrientation = "portrait"
+	PrintLandscape PrintOrientation = "landscape"
+)
+
+// PrintPage is the size of printed pages, in centimeters. Nil fields are
+// omitted, leaving the default of the W3C specification in place.
+type PrintPage struct {
+	Width  *float64 `json:"width,omitempty"`
+	Height *float64 `json:"height,omitempty"`
+}
+
+// PrintMargin is the margins of printed pages, in centimeters. Nil fields are
+// omitted, leaving the default of the W3C specification in place.
+type PrintMargin struct {
+	Top    *float64 `json:"top,omitempty"`
+	Bottom *float64 `json:"bottom,omitempty"`
+	Left   *float64 `json:"left,omitempty"`
+	Right  *float64 `json:"right,omitempty"`
+}
+
+// PrintOptions configures WebDriver.Print. Fields with zero values are
+// omitted, leaving the defaults of the W3C specification in place: portrait US
+// Letter pages with 1cm margins, at a scale of 1, without backgrounds.
+type PrintOptions struct {
+	// Orientation is the orientation of the pages.
+	Orientation PrintOrientation `json:"orientation,omitempty"`
+	// Scale scales the content, between 0.1 and 2.
+	Scale float64 `json:"scale,omitempty"`
+	// Background sets whether background colors and images are printed.
+	Background bool `json:"background,omitempty"`
+	// Page is the size of the pages.
+	Page *PrintPage `json:"page,omitempty"`
+	// Margin is the margins of the pages.
+	Margin *PrintMargin `json:"margin,omitempty"`
+	// PageRanges are the pages to print, e.g. "1-3" or "5". All pages are
+	// printed if it is empty.
+	PageRanges []string `json:"pageRanges,omitempty"`
+}
+
+func (wd *remoteWD) Print(opts PrintOptions) ([]byte, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/print", wd.id), data)
+	if err != nil {
+		// Chrome and Firefox only print in headless mode, and report it as an
+		// unsupported operation or an unknown error otherwise.
+		if errorIs(err, "unsupported operation") || errorIs(err, "unknown error") {
+			return nil, fmt.Errorf("printing the page (is the browser headless?): %v", err)
+		}
+		return nil, err
+	}
+	reply := new(struct{ Value string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(reply.Value)
+}
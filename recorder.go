@@ -0,0 +1,195 @@
+package selenium
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// CommandRecord describes a command sent to the remote end, as recorded by a
+// CommandRecorder.
+type CommandRecord struct {
+	// Time is when the command was sent.
+	Time time.Time `json:"time"`
+	// Method is the HTTP method of the command.
+	Method string `json:"method"`
+	// Path is the path of the command's URL, relative to the URL of the
+	// remote end, e.g. "/session/<id>/element".
+	Path string `json:"path"`
+	// Request is the JSON body of the command, if any. It is only recorded if
+	// CommandRecorder.RecordBodies is set.
+	Request json.RawMessage `json:"request,omitempty"`
+	// Response is the JSON reply of the remote end, if the command succeeded.
+	// It is only recorded if CommandRecorder.RecordBodies is set.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Status is "success" if the command succeeded, the WebDriver error code
+	// reported by the remote end, such as "no such element", if it failed, or
+	// empty if no reply was received.
+	Status string `json:"status"`
+	// Duration is how long the remote end took to reply. It is encoded in JSON
+	// as a number of nanoseconds.
+	Duration time.Duration `json:"duration"`
+	// Err is the error returned by the command, if any.
+	Err string `json:"error,omitempty"`
+	// Span is the name of the innermost span, started by WebDriver.Timed,
+	// within which the command was sent, if any.
+	Span string `json:"span,omitempty"`
+}
+
+// Span is a named block of commands timed by WebDriver.Timed.
+type Span struct {
+	// Name is the name passed to Timed.
+	Name string `json:"name"`
+	// Start is when the block started.
+	Start time.Time `json:"start"`
+	// Duration is how long the block took, in nanoseconds in JSON.
+	Duration time.Duration `json:"duration"`
+	// Err is the error returned by the block, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// CommandRecorder records the commands that a WebDriver sends to the remote
+// end, optionally with their request and response bodies, and the spans timed
+// by WebDriver.Timed, e.g. to report how long a test's steps took or to save
+// the trace of a failed test with ExportJSON. Create one with
+// NewCommandRecorder and attach it with WebDriver.SetCommandRecorder. It is
+// safe for concurrent use.
+type CommandRecorder struct {
+	// RecordBodies enables recording the request and response bodies of the
+	// commands. They are not recorded by default, as some are large, e.g. the
+	// images returned by screenshots. It must be set before the recorder is
+	// attached.
+	RecordBodies bool
+
+	mu       sync.Mutex
+	commands []CommandRecord
+	spans    []Span
+	// open are the spans that have not ended yet, innermost last.
+	open []openSpan
+	// nextSpan is the identifier of the next span to start.
+	nextSpan int
+}
+
+// openSpan is a span that has not ended yet.
+type openSpan struct {
+	// id identifies the span among the open ones, as concurrent calls of
+	// Timed may end their spans in any order.
+	id   int
+	name string
+}
+
+// NewCommandRecorder returns an empty CommandRecorder.
+func NewCommandRecorder() *CommandRecorder {
+	return new(CommandRecorder)
+}
+
+// Commands returns the commands recorded so far, in the order they were sent.
+func (r *CommandRecorder) Commands() []CommandRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CommandRecord(nil), r.commands...)
+}
+
+// Spans returns the spans that have ended so far, in the order they ended.
+func (r *CommandRecorder) Spans() []Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Span(nil), r.spans...)
+}
+
+// ExportJSON writes the commands and spans recorded so far to w as a JSON
+// object with the fields "commands" and "spans", which hold the encoded
+// CommandRecord and Span values in the order returned by Commands and Spans.
+func (r *CommandRecorder) ExportJSON(w io.Writer) error {
+	trace := struct {
+		Commands []CommandRecord `json:"commands"`
+		Spans    []Span          `json:"spans"`
+	}{r.Commands(), r.Spans()}
+	if trace.Commands == nil {
+		trace.Commands = []CommandRecord{}
+	}
+	if trace.Spans == nil {
+		trace.Spans = []Span{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trace)
+}
+
+func (r *CommandRecorder) record(start time.Time, method, path string, request, response json.RawMessage, err error) {
+	c := CommandRecord{
+		Time:     start,
+		Method:   method,
+		Path:     path,
+		Status:   "success",
+		Duration: time.Since(start),
+	}
+	if r.RecordBodies {
+		c.Request, c.Response = request, response
+	}
+	if err != nil {
+		c.Status = errorCode(err)
+		c.Err = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.open) > 0 {
+		c.Span = r.open[len(r.open)-1].name
+	}
+	r.commands = append(r.commands, c)
+}
+
+// errorCode returns the WebDriver error code of err, or the empty string if
+// err was not reported by the remote end.
+func errorCode(err error) string {
+	if e, ok := asError(err); ok {
+		return e.Err
+	}
+	return ""
+}
+
+// startSpan opens a span with the given name and returns its identifier, to
+// pass to endSpan.
+func (r *CommandRecorder) startSpan(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.nextSpan
+	r.nextSpan++
+	r.open = append(r.open, openSpan{id: id, name: name})
+	return id
+}
+
+// endSpan closes the open span with the given identifier and records s.
+func (r *CommandRecorder) endSpan(id int, s Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, o := range r.open {
+		if o.id == id {
+			r.open = append(r.open[:i], r.open[i+1:]...)
+			break
+		}
+	}
+	r.spans = append(r.spans, s)
+}
+
+func (wd *remoteWD) SetCommandRecorder(r *CommandRecorder) {
+	wd.recorder = r
+}
+
+func (wd *remoteWD) Timed(name string, fn func() error) (d time.Duration, err error) {
+	start := time.Now()
+	if r := wd.recorder; r != nil {
+		id := r.startSpan(name)
+		// End the span even if fn panics.
+		defer func() {
+			s := Span{Name: name, Start: start, Duration: d}
+			if err != nil {
+				s.Err = err.Error()
+			}
+			r.endSpan(id, s)
+		}()
+	}
+	err = fn()
+	return time.Since(start), err
+}
@@ -0,0 +1,168 @@
+package selenium
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RelativeLocator finds elements by their position relative to other
+// elements, like the relative locators of Selenium 4. Create one with
+// RelativeBy, add constraints with its methods and pass it to
+// WebDriver.FindRelativeElement or FindRelativeElements. For example:
+//
+//	l := RelativeBy(ByTagName, "input").Below(label).Near(form, 100)
+//	elem, err := wd.FindRelativeElement(l)
+//
+// Positions are compared using the elements' bounding boxes as rendered.
+type RelativeLocator struct {
+	by, value string
+	filters   []relativeFilter
+}
+
+// relativeFilter is a constraint of a RelativeLocator, as passed to
+// relativeFilterScript.
+type relativeFilter struct {
+	Kind     string     `json:"kind"`
+	Anchor   WebElement `json:"anchor"`
+	Distance int        `json:"distance,omitempty"`
+}
+
+// DefaultNearDistance is the distance, in CSS pixels, within which
+// RelativeLocator.Near matches elements if no distance is provided.
+const DefaultNearDistance = 50
+
+// RelativeBy returns a locator of the elements that match the query and
+// satisfy the constraints added to it.
+func RelativeBy(by, value string) *RelativeLocator {
+	return &RelativeLocator{by: by, value: value}
+}
+
+func (l *RelativeLocator) add(kind string, anchor WebElement, distance int) *RelativeLocator {
+	l.filters = append(l.filters, relativeFilter{Kind: kind, Anchor: anchor, Distance: distance})
+	return l
+}
+
+// Above constrains the elements to be entirely above anchor.
+func (l *RelativeLocator) Above(anchor WebElement) *RelativeLocator {
+	return l.add("above", anchor, 0)
+}
+
+// Below constrains the elements to be entirely below anchor.
+func (l *RelativeLocator) Below(anchor WebElement) *RelativeLocator {
+	return l.add("below", anchor, 0)
+}
+
+// ToLeftOf constrains the elements to be entirely to the left of anchor.
+func (l *RelativeLocator) ToLeftOf(anchor WebElement) *RelativeLocator {
+	return l.add("left", anchor, 0)
+}
+
+// ToRightOf constrains the elements to be entirely to the right of anchor.
+func (l *RelativeLocator) ToRightOf(anchor WebElement) *RelativeLocator {
+	return l.add("right", anchor, 0)
+}
+
+// Near constrains the elements to be at most distance CSS pixels away from
+// the edges of anchor. If distance is not positive, DefaultNearDistance is
+// used.
+func (l *RelativeLocator) Near(anchor WebElement, distance int) *RelativeLocator {
+	if distance <= 0 {
+		distance = DefaultNearDistance
+	}
+	return l.add("near", anchor, distance)
+}
+
+// String returns a description of the locator for error messages.
+func (l *RelativeLocator) String() string {
+	parts := []string{fmt.Sprintf("%s %q", l.by, l.value)}
+	for _, f := range l.filters {
+		if f.Kind == "near" {
+			parts = append(parts, fmt.Sprintf("near (%dpx)", f.Distance))
+			continue
+		}
+		parts = append(parts, f.Kind)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// relativeFilterScript returns the elements of the array provided as the
+// first argument that satisfy all of the filters provided as the second
+// argument, excluding the anchors, sorted by their distance to the first
+// anchor.
+const relativeFilterScript = `
+var candidates = arguments[0], filters = arguments[1];
+function rect(e) {
+  return e.getBoundingClientRect();
+}
+function gap(a, b) {
+  var dx = Math.max(0, a.left - b.right, b.left - a.right);
+  var dy = Math.max(0, a.top - b.bottom, b.top - a.bottom);
+  return Math.sqrt(dx * dx + dy * dy);
+}
+function center(r) {
+  return {x: r.left + r.width / 2, y: r.top + r.height / 2};
+}
+var tests = {
+  above: function(c, a) { return c.bottom <= a.top; },
+  below: function(c, a) { return c.top >= a.bottom; },
+  left: function(c, a) { return c.right <= a.left; },
+  right: function(c, a) { return c.left >= a.right; },
+  near: function(c, a, d) { return gap(c, a) <= d; }
+};
+var anchors = filters.map(function(f) { return f.anchor; });
+var matches = candidates.filter(function(e) {
+  if (anchors.indexOf(e) >= 0) {
+    return false;
+  }
+  var r = rect(e);
+  return filters.every(function(f) {
+    return tests[f.kind](r, rect(f.anchor), f.distance);
+  });
+});
+if (filters.length > 0) {
+  var origin = center(rect(filters[0].anchor));
+  var distance = function(e) {
+    var c = center(rect(e));
+    return Math.sqrt(Math.pow(c.x - origin.x, 2) + Math.pow(c.y - origin.y, 2));
+  };
+  matches = matches.map(function(e, i) {
+    return {e: e, d: distance(e), i: i};
+  }).sort(function(a, b) {
+    return a.d - b.d || a.i - b.i;
+  }).map(function(m) {
+    return m.e;
+  });
+}
+return matches;
+`
+
+func (wd *remoteWD) FindRelativeElements(l *RelativeLocator) ([]WebElement, error) {
+	candidates, err := wd.FindElements(l.by, l.value)
+	if err != nil {
+		return nil, err
+	}
+	// The script is run even without candidates to check that the reference
+	// elements are not stale.
+	response, err := wd.ExecuteScriptRaw(relativeFilterScript, []interface{}{candidates, l.filters})
+	if errorIs(err, "stale element reference") {
+		return nil, fmt.Errorf("finding elements by %s: a reference element is stale: %v", l, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wd.DecodeElements(response)
+}
+
+func (wd *remoteWD) FindRelativeElement(l *RelativeLocator) (WebElement, error) {
+	elems, err := wd.FindRelativeElements(l)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, &Error{
+			Err:     "no such element",
+			Message: fmt.Sprintf("no element matches %s", l),
+		}
+	}
+	return elems[0], nil
+}
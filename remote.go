@@ -4,16 +4,21 @@
 package selenium
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -53,12 +58,62 @@ type remoteWD struct {
 	w3cCompatible  bool
 	browser        string
 	browserVersion semver.Version
+
+	// events are the Chrome DevTools events read from the performance log
+	// during the current recording window.
+	events []devToolsEvent
+	// waitedRequests are the IDs of the network requests returned by
+	// WaitForRequest during the current recording window.
+	waitedRequests map[string]bool
+
+	// interactions, if not nil, records screenshots around element
+	// interactions.
+	interactions *interactionRecorder
+
+	// elementFactory, if not nil, wraps every element returned to the user.
+	elementFactory func(base WebElement) WebElement
+
+	// negotiated are the capabilities returned by the remote end when the
+	// session was created.
+	negotiated Capabilities
+
+	// pauseFailed and pauseDuration configure PauseOnFailure.
+	pauseFailed   func() bool
+	pauseDuration time.Duration
+
+	// recoverDetachedFrames is set by SetFrameDetachedRecovery.
+	recoverDetachedFrames bool
+
+	// recorder, if not nil, records every command sent to the remote end.
+	recorder *CommandRecorder
+
+	// defaultBy is the strategy used by Find and FindAll, if not empty.
+	defaultBy string
+
+	// actionLog, if not nil, records navigations and element interactions.
+	actionLog *ActionLog
+
+	// httpClient, if not nil, is used instead of HTTPClient to send the
+	// commands.
+	httpClient *http.Client
 }
 
 // HTTPClient is the default client to use to communicate with the WebDriver
 // server.
 var HTTPClient = http.DefaultClient
 
+// client returns the HTTP client used to send the commands of the session.
+func (wd *remoteWD) client() *http.Client {
+	if wd.httpClient != nil {
+		return wd.httpClient
+	}
+	return HTTPClient
+}
+
+func (wd *remoteWD) SetHTTPClient(client *http.Client) {
+	wd.httpClient = client
+}
+
 // jsonContentType is JSON content type.
 const jsonContentType = "application/json"
 
@@ -95,8 +150,14 @@ type serverReply struct {
 // Error contains information about a failure of a command. See the table of
 // these strings at https://www.w3.org/TR/webdriver/#handling-errors .
 //
-// This error type is only returned by servers that implement the W3C
-// specification.
+// Servers that implement the legacy JSON wire protocol report numeric status
+// codes instead, which are translated to the closest error strings and kept in
+// LegacyCode. Use IsNoSuchElement, IsStaleElement, IsElementNotInteractable
+// and IsTimeout to check for common errors.
+//
+// Some errors are returned wrapped in a more specific type, such as
+// *FrameDetachedError, so a type assertion to *Error does not find all of
+// them; the predicates above unwrap them.
 type Error struct {
 	// Err contains a general error string provided by the server.
 	Err string `json:"error"`
@@ -120,26 +181,171 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s: %s", e.Err, e.Message)
 }
 
+// IsNoSuchElement reports whether err is an *Error reporting that no element
+// matched the search.
+func IsNoSuchElement(err error) bool {
+	return errorIs(err, "no such element")
+}
+
+// IsStaleElement reports whether err is an *Error reporting that the element
+// is no longer attached to the DOM, e.g. because the page was re-rendered, so
+// that it has to be found again.
+func IsStaleElement(err error) bool {
+	return errorIs(err, "stale element reference")
+}
+
+// IsElementNotInteractable reports whether err is an *Error reporting that
+// the element cannot be interacted with, e.g. because it is hidden.
+func IsElementNotInteractable(err error) bool {
+	return errorIs(err, "element not interactable") || errorIs(err, "element not visible")
+}
+
+// IsTimeout reports whether err is an *Error reporting that a command, such as
+// a page load or an asynchronous script, did not complete in time.
+func IsTimeout(err error) bool {
+	return errorIs(err, "timeout") || errorIs(err, "script timeout")
+}
+
+// UnsupportedError is returned by methods that are not supported by the
+// browser or the driver of the current session.
+type UnsupportedError struct {
+	// Method is the name of the unsupported method.
+	Method string
+	// Browser is the name of the browser used by the session.
+	Browser string
+}
+
+// Error implements the error interface.
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by browser %q", e.Method, e.Browser)
+}
+
+// ContextError is returned by the methods that take a context, such as
+// WebDriver.GetWithContext, when the context is canceled or its deadline
+// passes before the remote end replies. The pending request is aborted.
+type ContextError struct {
+	// Method and URL are those of the aborted request.
+	Method, URL string
+	// Err is the error of the context, i.e. context.Canceled or
+	// context.DeadlineExceeded.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ContextError) Error() string {
+	if e.URL == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s %s: %v", e.Method, e.URL, e.Err)
+}
+
+// Unwrap returns the error of the context.
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+// FrameDetachedError is returned when a command fails because the frame that
+// the session was switched to has been detached from its page, e.g. because
+// the page navigated or a script replaced the iframe. The session has to be
+// switched to another frame to continue. See
+// WebDriver.SetFrameDetachedRecovery.
+type FrameDetachedError struct {
+	// Err is the error returned by the server.
+	Err *Error
+	// SwitchedToTop is true if the session was switched to the top-level
+	// browsing context after the error.
+	SwitchedToTop bool
+}
+
+// Error implements the error interface.
+func (e *FrameDetachedError) Error() string {
+	if e.SwitchedToTop {
+		return fmt.Sprintf("frame detached: %s; switched to the top-level browsing context", e.Err.Message)
+	}
+	return fmt.Sprintf("frame detached: %s", e.Err.Message)
+}
+
+// Unwrap returns the error returned by the server.
+func (e *FrameDetachedError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError returns a more specific error type for some errors returned
+// by the server.
+func classifyError(e *Error) error {
+	// ChromeDriver reports this as "no such window" or "unknown error",
+	// depending on the version.
+	if strings.Contains(e.Message, "target frame detached") {
+		return &FrameDetachedError{Err: e}
+	}
+	return e
+}
+
+func (wd *remoteWD) SetFrameDetachedRecovery(enabled bool) {
+	wd.recoverDetachedFrames = enabled
+}
+
+// recoverFrameDetached switches the session to the top-level browsing
+// context if err is a FrameDetachedError and recovery is enabled. It returns
+// err.
+func (wd *remoteWD) recoverFrameDetached(err error) error {
+	e, ok := err.(*FrameDetachedError)
+	if !ok || !wd.recoverDetachedFrames {
+		return err
+	}
+	url := wd.requestURL("/session/%s/frame", wd.id)
+	if _, serr := executeCommandContext(context.Background(), wd.client(), "POST", url, []byte(`{"id":null}`)); serr != nil {
+		debugLog("switching to the top-level browsing context after a detached frame: %v", serr)
+		return err
+	}
+	e.SwitchedToTop = true
+	return err
+}
+
 // execute performs an HTTP request and inspects the returned data for an error
 // encoded by the remote end in a JSON structure. If no error is present, the
 // entire, raw request payload is returned.
 func (wd *remoteWD) execute(method, url string, data []byte) (json.RawMessage, error) {
-	return executeCommand(method, url, data)
+	return wd.executeContext(context.Background(), method, url, data)
+}
+
+// executeContext is like execute, but aborts the request and returns a
+// *ContextError when ctx is done.
+func (wd *remoteWD) executeContext(ctx context.Context, method, url string, data []byte) (json.RawMessage, error) {
+	start := time.Now()
+	response, err := executeCommandContext(ctx, wd.client(), method, url, data)
+	if wd.recorder != nil {
+		wd.recorder.record(start, method, strings.TrimPrefix(url, wd.urlPrefix), data, response, err)
+	}
+	return response, wd.recoverFrameDetached(err)
 }
 
 func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
+	return executeCommandContext(context.Background(), HTTPClient, method, url, data)
+}
+
+// executeCommandContext is like executeCommand, but sends the request with
+// client and aborts it and returns a *ContextError when ctx is done.
+func executeCommandContext(ctx context.Context, client *http.Client, method, url string, data []byte) (json.RawMessage, error) {
 	debugLog("-> %s %s\n%s", method, filteredURL(url), data)
 	request, err := newRequest(method, url, data)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := HTTPClient.Do(request)
+	response, err := client.Do(request.WithContext(ctx))
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, &ContextError{Method: method, URL: filteredURL(url), Err: ctx.Err()}
+		}
 		return nil, err
 	}
+	defer response.Body.Close()
 
 	buf, err := ioutil.ReadAll(response.Body)
+	if err != nil && ctx.Err() != nil {
+		return nil, &ContextError{Method: method, URL: filteredURL(url), Err: ctx.Err()}
+	}
 	if debugFlag {
 		if err == nil {
 			// Pretty print the JSON response
@@ -180,7 +386,7 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 		respErr := new(Error)
 		if err := json.Unmarshal(reply.Value, respErr); err == nil && respErr.Err != "" {
 			respErr.HTTPCode = response.StatusCode
-			return nil, respErr
+			return nil, classifyError(respErr)
 		}
 	}
 
@@ -198,12 +404,12 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 		if err := json.Unmarshal(reply.Value, longMsg); err != nil {
 			return nil, errors.New(shortMsg)
 		}
-		return nil, &Error{
+		return nil, classifyError(&Error{
 			Err:        shortMsg,
 			Message:    longMsg.Message,
 			HTTPCode:   response.StatusCode,
 			LegacyCode: reply.Status,
-		}
+		})
 	}
 
 	return buf, nil
@@ -212,20 +418,64 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 // DefaultURLPrefix is the default HTTP endpoint that offers the WebDriver API.
 const DefaultURLPrefix = "http://127.0.0.1:4444/wd/hub"
 
+// The base paths under which remote ends serve the WebDriver API, which must
+// be part of the URL prefix passed to NewRemote, or passed to
+// NewRemoteWithBasePath.
+const (
+	// GridBasePath is the base path of Selenium servers and grids, e.g.
+	// "http://127.0.0.1:4444/wd/hub". Selenium 4 also serves the API at the
+	// root.
+	GridBasePath = "/wd/hub"
+	// DriverBasePath is the base path of drivers such as ChromeDriver and
+	// Geckodriver, i.e. the root, e.g. "http://127.0.0.1:9515", unless it was
+	// changed with their --url-base flag.
+	DriverBasePath = ""
+)
+
 // NewRemote creates new remote client, this will also start a new session.
 // capabilities provides the desired capabilities. urlPrefix is the URL to the
-// Selenium server, must be prefixed with protocol (http, https, ...).
+// Selenium server, must be prefixed with protocol (http, https, ...), and
+// includes the base path of the WebDriver API, e.g. GridBasePath for a
+// Selenium server or DriverBasePath for a driver. If the base path is wrong,
+// the error suggests checking it.
 //
 // Providing an empty string for urlPrefix causes the DefaultURLPrefix to be
 // used.
 func NewRemote(capabilities Capabilities, urlPrefix string) (WebDriver, error) {
+	return NewRemoteWithHTTPClient(capabilities, urlPrefix, nil)
+}
+
+// NewRemoteWithBasePath is like NewRemote, but takes the URL of the server,
+// e.g. "http://127.0.0.1:9515", and the base path of the WebDriver API on it,
+// e.g. GridBasePath or DriverBasePath, separately. If serverURL is empty, the
+// server of DefaultURLPrefix is used.
+func NewRemoteWithBasePath(capabilities Capabilities, serverURL, basePath string) (WebDriver, error) {
+	if serverURL == "" {
+		serverURL = strings.TrimSuffix(DefaultURLPrefix, GridBasePath)
+	}
+	basePath = strings.Trim(basePath, "/")
+	if basePath != "" {
+		basePath = "/" + basePath
+	}
+	return NewRemote(capabilities, strings.TrimSuffix(serverURL, "/")+basePath)
+}
+
+// NewRemoteWithHTTPClient is like NewRemote, but sends the commands of the
+// session, starting with the creation of the session, with client instead of
+// HTTPClient, e.g. to trust a private CA or tune the connection pool for
+// parallel sessions. The client only affects the connection to the WebDriver
+// server, not the traffic of the browser. If client is nil, HTTPClient is
+// used.
+func NewRemoteWithHTTPClient(capabilities Capabilities, urlPrefix string, client *http.Client) (WebDriver, error) {
 	if urlPrefix == "" {
 		urlPrefix = DefaultURLPrefix
 	}
 
 	wd := &remoteWD{
-		urlPrefix:    urlPrefix,
+		// The command paths start with a slash.
+		urlPrefix:    strings.TrimSuffix(urlPrefix, "/"),
 		capabilities: capabilities,
+		httpClient:   client,
 	}
 	if b := capabilities["browserName"]; b != nil {
 		wd.browser = b.(string)
@@ -279,7 +529,15 @@ func voidCommand(method, url string, params interface{}) error {
 }
 
 func (wd *remoteWD) voidCommand(urlTemplate string, params interface{}) error {
-	return voidCommand("POST", wd.requestURL(urlTemplate, wd.id), params)
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL(urlTemplate, wd.id), data)
+	return err
 }
 
 func (wd remoteWD) stringsCommand(urlTemplate string) ([]string, error) {
@@ -367,24 +625,33 @@ var chromeCapabilityNames = []string{
 }
 
 // Create a W3C-compatible capabilities instance.
-func newW3CCapabilities(caps Capabilities) Capabilities {
+func newW3CCapabilities(caps Capabilities) CapabilitiesRequest {
 	isValidW3CCapability := map[string]bool{}
 	for _, name := range w3cCapabilityNames {
 		isValidW3CCapability[name] = true
 	}
-	if b, ok := caps["browserName"]; ok && b == "chrome" {
+	browsers := map[interface{}]bool{caps["browserName"]: true}
+	alts, _ := caps[firstMatchKey].([]Capabilities)
+	for _, alt := range alts {
+		browsers[alt["browserName"]] = true
+	}
+	if browsers["chrome"] {
 		for _, name := range chromeCapabilityNames {
 			isValidW3CCapability[name] = true
 		}
 	}
-
-	alwaysMatch := make(Capabilities)
-	for name, value := range caps {
-		if isValidW3CCapability[name] || strings.Contains(name, ":") {
-			alwaysMatch[name] = value
+	filter := func(caps Capabilities) Capabilities {
+		filtered := make(Capabilities)
+		for name, value := range caps {
+			if isValidW3CCapability[name] || strings.Contains(name, ":") {
+				filtered[name] = value
+			}
 		}
+		return filtered
 	}
 
+	alwaysMatch := filter(caps)
+
 	// Move the Firefox profile setting from the old location to the new
 	// location.
 	if prof, ok := caps["firefox_profile"]; ok {
@@ -400,9 +667,80 @@ func newW3CCapabilities(caps Capabilities) Capabilities {
 		}
 	}
 
-	return Capabilities{
-		"alwaysMatch": alwaysMatch,
+	firstMatch := make([]Capabilities, len(alts))
+	for i, alt := range alts {
+		firstMatch[i] = filter(alt)
+	}
+	// A key may not be in both alwaysMatch and a firstMatch entry, so push
+	// shared keys, such as vendor options, down into every entry.
+	for name, value := range alwaysMatch {
+		shared := false
+		for _, alt := range firstMatch {
+			if _, ok := alt[name]; ok {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			continue
+		}
+		delete(alwaysMatch, name)
+		for _, alt := range firstMatch {
+			if altValue, ok := alt[name]; ok {
+				alt[name] = mergeCapability(value, altValue)
+			} else {
+				alt[name] = value
+			}
+		}
+	}
+
+	return CapabilitiesRequest{
+		AlwaysMatch: alwaysMatch,
+		FirstMatch:  firstMatch,
+	}
+}
+
+// mergeCapability returns override merged into base if both encode as JSON
+// objects, and override otherwise.
+func mergeCapability(base, override interface{}) interface{} {
+	toMap := func(v interface{}) map[string]interface{} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil
+		}
+		return m
+	}
+	baseMap, overrideMap := toMap(base), toMap(override)
+	if baseMap == nil || overrideMap == nil {
+		return override
+	}
+	for k, v := range overrideMap {
+		baseMap[k] = v
+	}
+	return baseMap
+}
+
+// checkBasePath adds a hint about the base path of the URL prefix to err, the
+// error returned when creating a session, if the remote end did not find the
+// command.
+func (wd *remoteWD) checkBasePath(err error) error {
+	e, ok := asError(err)
+	if !ok || (e.HTTPCode != http.StatusNotFound && e.Err != "unknown command") {
+		return err
+	}
+	u, perr := url.Parse(wd.urlPrefix)
+	if perr != nil {
+		return err
+	}
+	hint := fmt.Sprintf("the base path %q of the URL prefix may be wrong: Selenium servers use %q and drivers use none", u.Path, GridBasePath)
+	if u.Path == "" {
+		hint = fmt.Sprintf("the URL prefix has no base path: Selenium servers use %q", GridBasePath)
 	}
+	return fmt.Errorf("creating a session at %s: %v (%s)", wd.urlPrefix, err, hint)
 }
 
 func (wd *remoteWD) NewSession() (string, error) {
@@ -415,20 +753,30 @@ func (wd *remoteWD) NewSession() (string, error) {
 	//
 	// TODO(minusnine): audit which ones of these are still relevant. The W3C
 	// standard switched to the "alwaysMatch" version in February 2017.
+	desired := wd.capabilities
+	if _, ok := desired[firstMatchKey]; ok {
+		// The legacy protocol has no equivalent of firstMatch.
+		desired = make(Capabilities)
+		for k, v := range wd.capabilities {
+			if k != firstMatchKey {
+				desired[k] = v
+			}
+		}
+	}
 	attempts := []struct {
 		params map[string]interface{}
 	}{
 		{map[string]interface{}{
 			"capabilities":        newW3CCapabilities(wd.capabilities),
-			"desiredCapabilities": wd.capabilities,
+			"desiredCapabilities": desired,
 		}},
 		{map[string]interface{}{
 			"capabilities": map[string]interface{}{
-				"desiredCapabilities": wd.capabilities,
+				"desiredCapabilities": desired,
 			},
 		}},
 		{map[string]interface{}{
-			"desiredCapabilities": wd.capabilities,
+			"desiredCapabilities": desired,
 		}}}
 
 	for i, s := range attempts {
@@ -439,7 +787,7 @@ func (wd *remoteWD) NewSession() (string, error) {
 
 		response, err := wd.execute("POST", wd.requestURL("/session"), data)
 		if err != nil {
-			return "", err
+			return "", wd.checkBasePath(err)
 		}
 
 		reply := new(serverReply)
@@ -500,6 +848,19 @@ func (wd *remoteWD) NewSession() (string, error) {
 				caps = value.returnedCapabilities
 			}
 
+			// Keep the complete set of capabilities that the remote end
+			// negotiated.
+			negotiated := new(struct{ Capabilities Capabilities })
+			if err := json.Unmarshal(reply.Value, negotiated); err == nil && negotiated.Capabilities != nil {
+				wd.negotiated = negotiated.Capabilities
+			} else if err := json.Unmarshal(reply.Value, &wd.negotiated); err != nil {
+				debugLog("error unmarshalling capabilities: %v\n", err)
+			}
+			delete(wd.negotiated, "sessionId")
+			if b, ok := wd.negotiated["browserName"].(string); ok && wd.browser == "" {
+				wd.browser = b
+			}
+
 			for _, s := range []string{caps.Version, caps.BrowserVersion} {
 				if s == "" {
 					continue
@@ -536,6 +897,17 @@ func (wd *remoteWD) SwitchSession(sessionID string) error {
 }
 
 func (wd *remoteWD) Capabilities() (Capabilities, error) {
+	// The W3C specification has no command to fetch the capabilities of a
+	// session, e.g. geckodriver does not implement it, so return those that
+	// were negotiated when the session was created.
+	if wd.w3cCompatible && wd.negotiated != nil {
+		c := make(Capabilities, len(wd.negotiated))
+		for k, v := range wd.negotiated {
+			c[k] = v
+		}
+		return c, nil
+	}
+
 	url := wd.requestURL("/session/%s", wd.id)
 	response, err := wd.execute("GET", url, nil)
 	if err != nil {
@@ -584,10 +956,51 @@ func (wd *remoteWD) SetPageLoadTimeout(timeout time.Duration) error {
 	})
 }
 
+func (wd *remoteWD) PauseOnFailure(failed func() bool, d time.Duration) {
+	wd.pauseFailed = failed
+	wd.pauseDuration = d
+}
+
+var (
+	// pauseInput and pauseOutput are used by Quit to wait for the user after
+	// a failure.
+	pauseInput  io.Reader = os.Stdin
+	pauseOutput io.Writer = os.Stderr
+	// pauseInteractive reports whether pauseInput is a terminal, at which a
+	// user can press Enter.
+	pauseInteractive = stdinIsTerminal
+)
+
+// stdinIsTerminal reports whether the standard input is a terminal, rather
+// than e.g. a pipe that is never written to, as in CI systems.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// pauseIfFailed blocks if PauseOnFailure is enabled and the test failed.
+func (wd *remoteWD) pauseIfFailed() {
+	if wd.pauseFailed == nil || !wd.pauseFailed() {
+		return
+	}
+	if wd.pauseDuration > 0 {
+		fmt.Fprintf(pauseOutput, "selenium: failure detected; keeping session %s open for %v\n", wd.id, wd.pauseDuration)
+		time.Sleep(wd.pauseDuration)
+		return
+	}
+	if !pauseInteractive() {
+		fmt.Fprintf(pauseOutput, "selenium: failure detected; not keeping session %s open, as standard input is not a terminal\n", wd.id)
+		return
+	}
+	fmt.Fprintf(pauseOutput, "selenium: failure detected; keeping session %s open, press Enter to quit\n", wd.id)
+	bufio.NewReader(pauseInput).ReadString('\n')
+}
+
 func (wd *remoteWD) Quit() error {
 	if wd.id == "" {
 		return nil
 	}
+	wd.pauseIfFailed()
 	_, err := wd.execute("DELETE", wd.requestURL("/session/%s", wd.id), nil)
 	if err == nil {
 		wd.id = ""
@@ -624,6 +1037,15 @@ func (wd *remoteWD) CurrentURL() (string, error) {
 }
 
 func (wd *remoteWD) Get(url string) error {
+	return wd.getContext(context.Background(), url)
+}
+
+func (wd *remoteWD) GetWithContext(ctx context.Context, url string) error {
+	return wd.getContext(ctx, url)
+}
+
+// getContext navigates to url, aborting the command when ctx is done.
+func (wd *remoteWD) getContext(ctx context.Context, url string) error {
 	requestURL := wd.requestURL("/session/%s/url", wd.id)
 	params := map[string]string{
 		"url": url,
@@ -632,8 +1054,11 @@ func (wd *remoteWD) Get(url string) error {
 	if err != nil {
 		return err
 	}
-	_, err = wd.execute("POST", requestURL, data)
-	return err
+	if _, err = wd.executeContext(ctx, "POST", requestURL, data); err != nil {
+		return err
+	}
+	wd.logAction(LoggedAction{Type: ActionNavigate, URL: url})
+	return nil
 }
 
 func (wd *remoteWD) Forward() error {
@@ -656,24 +1081,59 @@ func (wd *remoteWD) PageSource() (string, error) {
 	return wd.stringCommand("/session/%s/source")
 }
 
+// InvalidSelectorError is returned when finding elements with a selector that
+// the driver rejects as malformed, e.g. because of a syntax error in a CSS
+// selector or an XPath expression.
+type InvalidSelectorError struct {
+	// By is the method by which elements were searched for, e.g. ByXPATH.
+	By string
+	// Selector is the offending selector.
+	Selector string
+	// Err is the error returned by the server.
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *InvalidSelectorError) Error() string {
+	return fmt.Sprintf("invalid selector %q (%s): %s", e.Selector, e.By, e.Err.Message)
+}
+
+// NoSuchShadowRootError is returned by WebElement.GetShadowRoot when the
+// element has no shadow root, or only a closed one.
+type NoSuchShadowRootError struct {
+	// Err is the error returned by the server.
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *NoSuchShadowRootError) Error() string {
+	return fmt.Sprintf("no such shadow root: %s", e.Err.Message)
+}
+
 func (wd *remoteWD) find(by, value, suffix, url string) ([]byte, error) {
+	return wd.findContext(context.Background(), by, value, suffix, url)
+}
+
+// findContext is like find, but aborts the command when ctx is done.
+func (wd *remoteWD) findContext(ctx context.Context, by, value, suffix, url string) ([]byte, error) {
+	using, selector := by, value
 	// The W3C specification removed the specific ID and Name locator strategies,
 	// instead only providing a CSS-based strategy. Emulate the old behavior to
 	// maintain API compatibility.
 	if wd.w3cCompatible {
 		switch by {
 		case ByID:
-			by = ByCSSSelector
-			value = "#" + value
+			using = ByCSSSelector
+			selector = "#" + value
 		case ByName:
-			by = ByCSSSelector
-			value = fmt.Sprintf("input[name=%q]", value)
+			using = ByCSSSelector
+			selector = fmt.Sprintf("input[name=%q]", value)
 		}
 	}
 
 	params := map[string]string{
-		"using": by,
-		"value": value,
+		"using": using,
+		"value": selector,
 	}
 	data, err := json.Marshal(params)
 	if err != nil {
@@ -684,11 +1144,42 @@ func (wd *remoteWD) find(by, value, suffix, url string) ([]byte, error) {
 		url = "/session/%s/element"
 	}
 
-	return wd.execute("POST", wd.requestURL(url+suffix, wd.id), data)
+	response, err := wd.executeContext(ctx, "POST", wd.requestURL(url+suffix, wd.id), data)
+	if e, ok := asError(err); ok && e.Err == "invalid selector" {
+		return nil, &InvalidSelectorError{By: by, Selector: value, Err: e}
+	}
+	return response, err
+}
+
+func (wd *remoteWD) SetElementFactory(factory func(base WebElement) WebElement) {
+	wd.elementFactory = factory
+}
+
+// newElement returns the element with the provided reference, wrapped by the
+// element factory if one is set.
+func (wd *remoteWD) newElement(id string) WebElement {
+	return wd.newLocatedElement(id, nil)
+}
+
+// newLocatedElement is like newElement for an element that was found by the
+// provided locator, which is used to find it again when replaying an
+// ActionLog.
+func (wd *remoteWD) newLocatedElement(id string, locator []ElementLocator) WebElement {
+	var elem WebElement = &remoteWE{parent: wd, id: id, locator: locator}
+	if wd.elementFactory != nil {
+		elem = wd.elementFactory(elem)
+	}
+	return elem
 }
 
 func (wd *remoteWD) DecodeElement(data []byte) (WebElement, error) {
-	reply := new(struct{ Value map[string]string })
+	return wd.decodeFoundElement(data, nil, "", "")
+}
+
+// decodeFoundElement decodes the element found by the strategy by and value
+// within the element with the provided locator. See locate.
+func (wd *remoteWD) decodeFoundElement(data []byte, parent []ElementLocator, by, value string) (WebElement, error) {
+	reply := new(struct{ Value map[string]interface{} })
 	if err := json.Unmarshal(data, &reply); err != nil {
 		return nil, err
 	}
@@ -697,10 +1188,7 @@ func (wd *remoteWD) DecodeElement(data []byte) (WebElement, error) {
 	if id == "" {
 		return nil, fmt.Errorf("invalid element returned: %+v", reply)
 	}
-	return &remoteWE{
-		parent: wd,
-		id:     id,
-	}, nil
+	return wd.newLocatedElement(id, locate(parent, by, value, 0)), nil
 }
 
 const (
@@ -712,11 +1200,19 @@ const (
 	// webElementIdentifier is the string constant defined by the W3C
 	// specification that is the key for the map that contains a unique element identifier.
 	webElementIdentifier = "element-6066-11e4-a52e-4f735466cecf"
+
+	// shadowRootIdentifier is the string constant defined by the W3C
+	// specification that is the key for the map that contains a unique shadow
+	// root identifier.
+	shadowRootIdentifier = "shadow-6066-11e4-a52e-4f735466cecf"
 )
 
-func elementIDFromValue(v map[string]string) string {
+// elementIDFromValue returns the element reference in v. Either key is
+// accepted regardless of the protocol of the session, as some servers return
+// the legacy key in W3C mode.
+func elementIDFromValue(v map[string]interface{}) string {
 	for _, key := range []string{webElementIdentifier, legacyWebElementIdentifier} {
-		v, ok := v[key]
+		v, ok := v[key].(string)
 		if !ok || v == "" {
 			continue
 		}
@@ -726,7 +1222,12 @@ func elementIDFromValue(v map[string]string) string {
 }
 
 func (wd *remoteWD) DecodeElements(data []byte) ([]WebElement, error) {
-	reply := new(struct{ Value []map[string]string })
+	return wd.decodeFoundElements(data, nil, "", "")
+}
+
+// decodeFoundElements is like decodeFoundElement for multiple elements.
+func (wd *remoteWD) decodeFoundElements(data []byte, parent []ElementLocator, by, value string) ([]WebElement, error) {
+	reply := new(struct{ Value []map[string]interface{} })
 	if err := json.Unmarshal(data, reply); err != nil {
 		return nil, err
 	}
@@ -737,10 +1238,7 @@ func (wd *remoteWD) DecodeElements(data []byte) ([]WebElement, error) {
 		if id == "" {
 			return nil, fmt.Errorf("invalid element returned: %+v", reply)
 		}
-		elems[i] = &remoteWE{
-			parent: wd,
-			id:     id,
-		}
+		elems[i] = wd.newLocatedElement(id, locate(parent, by, value, i))
 	}
 
 	return elems, nil
@@ -751,7 +1249,7 @@ func (wd *remoteWD) FindElement(by, value string) (WebElement, error) {
 	if err != nil {
 		return nil, err
 	}
-	return wd.DecodeElement(response)
+	return wd.decodeFoundElement(response, documentLocator, by, value)
 }
 
 func (wd *remoteWD) FindElements(by, value string) ([]WebElement, error) {
@@ -760,7 +1258,64 @@ func (wd *remoteWD) FindElements(by, value string) ([]WebElement, error) {
 		return nil, err
 	}
 
-	return wd.DecodeElements(response)
+	return wd.decodeFoundElements(response, documentLocator, by, value)
+}
+
+func (wd *remoteWD) FindElementsWithContext(ctx context.Context, by, value string) ([]WebElement, error) {
+	response, err := wd.findContext(ctx, by, value, "s", "")
+	if err != nil {
+		return nil, err
+	}
+
+	return wd.decodeFoundElements(response, documentLocator, by, value)
+}
+
+func (wd *remoteWD) SetDefaultStrategy(by string) {
+	wd.defaultBy = by
+}
+
+// defaultStrategy returns the strategy used by Find and FindAll.
+func (wd *remoteWD) defaultStrategy() string {
+	if wd.defaultBy == "" {
+		return ByCSSSelector
+	}
+	return wd.defaultBy
+}
+
+func (wd *remoteWD) Find(value string) (WebElement, error) {
+	return wd.FindElement(wd.defaultStrategy(), value)
+}
+
+func (wd *remoteWD) FindAll(value string) ([]WebElement, error) {
+	return wd.FindElements(wd.defaultStrategy(), value)
+}
+
+func (wd *remoteWD) ClickIfPresent(by, value string) (bool, error) {
+	elems, err := wd.FindElements(by, value)
+	if err != nil {
+		return false, err
+	}
+	for _, elem := range elems {
+		displayed, err := elem.IsDisplayed()
+		if errorIs(err, "stale element reference") {
+			// The element went away, e.g. a banner that dismissed itself.
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !displayed {
+			continue
+		}
+		if err := elem.Click(); err != nil {
+			if errorIs(err, "stale element reference") {
+				continue
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
 }
 
 func (wd *remoteWD) Close() error {
@@ -783,6 +1338,29 @@ func (wd *remoteWD) CloseWindow(name string) error {
 	return wd.modifyWindow(name, "DELETE", "", nil)
 }
 
+func (wd *remoteWD) NewWindow(typ string) (string, error) {
+	if !wd.w3cCompatible {
+		return "", errors.New("opening a new window requires a W3C-compatible session")
+	}
+	data, err := json.Marshal(map[string]string{"type": typ})
+	if err != nil {
+		return "", err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/window/new", wd.id), data)
+	if err != nil {
+		return "", err
+	}
+	reply := new(struct {
+		Value struct {
+			Handle string `json:"handle"`
+		}
+	})
+	if err := json.Unmarshal(response, reply); err != nil {
+		return "", err
+	}
+	return reply.Value.Handle, nil
+}
+
 func (wd *remoteWD) MaximizeWindow(name string) error {
 	if !wd.w3cCompatible {
 		if name != "" {
@@ -804,6 +1382,33 @@ func (wd *remoteWD) MinimizeWindow(name string) error {
 }
 
 func (wd *remoteWD) modifyWindow(name, verb, command string, params interface{}) error {
+	url := wd.requestURL("/session/%s/window", wd.id)
+	if command != "" {
+		if wd.w3cCompatible {
+			url = wd.requestURL("/session/%s/window/%s", wd.id, command)
+		} else {
+			url = wd.requestURL("/session/%s/window/%s/%s", wd.id, name, command)
+		}
+	}
+
+	var data []byte
+	if params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+
+	return wd.inWindow(name, func() error {
+		_, err := wd.execute(verb, url, data)
+		return err
+	})
+}
+
+// inWindow calls fn with the named window as the current window, if name is
+// not empty and the session is W3C-compatible, and switches back to the
+// original window afterwards.
+func (wd *remoteWD) inWindow(name string, fn func() error) error {
 	// The original protocol allowed for maximizing any named window. The W3C
 	// specification only allows the current window be be modified. Emulate the
 	// previous behavior by switching to the target window, maximizing the
@@ -822,24 +1427,7 @@ func (wd *remoteWD) modifyWindow(name, verb, command string, params interface{})
 		}
 	}
 
-	url := wd.requestURL("/session/%s/window", wd.id)
-	if command != "" {
-		if wd.w3cCompatible {
-			url = wd.requestURL("/session/%s/window/%s", wd.id, command)
-		} else {
-			url = wd.requestURL("/session/%s/window/%s/%s", wd.id, name, command)
-		}
-	}
-
-	var data []byte
-	if params != nil {
-		var err error
-		if data, err = json.Marshal(params); err != nil {
-			return err
-		}
-	}
-
-	if _, err := wd.execute(verb, url, data); err != nil {
+	if err := fn(); err != nil {
 		return err
 	}
 
@@ -860,10 +1448,128 @@ func (wd *remoteWD) ResizeWindow(name string, width, height int) error {
 			"height": height,
 		})
 	}
-	return wd.modifyWindow(name, "POST", "rect", map[string]float64{
-		"width":  float64(width),
-		"height": float64(height),
-	})
+	return wd.inWindow(name, func() error {
+		r, err := wd.GetWindowRect()
+		if err != nil {
+			return err
+		}
+		r.Width, r.Height = width, height
+		_, err = wd.SetWindowRect(r)
+		return err
+	})
+}
+
+// windowRect is the JSON representation of a window's rect.
+type windowRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r windowRect) toRect() Rect {
+	return Rect{X: round(r.X), Y: round(r.Y), Width: round(r.Width), Height: round(r.Height)}
+}
+
+func (wd *remoteWD) GetWindowRect() (Rect, error) {
+	if !wd.w3cCompatible {
+		return Rect{}, errors.New("getting the window rect requires a W3C-compatible session")
+	}
+	response, err := wd.execute("GET", wd.requestURL("/session/%s/window/rect", wd.id), nil)
+	if err != nil {
+		return Rect{}, err
+	}
+	reply := new(struct{ Value windowRect })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return Rect{}, err
+	}
+	return reply.Value.toRect(), nil
+}
+
+func (wd *remoteWD) SetWindowRect(r Rect) (Rect, error) {
+	if !wd.w3cCompatible {
+		return Rect{}, errors.New("setting the window rect requires a W3C-compatible session")
+	}
+	data, err := json.Marshal(windowRect{
+		X:      float64(r.X),
+		Y:      float64(r.Y),
+		Width:  float64(r.Width),
+		Height: float64(r.Height),
+	})
+	if err != nil {
+		return Rect{}, err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/window/rect", wd.id), data)
+	if err != nil {
+		return Rect{}, err
+	}
+	reply := new(struct{ Value windowRect })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return Rect{}, err
+	}
+	return reply.Value.toRect(), nil
+}
+
+// windowStateCommand sends the command, such as "maximize", that changes the
+// state of the current window and returns the resulting rect.
+func (wd *remoteWD) windowStateCommand(command string) (Rect, error) {
+	if !wd.w3cCompatible {
+		return Rect{}, fmt.Errorf("%s requires a W3C-compatible session", command)
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/window/%s", wd.id, command), []byte("{}"))
+	if err != nil {
+		return Rect{}, err
+	}
+	reply := new(struct{ Value windowRect })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return Rect{}, err
+	}
+	return reply.Value.toRect(), nil
+}
+
+func (wd *remoteWD) Maximize() (Rect, error) {
+	return wd.windowStateCommand("maximize")
+}
+
+func (wd *remoteWD) Minimize() (Rect, error) {
+	return wd.windowStateCommand("minimize")
+}
+
+func (wd *remoteWD) Fullscreen() (Rect, error) {
+	return wd.windowStateCommand("fullscreen")
+}
+
+const (
+	// stableWindowRectTolerance is the difference, in pixels, between the
+	// requested and reported window rects accepted by SetWindowRectStable.
+	stableWindowRectTolerance = 2
+	// stableWindowRectTimeout is how long SetWindowRectStable retries for.
+	stableWindowRectTimeout = 5 * time.Second
+)
+
+func (wd *remoteWD) SetWindowRectStable(want Rect) (Rect, error) {
+	within := func(a, b int) bool {
+		d := a - b
+		return d >= -stableWindowRectTolerance && d <= stableWindowRectTolerance
+	}
+	var got Rect
+	err := wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		var err error
+		if _, err = wd.SetWindowRect(want); err != nil {
+			return false, err
+		}
+		// The rect returned by the command may predate the window manager
+		// applying it, so read it back separately.
+		if got, err = wd.GetWindowRect(); err != nil {
+			return false, err
+		}
+		return within(got.X, want.X) && within(got.Y, want.Y) &&
+			within(got.Width, want.Width) && within(got.Height, want.Height), nil
+	}, stableWindowRectTimeout)
+	if err != nil {
+		return got, fmt.Errorf("setting window rect to %+v: %v; current rect is %+v", want, err, got)
+	}
+	return got, nil
 }
 
 func (wd *remoteWD) SwitchFrame(frame interface{}) error {
@@ -889,6 +1595,23 @@ func (wd *remoteWD) SwitchFrame(frame interface{}) error {
 	return wd.voidCommand("/session/%s/frame", params)
 }
 
+func (wd *remoteWD) SwitchToParentFrame() error {
+	return wd.voidCommand("/session/%s/frame/parent", nil)
+}
+
+func (wd *remoteWD) WithinFrame(frame WebElement, fn func() error) (err error) {
+	if err := wd.SwitchFrame(frame); err != nil {
+		return err
+	}
+	defer func() {
+		// Switch back even if fn panics, but report fn's error first.
+		if perr := wd.SwitchToParentFrame(); perr != nil && err == nil {
+			err = fmt.Errorf("switching back to the parent frame: %v", perr)
+		}
+	}()
+	return fn()
+}
+
 func (wd *remoteWD) ActiveElement() (WebElement, error) {
 	verb := "GET"
 	if wd.browser == "firefox" && wd.browserVersion.Major < 47 {
@@ -902,6 +1625,27 @@ func (wd *remoteWD) ActiveElement() (WebElement, error) {
 	return wd.DecodeElement(response)
 }
 
+func (wd *remoteWD) DeepActiveElement() (WebElement, []WebElement, error) {
+	var frames []WebElement
+	for {
+		elem, err := wd.ActiveElement()
+		if err != nil {
+			return nil, frames, err
+		}
+		tag, err := elem.TagName()
+		if err != nil {
+			return nil, frames, err
+		}
+		if tag = strings.ToLower(tag); tag != "iframe" && tag != "frame" {
+			return elem, frames, nil
+		}
+		if err := wd.SwitchFrame(elem); err != nil {
+			return nil, frames, err
+		}
+		frames = append(frames, elem)
+	}
+}
+
 // ChromeDriver returns the expiration date as a float. Handle both formats
 // via a type switch.
 type cookie struct {
@@ -912,7 +1656,7 @@ type cookie struct {
 	Secure   bool        `json:"secure"`
 	Expiry   interface{} `json:"expiry"`
 	HTTPOnly bool        `json:"httpOnly"`
-	SameSite string      `json:"sameSite",omitempty`
+	SameSite string      `json:"sameSite,omitempty"`
 }
 
 func (c cookie) sanitize() Cookie {
@@ -952,8 +1696,23 @@ func (c cookie) sanitize() Cookie {
 	}
 }
 
+// NoSuchCookieError is returned by WebDriver.GetCookie when the jar has no
+// cookie with the requested name.
+type NoSuchCookieError struct {
+	// Name is the name of the missing cookie.
+	Name string
+	// Err is the error returned by the server, if any.
+	Err *Error
+}
+
+// Error implements the error interface.
+func (e *NoSuchCookieError) Error() string {
+	return fmt.Sprintf("no such cookie %q", e.Name)
+}
+
 func (wd *remoteWD) GetCookie(name string) (Cookie, error) {
-	if wd.browser == "chrome" {
+	// Old versions of ChromeDriver do not implement the command.
+	if wd.browser == "chrome" && !wd.w3cCompatible {
 		cs, err := wd.GetCookies()
 		if err != nil {
 			return Cookie{}, err
@@ -963,10 +1722,12 @@ func (wd *remoteWD) GetCookie(name string) (Cookie, error) {
 				return c, nil
 			}
 		}
-		return Cookie{}, errors.New("cookie not found")
+		return Cookie{}, &NoSuchCookieError{Name: name}
+	}
+	data, err := wd.execute("GET", wd.requestURL("/session/%s/cookie/%s", wd.id, url.PathEscape(name)), nil)
+	if e, ok := asError(err); ok && e.Err == "no such cookie" {
+		return Cookie{}, &NoSuchCookieError{Name: name, Err: e}
 	}
-	url := wd.requestURL("/session/%s/cookie/%s", wd.id, name)
-	data, err := wd.execute("GET", url, nil)
 	if err != nil {
 		return Cookie{}, err
 	}
@@ -984,7 +1745,7 @@ func (wd *remoteWD) GetCookie(name string) (Cookie, error) {
 		return Cookie{}, err
 	}
 	if len(listReply.Value) == 0 {
-		return Cookie{}, errors.New("no cookies returned")
+		return Cookie{}, &NoSuchCookieError{Name: name}
 	}
 	return listReply.Value[0].sanitize(), nil
 }
@@ -1026,8 +1787,105 @@ func (wd *remoteWD) DeleteCookie(name string) error {
 	return err
 }
 
+func (wd *remoteWD) ExportCookies() ([]byte, error) {
+	var cookies []Cookie
+	var err error
+	if wd.browser == "chrome" {
+		cookies, err = wd.allCookies()
+	} else {
+		cookies, err = wd.GetCookies()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(cookies, "", "  ")
+}
+
+func (wd *remoteWD) ImportCookies(data []byte) error {
+	var cookies []Cookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return err
+	}
+	// Browsers drop expired cookies as soon as they are set, or reject them.
+	now := uint(time.Now().Unix())
+	var live []Cookie
+	for _, c := range cookies {
+		if c.Expiry != 0 && c.Expiry <= now {
+			continue
+		}
+		live = append(live, c)
+	}
+	if len(live) == 0 {
+		return nil
+	}
+	if wd.browser == "chrome" {
+		return wd.setCookies(live)
+	}
+	return wd.addCookiesByDomain(live)
+}
+
+// addCookiesByDomain adds the cookies using the WebDriver API, which only
+// allows setting cookies for the domain of the current page. The browser is
+// navigated to each cookie's domain as needed, and back to the original page
+// afterwards.
+func (wd *remoteWD) addCookiesByDomain(cookies []Cookie) error {
+	// Group the cookies by domain to minimize navigation, with secure cookies
+	// first so that a single HTTPS page can be used for all of them.
+	sort.SliceStable(cookies, func(i, j int) bool {
+		if cookies[i].Domain != cookies[j].Domain {
+			return cookies[i].Domain < cookies[j].Domain
+		}
+		return cookies[i].Secure && !cookies[j].Secure
+	})
+
+	orig, err := wd.CurrentURL()
+	if err != nil {
+		return err
+	}
+	current := orig
+	for _, c := range cookies {
+		c := c
+		if c.Domain != "" && !cookieCanBeSetFrom(current, &c) {
+			scheme := "http"
+			if c.Secure {
+				scheme = "https"
+			}
+			current = scheme + "://" + strings.TrimPrefix(c.Domain, ".") + "/"
+			if err := wd.Get(current); err != nil {
+				return fmt.Errorf("navigating to %q to add cookie %q: %v", current, c.Name, err)
+			}
+		}
+		if err := wd.AddCookie(&c); err != nil {
+			return fmt.Errorf("adding cookie %q for domain %q: %v", c.Name, c.Domain, err)
+		}
+	}
+	if current != orig {
+		return wd.Get(orig)
+	}
+	return nil
+}
+
+// cookieCanBeSetFrom reports whether the cookie can be set from the page at
+// pageURL: the page's host must domain-match the cookie's domain and secure
+// cookies require an HTTPS page.
+func cookieCanBeSetFrom(pageURL string, c *Cookie) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return false
+	}
+	if c.Secure && u.Scheme != "https" {
+		return false
+	}
+	host := u.Hostname()
+	domain := strings.TrimPrefix(c.Domain, ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
 // TODO(minusnine): add a test for Click.
 func (wd *remoteWD) Click(button int) error {
+	if wd.w3cCompatible {
+		return wd.NewActions().PointerDown(button).PointerUp(button).Perform()
+	}
 	return wd.voidCommand("/session/%s/click", map[string]int{
 		"button": button,
 	})
@@ -1035,16 +1893,28 @@ func (wd *remoteWD) Click(button int) error {
 
 // TODO(minusnine): add a test for DoubleClick.
 func (wd *remoteWD) DoubleClick() error {
+	if wd.w3cCompatible {
+		return wd.NewActions().
+			PointerDown(LeftButton).PointerUp(LeftButton).
+			PointerDown(LeftButton).PointerUp(LeftButton).
+			Perform()
+	}
 	return wd.voidCommand("/session/%s/doubleclick", nil)
 }
 
 // TODO(minusnine): add a test for ButtonDown.
 func (wd *remoteWD) ButtonDown() error {
+	if wd.w3cCompatible {
+		return wd.NewActions().PointerDown(LeftButton).Perform()
+	}
 	return wd.voidCommand("/session/%s/buttondown", nil)
 }
 
 // TODO(minusnine): add a test for ButtonUp.
 func (wd *remoteWD) ButtonUp() error {
+	if wd.w3cCompatible {
+		return wd.NewActions().PointerUp(LeftButton).Perform()
+	}
 	return wd.voidCommand("/session/%s/buttonup", nil)
 }
 
@@ -1067,13 +1937,19 @@ func (wd *remoteWD) keyAction(action, keys string) error {
 			Key:  string(key),
 		})
 	}
+	return wd.performActions(map[string]interface{}{
+		"type":    "key",
+		"id":      keyboardSourceID,
+		"actions": actions,
+	})
+}
+
+// performActions implements the "Perform Actions" method of the W3C
+// standard. Each source is an input source, e.g. a keyboard or a pointer, with
+// its sequence of actions.
+func (wd *remoteWD) performActions(sources ...interface{}) error {
 	return wd.voidCommand("/session/%s/actions", map[string]interface{}{
-		"actions": []interface{}{
-			map[string]interface{}{
-				"type":    "key",
-				"id":      "default keyboard",
-				"actions": actions,
-			}},
+		"actions": sources,
 	})
 }
 
@@ -1095,8 +1971,6 @@ func (wd *remoteWD) KeyUp(keys string) error {
 	return wd.keyAction("keyUp", keys)
 }
 
-// TODO(minusnine): Implement PerformActions and ReleaseActions, for more
-// direct access to the W3C specification.
 func (wd *remoteWD) DismissAlert() error {
 	return wd.voidCommand("/session/%s/alert/dismiss", nil)
 }
@@ -1118,7 +1992,7 @@ func (wd *remoteWD) SetAlertText(text string) error {
 	return wd.voidCommand("/session/%s/alert/text", data)
 }
 
-func (wd *remoteWD) execScriptRaw(script string, args []interface{}, suffix string) ([]byte, error) {
+func (wd *remoteWD) execScriptRaw(ctx context.Context, script string, args []interface{}, suffix string) ([]byte, error) {
 	if args == nil {
 		args = make([]interface{}, 0)
 	}
@@ -1131,49 +2005,128 @@ func (wd *remoteWD) execScriptRaw(script string, args []interface{}, suffix stri
 		return nil, err
 	}
 
-	return wd.execute("POST", wd.requestURL("/session/%s/execute"+suffix, wd.id), data)
+	return wd.executeContext(ctx, "POST", wd.requestURL("/session/%s/execute"+suffix, wd.id), data)
 }
 
-func (wd *remoteWD) execScript(script string, args []interface{}, suffix string) (interface{}, error) {
-	response, err := wd.execScriptRaw(script, args, suffix)
+// execScriptValue executes the script and returns the undecoded "value" field
+// of the reply.
+func (wd *remoteWD) execScriptValue(ctx context.Context, script string, args []interface{}, suffix string) (json.RawMessage, error) {
+	response, err := wd.execScriptRaw(ctx, script, args, suffix)
 	if err != nil {
 		return nil, err
 	}
 
-	reply := new(struct{ Value interface{} })
-	if err = json.Unmarshal(response, reply); err != nil {
+	reply := new(struct{ Value json.RawMessage })
+	if err := json.Unmarshal(response, reply); err != nil {
 		return nil, err
 	}
-
 	return reply.Value, nil
 }
 
+func (wd *remoteWD) execScript(ctx context.Context, script string, args []interface{}, suffix string) (interface{}, error) {
+	raw, err := wd.execScriptValue(ctx, script, args, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if len(raw) > 0 {
+		if err = json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+	}
+
+	if wd.elementFactory != nil {
+		return wd.decodeScriptElements(value), nil
+	}
+	return value, nil
+}
+
+// decodeScriptElements replaces the element references in v, a decoded script
+// result, with WebElement values.
+func (wd *remoteWD) decodeScriptElements(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = wd.decodeScriptElements(v[i])
+		}
+	case map[string]interface{}:
+		if len(v) <= 2 {
+			ref := make(map[string]interface{})
+			for _, key := range []string{webElementIdentifier, legacyWebElementIdentifier} {
+				if id, ok := v[key]; ok {
+					ref[key] = id
+				}
+			}
+			if len(ref) == len(v) {
+				if id := elementIDFromValue(ref); id != "" {
+					return wd.newElement(id)
+				}
+			}
+		}
+		for key := range v {
+			v[key] = wd.decodeScriptElements(v[key])
+		}
+	}
+	return v
+}
+
+// execScriptInto executes a script and JSON-decodes its return value into v.
+func (wd *remoteWD) execScriptInto(script string, args []interface{}, v interface{}) error {
+	raw, err := wd.ExecuteScriptRawValue(script, args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
 func (wd *remoteWD) ExecuteScript(script string, args []interface{}) (interface{}, error) {
 	if !wd.w3cCompatible {
-		return wd.execScript(script, args, "")
+		return wd.execScript(context.Background(), script, args, "")
 	}
-	return wd.execScript(script, args, "/sync")
+	return wd.execScript(context.Background(), script, args, "/sync")
 }
 
 func (wd *remoteWD) ExecuteScriptAsync(script string, args []interface{}) (interface{}, error) {
 	if !wd.w3cCompatible {
-		return wd.execScript(script, args, "_async")
+		return wd.execScript(context.Background(), script, args, "_async")
 	}
-	return wd.execScript(script, args, "/async")
+	return wd.execScript(context.Background(), script, args, "/async")
+}
+
+func (wd *remoteWD) ExecuteScriptAsyncWithContext(ctx context.Context, script string, args []interface{}) (interface{}, error) {
+	if !wd.w3cCompatible {
+		return wd.execScript(ctx, script, args, "_async")
+	}
+	return wd.execScript(ctx, script, args, "/async")
 }
 
 func (wd *remoteWD) ExecuteScriptRaw(script string, args []interface{}) ([]byte, error) {
 	if !wd.w3cCompatible {
-		return wd.execScriptRaw(script, args, "")
+		return wd.execScriptRaw(context.Background(), script, args, "")
 	}
-	return wd.execScriptRaw(script, args, "/sync")
+	return wd.execScriptRaw(context.Background(), script, args, "/sync")
 }
 
 func (wd *remoteWD) ExecuteScriptAsyncRaw(script string, args []interface{}) ([]byte, error) {
 	if !wd.w3cCompatible {
-		return wd.execScriptRaw(script, args, "_async")
+		return wd.execScriptRaw(context.Background(), script, args, "_async")
+	}
+	return wd.execScriptRaw(context.Background(), script, args, "/async")
+}
+
+func (wd *remoteWD) ExecuteScriptRawValue(script string, args []interface{}) (json.RawMessage, error) {
+	if !wd.w3cCompatible {
+		return wd.execScriptValue(context.Background(), script, args, "")
 	}
-	return wd.execScriptRaw(script, args, "/async")
+	return wd.execScriptValue(context.Background(), script, args, "/sync")
+}
+
+func (wd *remoteWD) ExecuteScriptAsyncRawValue(script string, args []interface{}) (json.RawMessage, error) {
+	if !wd.w3cCompatible {
+		return wd.execScriptValue(context.Background(), script, args, "_async")
+	}
+	return wd.execScriptValue(context.Background(), script, args, "/async")
 }
 
 func (wd *remoteWD) Screenshot() ([]byte, error) {
@@ -1201,19 +2154,57 @@ const (
 	DefaultWaitTimeout = 60 * time.Second
 )
 
+// errorIs reports whether err is an *Error returned by the server with the
+// provided error string, or a *FrameDetachedError that wraps one. Legacy
+// status codes are translated to the W3C strings by executeCommand.
+func errorIs(err error, code string) bool {
+	e, ok := asError(err)
+	return ok && e.Err == code
+}
+
+// asError returns the *Error reported by the remote end that err is or wraps,
+// e.g. in a *FrameDetachedError.
+func asError(err error) (*Error, bool) {
+	switch e := err.(type) {
+	case *Error:
+		return e, true
+	case *FrameDetachedError:
+		return e.Err, e.Err != nil
+	}
+	return nil, false
+}
+
+// isTransientWaitError reports whether an error returned by a Condition only
+// means that the page is not ready yet, e.g. because it is being re-rendered,
+// so that waits should keep polling rather than fail.
+func isTransientWaitError(err error) bool {
+	if _, ok := err.(waitState); ok {
+		return true
+	}
+	return IsNoSuchElement(err) || IsStaleElement(err)
+}
+
 func (wd *remoteWD) WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error {
 	startTime := time.Now()
 
+	var lastErr error
 	for {
 		done, err := condition(wd)
-		if err != nil {
+		if err != nil && !isTransientWaitError(err) {
 			return err
 		}
-		if done {
+		if done && err == nil {
 			return nil
 		}
+		lastErr = err
 
 		if elapsed := time.Since(startTime); elapsed > timeout {
+			if _, ok := lastErr.(waitState); ok {
+				return fmt.Errorf("timeout after %v; last state: %v", elapsed, lastErr)
+			}
+			if lastErr != nil {
+				return fmt.Errorf("timeout after %v; last error: %v", elapsed, lastErr)
+			}
 			return fmt.Errorf("timeout after %v", elapsed)
 		}
 		time.Sleep(interval)
@@ -1228,6 +2219,49 @@ func (wd *remoteWD) Wait(condition Condition) error {
 	return wd.WaitWithTimeoutAndInterval(condition, DefaultWaitTimeout, DefaultWaitInterval)
 }
 
+func (wd *remoteWD) WaitForTitle(title string, timeout time.Duration) error {
+	return wd.waitForTitle(TitleIs(title), timeout, fmt.Sprintf("title %q", title))
+}
+
+func (wd *remoteWD) WaitForTitleContains(substr string, timeout time.Duration) error {
+	return wd.waitForTitle(TitleContains(substr), timeout, fmt.Sprintf("title containing %q", substr))
+}
+
+// waitForTitle waits for condition and, upon failure, annotates the error
+// with the description of the expected title and the actual title.
+func (wd *remoteWD) waitForTitle(condition Condition, timeout time.Duration, want string) error {
+	err := wd.WaitWithTimeout(condition, timeout)
+	if err == nil {
+		return nil
+	}
+	title, titleErr := wd.Title()
+	if titleErr != nil {
+		return fmt.Errorf("waiting for %s: %v", want, err)
+	}
+	return fmt.Errorf("waiting for %s: %v; current title is %q", want, err, title)
+}
+
+func (wd *remoteWD) GetElement(by, value string, timeout time.Duration) (WebElement, error) {
+	var elem WebElement
+	err := wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		e, err := wd.FindElement(by, value)
+		if err != nil {
+			return false, err
+		}
+		// Check that the reference is usable, as the page may have replaced the
+		// element right after it was found, e.g. while re-rendering.
+		if _, err := e.TagName(); err != nil {
+			return false, err
+		}
+		elem = e
+		return true, nil
+	}, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for element %s %q: %v", by, value, err)
+	}
+	return elem, nil
+}
+
 func (wd *remoteWD) Log(typ log.Type) ([]log.Message, error) {
 	url := wd.requestURL("/session/%s/log", wd.id)
 	params := map[string]log.Type{
@@ -1275,16 +2309,115 @@ type remoteWE struct {
 	// that the value is called a "reference". For ease of transition, we store
 	// the "reference" in this now misnamed field.
 	id string
+	// locator is how the element was found, or nil if it is unknown, e.g.
+	// because the element was returned by a script.
+	locator []ElementLocator
 }
 
 func (elem *remoteWE) Click() error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/click", elem.id)
-	return elem.parent.voidCommand(urlTemplate, nil)
+	return elem.parent.recordInteraction(elem, "click", "", func() error {
+		urlTemplate := fmt.Sprintf("/session/%%s/element/%s/click", elem.id)
+		return elem.parent.voidCommand(urlTemplate, nil)
+	})
+}
+
+func (elem *remoteWE) ClickNoScroll() error {
+	wd := elem.parent
+	if !wd.w3cCompatible {
+		return errors.New("ClickNoScroll requires a W3C-compatible session")
+	}
+
+	// Compute the center of the element relative to the viewport without
+	// scrolling it into view, which "Element Click" would do.
+	center, err := elem.Center()
+	if err != nil {
+		return err
+	}
+
+	return wd.performActions(map[string]interface{}{
+		"type":       "pointer",
+		"id":         mouseSourceID,
+		"parameters": map[string]string{"pointerType": "mouse"},
+		"actions": []map[string]interface{}{
+			{
+				"type":     "pointerMove",
+				"duration": 0,
+				"origin":   "viewport",
+				"x":        center.X,
+				"y":        center.Y,
+			},
+			{"type": "pointerDown", "button": LeftButton},
+			{"type": "pointerUp", "button": LeftButton},
+		},
+	})
+}
+
+// elementCenterScript returns the center of the element provided as the first
+// argument, in viewport coordinates.
+const elementCenterScript = `
+var r = arguments[0].getBoundingClientRect();
+return {X: r.left + r.width / 2, Y: r.top + r.height / 2};
+`
+
+func (elem *remoteWE) Center() (*Point, error) {
+	center := new(struct{ X, Y float64 })
+	if err := elem.parent.execScriptInto(elementCenterScript, []interface{}{elem}, center); err != nil {
+		return nil, err
+	}
+	return &Point{round(center.X), round(center.Y)}, nil
+}
+
+// focusScript focuses the element provided as the first argument and returns
+// whether the browser runs on macOS, where the "select all" shortcut uses the
+// Meta key instead of Control.
+const focusScript = `
+arguments[0].focus();
+return /Mac/.test(navigator.platform);
+`
+
+func (elem *remoteWE) ReplaceText(text string) error {
+	wd := elem.parent
+	if !wd.w3cCompatible {
+		return errors.New("ReplaceText requires a W3C-compatible session")
+	}
+	var isMac bool
+	if err := wd.execScriptInto(focusScript, []interface{}{elem}, &isMac); err != nil {
+		return err
+	}
+	modifier := ControlKey
+	if isMac {
+		modifier = MetaKey
+	}
+
+	press := func(key string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"type": "keyDown", "value": key},
+			{"type": "keyUp", "value": key},
+		}
+	}
+	// Select the current text and type over it, so that the field never
+	// becomes empty in between.
+	actions := []map[string]interface{}{{"type": "keyDown", "value": modifier}}
+	actions = append(actions, press("a")...)
+	actions = append(actions, map[string]interface{}{"type": "keyUp", "value": modifier})
+	if text == "" {
+		actions = append(actions, press(BackspaceKey)...)
+	}
+	for _, r := range text {
+		actions = append(actions, press(string(r))...)
+	}
+	return wd.performActions(map[string]interface{}{
+		"type":    "key",
+		"id":      keyboardSourceID,
+		"actions": actions,
+	})
 }
 
 func (elem *remoteWE) SendKeys(keys string) error {
-	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/value", elem.id)
-	return elem.parent.voidCommand(urlTemplate, elem.parent.processKeyString(keys))
+	return elem.parent.recordInteraction(elem, "sendKeys", keys, func() error {
+		urlTemplate := fmt.Sprintf("/session/%%s/element/%s/value", elem.id)
+		return elem.parent.voidCommand(urlTemplate, elem.parent.processKeyString(keys))
+	})
 }
 
 func (wd *remoteWD) processKeyString(keys string) interface{} {
@@ -1319,6 +2452,9 @@ func (elem *remoteWE) Clear() error {
 }
 
 func (elem *remoteWE) MoveTo(xOffset, yOffset int) error {
+	if elem.parent.w3cCompatible {
+		return elem.parent.NewActions().PointerMove(elem, xOffset, yOffset, 0).Perform()
+	}
 	return elem.parent.voidCommand("/session/%s/moveto", map[string]interface{}{
 		"element": elem.id,
 		"xoffset": xOffset,
@@ -1333,7 +2469,7 @@ func (elem *remoteWE) FindElement(by, value string) (WebElement, error) {
 		return nil, err
 	}
 
-	return elem.parent.DecodeElement(response)
+	return elem.parent.decodeFoundElement(response, elem.locator, by, value)
 }
 
 func (elem *remoteWE) FindElements(by, value string) ([]WebElement, error) {
@@ -1343,7 +2479,30 @@ func (elem *remoteWE) FindElements(by, value string) ([]WebElement, error) {
 		return nil, err
 	}
 
-	return elem.parent.DecodeElements(response)
+	return elem.parent.decodeFoundElements(response, elem.locator, by, value)
+}
+
+func (elem *remoteWE) GetShadowRoot() (ShadowRoot, error) {
+	wd := elem.parent
+	if !wd.w3cCompatible {
+		return nil, errors.New("GetShadowRoot requires a W3C-compatible session")
+	}
+	response, err := wd.execute("GET", wd.requestURL("/session/%s/element/%s/shadow", wd.id, elem.id), nil)
+	if e, ok := asError(err); ok && e.Err == "no such shadow root" {
+		return nil, &NoSuchShadowRootError{Err: e}
+	}
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value map[string]interface{} })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	id, ok := reply.Value[shadowRootIdentifier].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("invalid shadow root returned: %+v", reply)
+	}
+	return &remoteSR{parent: wd, id: id}, nil
 }
 
 func (elem *remoteWE) boolQuery(urlTemplate string) (bool, error) {
@@ -1413,8 +2572,30 @@ func (elem *remoteWE) Location() (*Point, error) {
 	return elem.location("")
 }
 
+// locationInViewScript scrolls the element provided as the first argument
+// into view, if it is not already, and returns its location relative to the
+// viewport.
+const locationInViewScript = `
+var e = arguments[0];
+var r = e.getBoundingClientRect();
+if (r.top < 0 || r.left < 0 || r.bottom > window.innerHeight || r.right > window.innerWidth) {
+  e.scrollIntoView({block: 'nearest', inline: 'nearest'});
+  r = e.getBoundingClientRect();
+}
+return {x: r.left, y: r.top};
+`
+
 func (elem *remoteWE) LocationInView() (*Point, error) {
-	return elem.location("_in_view")
+	if !elem.parent.w3cCompatible {
+		return elem.location("_in_view")
+	}
+	// W3C has no equivalent of the legacy "location_in_view" endpoint, and
+	// the element rect is relative to the document rather than the viewport.
+	var r rect
+	if err := elem.parent.execScriptInto(locationInViewScript, []interface{}{elem}, &r); err != nil {
+		return nil, err
+	}
+	return &Point{round(r.X), round(r.Y)}, nil
 }
 
 func (elem *remoteWE) Size() (*Size, error) {
@@ -1467,11 +2648,46 @@ func (elem *remoteWE) CSSProperty(name string) (string, error) {
 	return wd.stringCommand(fmt.Sprintf("/session/%%s/element/%s/css/%s", elem.id, name))
 }
 
+// MarshalJSON encodes the element reference with the key of the protocol
+// negotiated for the session.
 func (elem *remoteWE) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]string{
-		"ELEMENT":            elem.id,
-		webElementIdentifier: elem.id,
-	})
+	key := legacyWebElementIdentifier
+	if elem.parent.w3cCompatible {
+		key = webElementIdentifier
+	}
+	return json.Marshal(map[string]string{key: elem.id})
+}
+
+// remoteSR is the shadow root of an element of a remote session.
+type remoteSR struct {
+	parent *remoteWD
+	id     string
+}
+
+func (sr *remoteSR) FindElement(by, value string) (WebElement, error) {
+	url := fmt.Sprintf("/session/%%s/shadow/%s/element", sr.id)
+	response, err := sr.parent.find(by, value, "", url)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.parent.DecodeElement(response)
+}
+
+func (sr *remoteSR) FindElements(by, value string) ([]WebElement, error) {
+	url := fmt.Sprintf("/session/%%s/shadow/%s/element", sr.id)
+	response, err := sr.parent.find(by, value, "s", url)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.parent.DecodeElements(response)
+}
+
+// MarshalJSON encodes the shadow root reference, so that it can be passed as
+// a script argument.
+func (sr *remoteSR) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{shadowRootIdentifier: sr.id})
 }
 
 func (elem *remoteWE) Screenshot(scroll bool) ([]byte, error) {
@@ -0,0 +1,1947 @@
+package selenium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTestRemote returns a client for a W3C-compatible session against a
+// server that answers every request with the provided status code and JSON
+// body.
+func newTestRemote(t *testing.T, code int, body string) (*remoteWD, func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+	wd := &remoteWD{
+		id:            "test-session",
+		urlPrefix:     s.URL,
+		w3cCompatible: true,
+	}
+	return wd, s.Close
+}
+
+func TestFindInvalidSelector(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusBadRequest, `{"value": {"error": "invalid selector", "message": "Unable to locate an element with the xpath expression //div[", "stacktrace": ""}}`)
+	defer done()
+
+	const selector = "//div["
+	_, err := wd.FindElement(ByXPATH, selector)
+	e, ok := err.(*InvalidSelectorError)
+	if !ok {
+		t.Fatalf("wd.FindElement(%q, %q) returned error %v of type %T, want *InvalidSelectorError", ByXPATH, selector, err, err)
+	}
+	if e.By != ByXPATH || e.Selector != selector {
+		t.Errorf("wd.FindElement(%q, %q) returned error with By = %q, Selector = %q", ByXPATH, selector, e.By, e.Selector)
+	}
+	if e.Err.HTTPCode != http.StatusBadRequest {
+		t.Errorf("wd.FindElement(%q, %q) returned error with HTTP code %d, want %d", ByXPATH, selector, e.Err.HTTPCode, http.StatusBadRequest)
+	}
+}
+
+func TestFindNoSuchElementIsNotInvalidSelector(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusNotFound, `{"value": {"error": "no such element", "message": "no such element", "stacktrace": ""}}`)
+	defer done()
+
+	_, err := wd.FindElement(ByCSSSelector, "#missing")
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("wd.FindElement(_, _) returned error %v of type %T, want *Error", err, err)
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	for _, tc := range []struct {
+		code                                     int
+		body                                     string
+		noSuchElement, stale, notInteract, timed bool
+	}{
+		{http.StatusNotFound, `{"value": {"error": "no such element", "message": "m"}}`, true, false, false, false},
+		{http.StatusNotFound, `{"value": {"error": "stale element reference", "message": "m"}}`, false, true, false, false},
+		{http.StatusBadRequest, `{"value": {"error": "element not interactable", "message": "m"}}`, false, false, true, false},
+		{http.StatusInternalServerError, `{"value": {"error": "timeout", "message": "m"}}`, false, false, false, true},
+		{http.StatusInternalServerError, `{"value": {"error": "script timeout", "message": "m"}}`, false, false, false, true},
+		// Legacy status codes.
+		{http.StatusOK, `{"status": 7, "value": {"message": "m"}}`, true, false, false, false},
+		{http.StatusOK, `{"status": 10, "value": {"message": "m"}}`, false, true, false, false},
+		{http.StatusOK, `{"status": 11, "value": {"message": "m"}}`, false, false, true, false},
+		{http.StatusOK, `{"status": 21, "value": {"message": "m"}}`, false, false, false, true},
+	} {
+		wd, done := newTestRemote(t, tc.code, tc.body)
+		_, err := wd.Title()
+		done()
+		if err == nil {
+			t.Fatalf("With reply %s, wd.Title() returned nil error", tc.body)
+		}
+		if got := IsNoSuchElement(err); got != tc.noSuchElement {
+			t.Errorf("IsNoSuchElement(%v) = %t, want %t", err, got, tc.noSuchElement)
+		}
+		if got := IsStaleElement(err); got != tc.stale {
+			t.Errorf("IsStaleElement(%v) = %t, want %t", err, got, tc.stale)
+		}
+		if got := IsElementNotInteractable(err); got != tc.notInteract {
+			t.Errorf("IsElementNotInteractable(%v) = %t, want %t", err, got, tc.notInteract)
+		}
+		if got := IsTimeout(err); got != tc.timed {
+			t.Errorf("IsTimeout(%v) = %t, want %t", err, got, tc.timed)
+		}
+	}
+	if IsNoSuchElement(errors.New("no such element")) {
+		t.Errorf("IsNoSuchElement() of an error that was not returned by the server = true, want false")
+	}
+}
+
+func TestAlertIsPresent(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		w3c     bool
+		code    int
+		body    string
+		want    bool
+		wantErr bool
+	}{
+		{"open", true, http.StatusOK, `{"value": "Are you sure?"}`, true, false},
+		{"W3C no such alert", true, http.StatusNotFound, `{"value": {"error": "no such alert", "message": ""}}`, false, false},
+		{"legacy no alert open", false, http.StatusInternalServerError, `{"status": 27, "value": {"message": "no alert"}}`, false, false},
+		{"other error", true, http.StatusInternalServerError, `{"value": {"error": "unknown error", "message": ""}}`, false, true},
+	} {
+		wd, done := newTestRemote(t, tc.code, tc.body)
+		wd.w3cCompatible = tc.w3c
+		got, err := AlertIsPresent()(wd)
+		done()
+		// Missing alerts are reported as a state that does not abort waits.
+		fatal := err != nil && !isTransientWaitError(err)
+		if got != tc.want || fatal != tc.wantErr {
+			t.Errorf("%s: AlertIsPresent() = (%t, %v), want %t and fatal error = %t", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestWaitTimeoutReportsLastState(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": "http://example.com/login"}`)
+	defer done()
+
+	err := wd.WaitWithTimeoutAndInterval(URLContains("/home"), 10*time.Millisecond, time.Millisecond)
+	if err == nil {
+		t.Fatalf("Waiting for URLContains() returned nil error, want a timeout")
+	}
+	if want := `last state: current URL is "http://example.com/login"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("Waiting for URLContains() returned %q, want it to contain %q", err, want)
+	}
+}
+
+func TestFrameDetached(t *testing.T) {
+	var switchedToTop bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		if r.URL.Path == "/session/test-session/frame" {
+			switchedToTop = true
+			fmt.Fprint(w, `{"value": null}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"value": {"error": "no such window", "message": "no such window: target frame detached", "stacktrace": ""}}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	for _, recover := range []bool{false, true} {
+		switchedToTop = false
+		wd.SetFrameDetachedRecovery(recover)
+		_, err := wd.FindElement(ByID, "id")
+		e, ok := err.(*FrameDetachedError)
+		if !ok {
+			t.Fatalf("With recovery %t, wd.FindElement() returned error %v of type %T, want *FrameDetachedError", recover, err, err)
+		}
+		if e.SwitchedToTop != recover || switchedToTop != recover {
+			t.Errorf("With recovery %t, SwitchedToTop = %t and the frame was switched = %t, want both %t", recover, e.SwitchedToTop, switchedToTop, recover)
+		}
+	}
+}
+
+func TestAsErrorUnwrapsFrameDetached(t *testing.T) {
+	// A detached frame can be reported with any error code.
+	wd, done := newTestRemote(t, http.StatusBadRequest, `{"value": {"error": "invalid selector", "message": "invalid selector: target frame detached", "stacktrace": ""}}`)
+	defer done()
+
+	_, err := wd.FindElement(ByXPATH, "//[")
+	if _, ok := err.(*InvalidSelectorError); !ok {
+		t.Errorf("wd.FindElement() returned error %v of type %T, want *InvalidSelectorError", err, err)
+	}
+
+	e, ok := asError(&FrameDetachedError{Err: &Error{Err: "no such window"}})
+	if !ok || e.Err != "no such window" {
+		t.Errorf("asError(&FrameDetachedError{...}) = (%v, %t), want the wrapped *Error", e, ok)
+	}
+	if _, ok := asError(errors.New("other")); ok {
+		t.Errorf("asError() of an error not reported by the remote end returned true")
+	}
+}
+
+func TestOutlinePNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() returned error: %v", err)
+	}
+
+	data, err := outlinePNG(buf.Bytes(), image.Rect(5, 5, 10, 10))
+	if err != nil {
+		t.Fatalf("outlinePNG() returned error: %v", err)
+	}
+	got, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	red := color.RGBA{R: 255, A: 255}
+	for _, p := range []image.Point{{4, 4}, {7, 4}, {4, 7}, {10, 7}, {7, 10}} {
+		if c := color.RGBAModel.Convert(got.At(p.X, p.Y)); c != red {
+			t.Errorf("pixel %v = %v, want %v", p, c, red)
+		}
+	}
+	for _, p := range []image.Point{{7, 7}, {0, 0}, {15, 15}} {
+		if c := color.RGBAModel.Convert(got.At(p.X, p.Y)); c == red {
+			t.Errorf("pixel %v is outlined, want it unchanged", p)
+		}
+	}
+}
+
+func TestCropPNG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	red := color.RGBA{R: 255, A: 255}
+	img.Set(5, 5, red)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() returned error: %v", err)
+	}
+
+	data, err := cropPNG(buf.Bytes(), image.Rect(5, 5, 15, 10))
+	if err != nil {
+		t.Fatalf("cropPNG() returned error: %v", err)
+	}
+	got, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() returned error: %v", err)
+	}
+	if b := got.Bounds(); b.Dx() != 10 || b.Dy() != 5 {
+		t.Errorf("cropPNG() returned an image of size %dx%d, want 10x5", b.Dx(), b.Dy())
+	}
+	if c := color.RGBAModel.Convert(got.At(got.Bounds().Min.X, got.Bounds().Min.Y)); c != red {
+		t.Errorf("top-left pixel = %v, want %v", c, red)
+	}
+
+	if _, err := cropPNG(buf.Bytes(), image.Rect(10, 10, 30, 30)); err == nil {
+		t.Errorf("cropPNG() of a region outside the image returned no error")
+	}
+}
+
+func TestDecodeElementKeys(t *testing.T) {
+	tests := []struct {
+		desc string
+		w3c  bool
+		data string
+	}{
+		{
+			desc: "W3C key in W3C mode",
+			w3c:  true,
+			data: `{"value": {"element-6066-11e4-a52e-4f735466cecf": "abc"}}`,
+		},
+		{
+			desc: "legacy key in W3C mode",
+			w3c:  true,
+			data: `{"value": {"ELEMENT": "abc"}}`,
+		},
+		{
+			desc: "W3C key in legacy mode",
+			data: `{"value": {"element-6066-11e4-a52e-4f735466cecf": "abc"}}`,
+		},
+		{
+			desc: "legacy key with a non-string field",
+			data: `{"value": {"ELEMENT": "abc", "index": 3}}`,
+		},
+	}
+	for _, tc := range tests {
+		wd := &remoteWD{w3cCompatible: tc.w3c}
+		elem, err := wd.DecodeElement([]byte(tc.data))
+		if err != nil {
+			t.Errorf("%s: DecodeElement(%s) returned error: %v", tc.desc, tc.data, err)
+			continue
+		}
+		if id := elem.(*remoteWE).id; id != "abc" {
+			t.Errorf("%s: DecodeElement(%s) returned element with ID %q, want %q", tc.desc, tc.data, id, "abc")
+		}
+	}
+}
+
+func TestMarshalElementKey(t *testing.T) {
+	for _, tc := range []struct {
+		w3c  bool
+		want string
+	}{
+		{w3c: true, want: `{"element-6066-11e4-a52e-4f735466cecf":"abc"}`},
+		{w3c: false, want: `{"ELEMENT":"abc"}`},
+	} {
+		elem := &remoteWE{parent: &remoteWD{w3cCompatible: tc.w3c}, id: "abc"}
+		got, err := json.Marshal(elem)
+		if err != nil {
+			t.Fatalf("json.Marshal(elem) returned error: %v", err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("json.Marshal(elem) with w3cCompatible = %t returned %s, want %s", tc.w3c, got, tc.want)
+		}
+	}
+}
+
+func TestGetShadowRoot(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": {"shadow-6066-11e4-a52e-4f735466cecf": "root-1"}}`)
+	defer done()
+
+	elem := &remoteWE{parent: wd, id: "host"}
+	root, err := elem.GetShadowRoot()
+	if err != nil {
+		t.Fatalf("elem.GetShadowRoot() returned error: %v", err)
+	}
+	got, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("json.Marshal(root) returned error: %v", err)
+	}
+	if want := `{"shadow-6066-11e4-a52e-4f735466cecf":"root-1"}`; string(got) != want {
+		t.Errorf("json.Marshal(root) returned %s, want %s", got, want)
+	}
+}
+
+func TestGetShadowRootMissing(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusNotFound, `{"value": {"error": "no such shadow root", "message": "no such shadow root", "stacktrace": ""}}`)
+	defer done()
+
+	elem := &remoteWE{parent: wd, id: "plain"}
+	_, err := elem.GetShadowRoot()
+	if _, ok := err.(*NoSuchShadowRootError); !ok {
+		t.Fatalf("elem.GetShadowRoot() returned error %v of type %T, want *NoSuchShadowRootError", err, err)
+	}
+}
+
+func TestCookieCanBeSetFrom(t *testing.T) {
+	tests := []struct {
+		url    string
+		cookie Cookie
+		want   bool
+	}{
+		{"http://example.com/a", Cookie{Domain: "example.com"}, true},
+		{"http://example.com/a", Cookie{Domain: ".example.com"}, true},
+		{"http://www.example.com/", Cookie{Domain: ".example.com"}, true},
+		{"http://example.com/", Cookie{Domain: "www.example.com"}, false},
+		{"http://notexample.com/", Cookie{Domain: "example.com"}, false},
+		{"http://example.com/", Cookie{Domain: "example.com", Secure: true}, false},
+		{"https://example.com/", Cookie{Domain: "example.com", Secure: true}, true},
+		{"http://127.0.0.1:8080/", Cookie{Domain: "127.0.0.1"}, true},
+		{"about:blank", Cookie{Domain: "example.com"}, false},
+	}
+	for _, tc := range tests {
+		if got := cookieCanBeSetFrom(tc.url, &tc.cookie); got != tc.want {
+			t.Errorf("cookieCanBeSetFrom(%q, %+v) = %t, want %t", tc.url, tc.cookie, got, tc.want)
+		}
+	}
+}
+
+type taggedElement struct {
+	WebElement
+	tag string
+}
+
+func TestCookieRoundTrip(t *testing.T) {
+	var stored json.RawMessage
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		if r.Method == "POST" {
+			body := new(struct{ Cookie json.RawMessage })
+			if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+				t.Errorf("Decoding the request body returned error: %v", err)
+			}
+			stored = body.Cookie
+			fmt.Fprint(w, `{"value": null}`)
+			return
+		}
+		fmt.Fprintf(w, `{"value": [%s]}`, stored)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	expiry := time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)
+	want := Cookie{Name: "name", Value: "value", Path: "/", SameSite: SameSiteLax}
+	want.SetExpiry(expiry)
+	if err := wd.AddCookie(&want); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	if !strings.Contains(string(stored), `"sameSite":"Lax"`) {
+		t.Errorf("wd.AddCookie() sent cookie %s, want it to include the SameSite attribute", stored)
+	}
+	cookies, err := wd.GetCookies()
+	if err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	}
+	if diff := cmp.Diff([]Cookie{want}, cookies); diff != "" {
+		t.Errorf("wd.GetCookies() returned diff (-want +got):\n%s", diff)
+	}
+	if got := cookies[0].ExpiryTime(); !got.Equal(expiry) {
+		t.Errorf("cookies[0].ExpiryTime() = %v, want %v", got, expiry)
+	}
+
+	if b, err := json.Marshal(Cookie{Name: "n"}); err != nil || strings.Contains(string(b), "sameSite") {
+		t.Errorf("json.Marshal() of a cookie without SameSite = %s, %v, want the attribute omitted", b, err)
+	}
+}
+
+func TestGetCookieMissing(t *testing.T) {
+	var path string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"value": {"error": "no such cookie", "message": "no such cookie"}}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "chrome"}
+
+	const name = "a cookie"
+	_, err := wd.GetCookie(name)
+	e, ok := err.(*NoSuchCookieError)
+	if !ok {
+		t.Fatalf("wd.GetCookie(%q) returned error %v of type %T, want *NoSuchCookieError", name, err, err)
+	}
+	if e.Name != name || e.Err == nil {
+		t.Errorf("wd.GetCookie(%q) returned error %+v, want one for %q wrapping the server's error", name, e, name)
+	}
+	if want := "/session/test-session/cookie/a%20cookie"; path != want {
+		t.Errorf("wd.GetCookie(%q) requested path %q, want %q", name, path, want)
+	}
+}
+
+func TestElementFactory(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": {"element-6066-11e4-a52e-4f735466cecf": "abc"}}`)
+	defer done()
+	wd.SetElementFactory(func(base WebElement) WebElement {
+		return &taggedElement{WebElement: base, tag: "tagged"}
+	})
+
+	elem, err := wd.FindElement(ByID, "id")
+	if err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+	if e, ok := elem.(*taggedElement); !ok || e.WebElement.(*remoteWE).id != "abc" {
+		t.Errorf("wd.FindElement() returned %#v, want a *taggedElement wrapping element %q", elem, "abc")
+	}
+
+	v, err := wd.ExecuteScript("return document.body;", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if _, ok := v.(*taggedElement); !ok {
+		t.Errorf("wd.ExecuteScript() returned %#v, want a *taggedElement", v)
+	}
+}
+
+func TestDecodeScriptElements(t *testing.T) {
+	wd := &remoteWD{w3cCompatible: true}
+	var v interface{}
+	const data = `{"elems": [{"ELEMENT": "a"}, {"element-6066-11e4-a52e-4f735466cecf": "b", "ELEMENT": "b"}], "notElem": {"ELEMENT": "c", "other": 1}}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	got := wd.decodeScriptElements(v).(map[string]interface{})
+	elems := got["elems"].([]interface{})
+	for i, want := range []string{"a", "b"} {
+		if e, ok := elems[i].(*remoteWE); !ok || e.id != want {
+			t.Errorf("elems[%d] = %#v, want element %q", i, elems[i], want)
+		}
+	}
+	if _, ok := got["notElem"].(map[string]interface{}); !ok {
+		t.Errorf("notElem = %#v, want it left as a map", got["notElem"])
+	}
+}
+
+func TestWaitTransientErrors(t *testing.T) {
+	wd := &remoteWD{}
+	for _, code := range []string{"no such element", "stale element reference"} {
+		calls := 0
+		err := wd.WaitWithTimeoutAndInterval(func(WebDriver) (bool, error) {
+			calls++
+			if calls < 3 {
+				return false, &Error{Err: code}
+			}
+			return true, nil
+		}, time.Second, time.Millisecond)
+		if err != nil {
+			t.Errorf("Wait with %q errors returned error: %v", code, err)
+		}
+		if calls != 3 {
+			t.Errorf("Wait with %q errors called the condition %d times, want 3", code, calls)
+		}
+	}
+
+	err := wd.WaitWithTimeoutAndInterval(func(WebDriver) (bool, error) {
+		return false, &Error{Err: "stale element reference", Message: "gone"}
+	}, 10*time.Millisecond, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "gone") {
+		t.Errorf("Wait that times out returned error %v, want one mentioning the last error", err)
+	}
+
+	calls := 0
+	invalid := &InvalidSelectorError{By: ByXPATH, Selector: "//[", Err: &Error{Err: "invalid selector"}}
+	err = wd.WaitWithTimeoutAndInterval(func(WebDriver) (bool, error) {
+		calls++
+		return false, invalid
+	}, time.Second, time.Millisecond)
+	if err != invalid || calls != 1 {
+		t.Errorf("Wait with an invalid selector returned %v after %d calls, want %v after 1 call", err, calls, invalid)
+	}
+}
+
+func TestNewW3CCapabilitiesFirstMatch(t *testing.T) {
+	caps := Capabilities{
+		"browserName":   "chrome",
+		"sauce:options": map[string]interface{}{"name": "test", "build": "1"},
+		"legacyOnly":    true,
+	}
+	caps.AddFirstMatch(Capabilities{
+		"platformName":  "Windows 10",
+		"sauce:options": map[string]interface{}{"screenResolution": "1920x1080"},
+	})
+	caps.AddFirstMatch(Capabilities{
+		"platformName": "macOS 10.15",
+		"legacyOnly":   false,
+	})
+
+	data, err := json.Marshal(newW3CCapabilities(caps))
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	var got interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	const wantJSON = `{
+		"alwaysMatch": {"browserName": "chrome"},
+		"firstMatch": [
+			{
+				"platformName": "Windows 10",
+				"sauce:options": {"name": "test", "build": "1", "screenResolution": "1920x1080"}
+			},
+			{
+				"platformName": "macOS 10.15",
+				"sauce:options": {"name": "test", "build": "1"}
+			}
+		]
+	}`
+	var want interface{}
+	if err := json.Unmarshal([]byte(wantJSON), &want); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newW3CCapabilities() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func TestMarshalSession(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": {"sessionId": "abc", "capabilities": {"browserName": "firefox", "browserVersion": "78.0.1", "acceptInsecureCerts": true}}}`)
+	defer done()
+	wd.id = ""
+	wd.w3cCompatible = false
+	if _, err := wd.NewSession(); err != nil {
+		t.Fatalf("wd.NewSession() returned error: %v", err)
+	}
+
+	data, err := wd.MarshalSession()
+	if err != nil {
+		t.Fatalf("wd.MarshalSession() returned error: %v", err)
+	}
+	resumed, err := UnmarshalSession(data)
+	if err != nil {
+		t.Fatalf("UnmarshalSession(%s) returned error: %v", data, err)
+	}
+	got := resumed.(*remoteWD)
+	if got.id != "abc" || got.urlPrefix != wd.urlPrefix || !got.w3cCompatible || got.browser != "firefox" || got.browserVersion.Major != 78 {
+		t.Errorf("UnmarshalSession(%s) = %+v, want a W3C firefox 78 session %q at %q", data, got, "abc", wd.urlPrefix)
+	}
+	want := Capabilities{"browserName": "firefox", "browserVersion": "78.0.1", "acceptInsecureCerts": true}
+	if diff := cmp.Diff(want, got.negotiated); diff != "" {
+		t.Errorf("UnmarshalSession(%s) returned capabilities diff (-want/+got):\n%s", data, diff)
+	}
+
+	if _, err := UnmarshalSession([]byte(`{"id": "abc"}`)); err == nil {
+		t.Errorf("UnmarshalSession() without a URL returned nil, want an error")
+	}
+}
+
+func TestConnectToSession(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		w3c     bool
+		replies map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "W3C",
+			w3c:     true,
+			replies: map[string]string{"/session/abc/window": `{"value": "handle"}`},
+		},
+		{
+			name:    "W3C without a current window",
+			w3c:     true,
+			replies: map[string]string{"/session/abc/window": `{"value": {"error": "no such window", "message": "window closed"}}`},
+		},
+		{
+			name: "legacy",
+			replies: map[string]string{
+				"/session/abc/window":        `{"status": 9, "value": {"message": "unknown command"}}`,
+				"/session/abc/window_handle": `{"status": 0, "value": "handle"}`,
+			},
+		},
+		{
+			name:    "ended",
+			replies: map[string]string{"/session/abc/window": `{"value": {"error": "invalid session id", "message": "gone"}}`},
+			wantErr: true,
+		},
+	} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reply, ok := tc.replies[r.URL.Path]
+			if !ok {
+				t.Errorf("%s: unexpected request %s %s", tc.name, r.Method, r.URL.Path)
+				reply = `{"value": {"error": "unknown command", "message": ""}}`
+			}
+			w.Header().Set("Content-Type", jsonContentType)
+			fmt.Fprint(w, reply)
+		}))
+		wd, err := ConnectToSession(s.URL, "abc", Capabilities{"browserName": "firefox", "browserVersion": "78.0"})
+		s.Close()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ConnectToSession() returned nil error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: ConnectToSession() returned error: %v", tc.name, err)
+		}
+		got := wd.(*remoteWD)
+		if got.SessionID() != "abc" || got.w3cCompatible != tc.w3c || got.browser != "firefox" || got.browserVersion.Major != 78 {
+			t.Errorf("%s: ConnectToSession() = %+v, want a firefox 78 session %q with W3C = %t", tc.name, got, "abc", tc.w3c)
+		}
+		if got.negotiated != nil {
+			t.Errorf("%s: ConnectToSession() took the capabilities %v as negotiated, want nil", tc.name, got.negotiated)
+		}
+	}
+}
+
+func TestConnectToSessionWithHTTPClient(t *testing.T) {
+	var paths []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{jsonContentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"value": "handle"}`)),
+			Request:    r,
+		}, nil
+	})}
+
+	wd, err := ConnectToSessionWithHTTPClient("http://selenium.invalid/wd/hub", "abc", nil, client)
+	if err != nil {
+		t.Fatalf("ConnectToSessionWithHTTPClient() returned error: %v", err)
+	}
+	if _, err := wd.CurrentWindowHandle(); err != nil {
+		t.Fatalf("wd.CurrentWindowHandle() returned error: %v", err)
+	}
+	want := []string{"GET /wd/hub/session/abc/window", "GET /wd/hub/session/abc/window"}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("The client sent different requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestPauseOnFailure(t *testing.T) {
+	defer func(in io.Reader, out io.Writer, interactive func() bool) {
+		pauseInput, pauseOutput, pauseInteractive = in, out, interactive
+	}(pauseInput, pauseOutput, pauseInteractive)
+
+	for _, tc := range []struct {
+		desc        string
+		failed      bool
+		d           time.Duration
+		input       string
+		interactive bool
+		wantWait    bool
+		wantRead    bool
+	}{
+		{desc: "passed", failed: false, interactive: true},
+		{desc: "failed, waiting for Enter", failed: true, input: "\n", interactive: true, wantRead: true},
+		{desc: "failed, without input", failed: true, interactive: true},
+		{desc: "failed, input is not a terminal", failed: true, input: "\n"},
+		{desc: "failed, waiting for a duration", failed: true, d: 50 * time.Millisecond, input: "\n", wantWait: true},
+	} {
+		wd, done := newTestRemote(t, http.StatusOK, `{"value": null}`)
+		input := strings.NewReader(tc.input)
+		var output bytes.Buffer
+		interactive := tc.interactive
+		pauseInput, pauseOutput = input, &output
+		pauseInteractive = func() bool { return interactive }
+
+		wd.PauseOnFailure(func() bool { return tc.failed }, tc.d)
+		start := time.Now()
+		if err := wd.Quit(); err != nil {
+			t.Errorf("%s: wd.Quit() returned error: %v", tc.desc, err)
+		}
+		done()
+
+		if waited := time.Since(start) >= tc.d && tc.d > 0; waited != tc.wantWait {
+			t.Errorf("%s: wd.Quit() waited for the duration = %t, want %t", tc.desc, waited, tc.wantWait)
+		}
+		if read := tc.input != "" && input.Len() == 0; read != tc.wantRead {
+			t.Errorf("%s: wd.Quit() read the input = %t, want %t", tc.desc, read, tc.wantRead)
+		}
+		if paused := output.Len() > 0; paused != tc.failed {
+			t.Errorf("%s: wd.Quit() printed %q, want a message only on failure", tc.desc, output.String())
+		}
+	}
+}
+
+func TestNewW3CCapabilitiesPromptBehavior(t *testing.T) {
+	caps := Capabilities{"browserName": "firefox"}
+	caps.SetUnhandledPromptBehavior(AcceptAndNotifyPrompt)
+	got := newW3CCapabilities(caps).AlwaysMatch
+	want := Capabilities{"browserName": "firefox", "unhandledPromptBehavior": "accept and notify"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newW3CCapabilities() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func TestProbeGrid(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wd/hub/status" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {
+  "ready": true,
+  "message": "Selenium Grid ready.",
+  "nodes": [{
+    "id": "node-1",
+    "uri": "http://10.0.0.2:5555",
+    "availability": "UP",
+    "version": "4.8.0",
+    "maxSessions": 2,
+    "slots": [
+      {"session": {"sessionId": "abc"}, "stereotype": {"browserName": "chrome", "se:recordVideo": true}},
+      {"session": null, "stereotype": {"browserName": "firefox", "se:recordVideo": false}}
+    ]
+  }]
+}}`)
+	}))
+	defer s.Close()
+
+	got, err := ProbeGrid(s.URL + "/wd/hub")
+	if err != nil {
+		t.Fatalf("ProbeGrid() returned error: %v", err)
+	}
+	want := GridInfo{
+		Nodes: []GridNode{{
+			ID:           "node-1",
+			URI:          "http://10.0.0.2:5555",
+			Availability: "UP",
+			Version:      "4.8.0",
+			MaxSessions:  2,
+			Slots: []Capabilities{
+				{"browserName": "chrome", "se:recordVideo": true},
+				{"browserName": "firefox", "se:recordVideo": false},
+			},
+			ActiveSessions: 1,
+		}},
+	}
+	want.Status.Ready = true
+	want.Status.Message = "Selenium Grid ready."
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ProbeGrid() returned diff (-want/+got):\n%s", diff)
+	}
+	for _, tc := range []struct {
+		name string
+		want bool
+	}{
+		{"se:recordVideo", true},
+		{"browserName", true},
+		{"se:vncEnabled", false},
+	} {
+		if got := got.SupportsCapability(tc.name); got != tc.want {
+			t.Errorf("SupportsCapability(%q) = %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCommandRecorder(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": "Title"}`)
+	defer done()
+	r := NewCommandRecorder()
+	wd.SetCommandRecorder(r)
+
+	if _, err := wd.Title(); err != nil {
+		t.Fatalf("wd.Title() returned error: %v", err)
+	}
+	wantErr := fmt.Errorf("step failed")
+	d, err := wd.Timed("step", func() error {
+		if _, err := wd.Title(); err != nil {
+			t.Errorf("wd.Title() returned error: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return wantErr
+	})
+	if err != wantErr {
+		t.Errorf("wd.Timed() returned error %v, want %v", err, wantErr)
+	}
+	if d < 10*time.Millisecond {
+		t.Errorf("wd.Timed() returned duration %v, want at least 10ms", d)
+	}
+
+	var got []CommandRecord
+	for _, c := range r.Commands() {
+		got = append(got, CommandRecord{Method: c.Method, Path: c.Path, Span: c.Span})
+	}
+	want := []CommandRecord{
+		{Method: "GET", Path: "/session/test-session/title"},
+		{Method: "GET", Path: "/session/test-session/title", Span: "step"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("r.Commands() returned diff (-want/+got):\n%s", diff)
+	}
+	spans := r.Spans()
+	if len(spans) != 1 || spans[0].Name != "step" || spans[0].Duration != d || spans[0].Err != "step failed" {
+		t.Errorf("r.Spans() = %+v, want one span named %q of %v with error %q", spans, "step", d, "step failed")
+	}
+}
+
+func TestCommandRecorderBodies(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": "Title"}`)
+	defer done()
+	for _, bodies := range []bool{false, true} {
+		r := NewCommandRecorder()
+		r.RecordBodies = bodies
+		wd.SetCommandRecorder(r)
+		if _, err := wd.Title(); err != nil {
+			t.Fatalf("wd.Title() returned error: %v", err)
+		}
+		c := r.Commands()[0]
+		if got := c.Response != nil; got != bodies {
+			t.Errorf("With RecordBodies = %t, the response %q was recorded = %t", bodies, c.Response, got)
+		}
+	}
+}
+
+func TestCommandRecorderConcurrentSpans(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": "Title"}`)
+	defer done()
+	r := NewCommandRecorder()
+	wd.SetCommandRecorder(r)
+
+	// "first" ends while "second", which started after it, is still open.
+	started, ended, finish := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		wd.Timed("first", func() error {
+			close(started)
+			<-finish
+			return nil
+		})
+		close(ended)
+	}()
+	<-started
+	wd.Timed("second", func() error {
+		close(finish)
+		<-ended
+		_, err := wd.Title()
+		return err
+	})
+
+	var got []string
+	for _, c := range r.Commands() {
+		got = append(got, c.Span)
+	}
+	if diff := cmp.Diff([]string{"second"}, got); diff != "" {
+		t.Errorf("r.Commands() returned spans diff (-want/+got):\n%s", diff)
+	}
+	got = nil
+	for _, s := range r.Spans() {
+		got = append(got, s.Name)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, got); diff != "" {
+		t.Errorf("r.Spans() returned names diff (-want/+got):\n%s", diff)
+	}
+}
+
+func TestCommandRecorderExportJSON(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusNotFound, `{"value": {"error": "no such element", "message": "not found"}}`)
+	defer done()
+	r := NewCommandRecorder()
+	r.RecordBodies = true
+	wd.SetCommandRecorder(r)
+
+	if _, err := wd.FindElement(ByID, "missing"); err == nil {
+		t.Fatalf("wd.FindElement() returned nil error")
+	}
+	var buf bytes.Buffer
+	if err := r.ExportJSON(&buf); err != nil {
+		t.Fatalf("r.ExportJSON() returned error: %v", err)
+	}
+
+	var got struct {
+		Commands []map[string]interface{}
+		Spans    []Span
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Decoding the output of r.ExportJSON() returned error: %v\n%s", err, buf.Bytes())
+	}
+	if len(got.Commands) != 1 {
+		t.Fatalf("r.ExportJSON() wrote %d commands, want 1:\n%s", len(got.Commands), buf.Bytes())
+	}
+	c := got.Commands[0]
+	for _, key := range []string{"time", "duration", "error"} {
+		if _, ok := c[key]; !ok {
+			t.Errorf("r.ExportJSON() wrote a command without %q:\n%s", key, buf.Bytes())
+		}
+		delete(c, key)
+	}
+	want := map[string]interface{}{
+		"method":  "POST",
+		"path":    "/session/test-session/element",
+		"request": map[string]interface{}{"using": "css selector", "value": "#missing"},
+		"status":  "no such element",
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Errorf("r.ExportJSON() wrote a different command (-want +got):\n%s", diff)
+	}
+	if got.Spans == nil || len(got.Spans) != 0 {
+		t.Errorf("r.ExportJSON() wrote spans %v, want an empty list", got.Spans)
+	}
+}
+
+func TestWindowStateCommands(t *testing.T) {
+	var paths []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {"x": 0, "y": 0, "width": 1280.4, "height": 1024}}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	want := Rect{Width: 1280, Height: 1024}
+	for _, tc := range []struct {
+		name string
+		fn   func() (Rect, error)
+	}{
+		{"Maximize", wd.Maximize},
+		{"Minimize", wd.Minimize},
+		{"Fullscreen", wd.Fullscreen},
+	} {
+		got, err := tc.fn()
+		if err != nil {
+			t.Fatalf("wd.%s() returned error: %v", tc.name, err)
+		}
+		if got != want {
+			t.Errorf("wd.%s() = %+v, want %+v", tc.name, got, want)
+		}
+	}
+	wantPaths := []string{
+		"POST /session/test-session/window/maximize",
+		"POST /session/test-session/window/minimize",
+		"POST /session/test-session/window/fullscreen",
+	}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("The window commands sent different requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestSwitchFrame(t *testing.T) {
+	var got []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Reading the request body returned error: %v", err)
+		}
+		got = append(got, r.URL.Path+" "+string(body))
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": null}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	for _, frame := range []interface{}{
+		nil,
+		"",
+		1,
+		&remoteWE{parent: wd, id: "frame-element"},
+	} {
+		if err := wd.SwitchFrame(frame); err != nil {
+			t.Fatalf("wd.SwitchFrame(%v) returned error: %v", frame, err)
+		}
+	}
+	if err := wd.SwitchFrame(1.5); err == nil {
+		t.Errorf("wd.SwitchFrame(1.5) returned nil error")
+	}
+	want := []string{
+		`/session/test-session/frame {"id":null}`,
+		`/session/test-session/frame {"id":null}`,
+		`/session/test-session/frame {"id":1}`,
+		`/session/test-session/frame {"id":{"` + webElementIdentifier + `":"frame-element"}}`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wd.SwitchFrame() sent different requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	var reply string
+	var args []interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := new(struct{ Args []interface{} })
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		args = body.Args
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprintf(w, `{"value": %s}`, reply)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	reply = `{"value": "v"}`
+	if v, ok, err := wd.LocalStorage().GetItem("k"); err != nil || !ok || v != "v" {
+		t.Errorf(`LocalStorage().GetItem("k") = %q, %t, %v, want "v", true, nil`, v, ok, err)
+	}
+	if diff := cmp.Diff([]interface{}{"localStorage", "get", "k"}, args); diff != "" {
+		t.Errorf(`LocalStorage().GetItem("k") sent different arguments (-want +got):\n%s`, diff)
+	}
+
+	reply = `{"value": null}`
+	if v, ok, err := wd.SessionStorage().GetItem("k"); err != nil || ok || v != "" {
+		t.Errorf(`SessionStorage().GetItem("k") of a missing key = %q, %t, %v, want "", false, nil`, v, ok, err)
+	}
+	if len(args) == 0 || args[0] != "sessionStorage" {
+		t.Errorf(`SessionStorage().GetItem("k") sent arguments %v, want the session storage`, args)
+	}
+
+	reply = `{"value": ["a", "b"]}`
+	if keys, err := wd.LocalStorage().Keys(); err != nil || !cmp.Equal(keys, []string{"a", "b"}) {
+		t.Errorf(`LocalStorage().Keys() = %v, %v, want [a b], nil`, keys, err)
+	}
+
+	reply = `{"value": 2}`
+	if n, err := wd.LocalStorage().Len(); err != nil || n != 2 {
+		t.Errorf(`LocalStorage().Len() = %d, %v, want 2, nil`, n, err)
+	}
+
+	reply = `{}`
+	if err := wd.LocalStorage().SetItem("k", "v"); err != nil {
+		t.Errorf(`LocalStorage().SetItem("k", "v") returned error: %v`, err)
+	}
+	if diff := cmp.Diff([]interface{}{"localStorage", "set", "k", "v"}, args); diff != "" {
+		t.Errorf(`LocalStorage().SetItem("k", "v") sent different arguments (-want +got):\n%s`, diff)
+	}
+
+	reply = `{"exception": "SecurityError: Access is denied for this document.", "unavailable": true}`
+	err := wd.LocalStorage().Clear()
+	if err == nil || !strings.Contains(err.Error(), "SecurityError") || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("LocalStorage().Clear() on a page without storage returned error %v, want a SecurityError", err)
+	}
+
+	reply = `{"exception": "QuotaExceededError: The quota has been exceeded."}`
+	err = wd.LocalStorage().SetItem("k", "v")
+	if err == nil || !strings.Contains(err.Error(), "QuotaExceededError") || strings.Contains(err.Error(), "not available") {
+		t.Errorf("LocalStorage().SetItem() over the quota returned error %v, want the QuotaExceededError as is", err)
+	}
+}
+
+func TestExecuteScriptRawValue(t *testing.T) {
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": {"id": 9007199254740993, "tags": ["a"]}}`)
+	defer done()
+
+	for _, tc := range []struct {
+		name string
+		fn   func(string, []interface{}) (json.RawMessage, error)
+	}{
+		{"ExecuteScriptRawValue", wd.ExecuteScriptRawValue},
+		{"ExecuteScriptAsyncRawValue", wd.ExecuteScriptAsyncRawValue},
+	} {
+		raw, err := tc.fn("return window.thing;", nil)
+		if err != nil {
+			t.Fatalf("wd.%s() returned error: %v", tc.name, err)
+		}
+		var got struct {
+			ID   int64
+			Tags []string
+		}
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatalf("Decoding the result of wd.%s(), %s, returned error: %v", tc.name, raw, err)
+		}
+		if got.ID != 9007199254740993 || len(got.Tags) != 1 {
+			t.Errorf("wd.%s() = %s, decoded as %+v", tc.name, raw, got)
+		}
+	}
+}
+
+// roundTripFunc is an http.RoundTripper implemented by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewRemoteWithHTTPClient(t *testing.T) {
+	// The server only receives the commands sent with the default client.
+	var serverPaths []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverPaths = append(serverPaths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": "Title"}`)
+	}))
+	defer s.Close()
+
+	var clientPaths []string
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		clientPaths = append(clientPaths, r.Method+" "+r.URL.Path)
+		body := `{"value": {"sessionId": "test-session", "capabilities": {"browserName": "chrome"}}}`
+		if r.URL.Path != "/wd/hub/session" {
+			body = `{"value": "Title"}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{jsonContentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})}
+
+	wd, err := NewRemoteWithHTTPClient(Capabilities{"browserName": "chrome"}, s.URL+"/wd/hub", client)
+	if err != nil {
+		t.Fatalf("NewRemoteWithHTTPClient() returned error: %v", err)
+	}
+	if _, err := wd.Title(); err != nil {
+		t.Fatalf("wd.Title() returned error: %v", err)
+	}
+	want := []string{"POST /wd/hub/session", "GET /wd/hub/session/test-session/title"}
+	if diff := cmp.Diff(want, clientPaths); diff != "" {
+		t.Errorf("The client sent different requests (-want +got):\n%s", diff)
+	}
+	if len(serverPaths) != 0 {
+		t.Errorf("The server received %v, want no requests while the client is set", serverPaths)
+	}
+
+	wd.SetHTTPClient(nil)
+	clientPaths = nil
+	if _, err := wd.Title(); err != nil {
+		t.Fatalf("wd.Title() with the default client returned error: %v", err)
+	}
+	if len(clientPaths) != 0 {
+		t.Errorf("The client sent %v after it was unset, want no requests", clientPaths)
+	}
+	want = []string{"GET /wd/hub/session/test-session/title"}
+	if diff := cmp.Diff(want, serverPaths); diff != "" {
+		t.Errorf("The server received different requests with the default client (-want +got):\n%s", diff)
+	}
+}
+
+func TestNegotiatedCapabilities(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path != "/wd/hub/session" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		body := `{"value": {"sessionId": "test-session", "capabilities": {"browserName": "firefox", "browserVersion": "78.0.2", "acceptInsecureCerts": false}}}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{jsonContentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})}
+
+	wd, err := NewRemoteWithHTTPClient(Capabilities{"browserName": "firefox"}, "http://selenium.invalid/wd/hub", client)
+	if err != nil {
+		t.Fatalf("NewRemoteWithHTTPClient() returned error: %v", err)
+	}
+	if got, want := wd.SessionID(), "test-session"; got != want {
+		t.Errorf("wd.SessionID() = %q, want %q", got, want)
+	}
+	caps, err := wd.Capabilities()
+	if err != nil {
+		t.Fatalf("wd.Capabilities() returned error: %v", err)
+	}
+	want := Capabilities{"browserName": "firefox", "browserVersion": "78.0.2", "acceptInsecureCerts": false}
+	if diff := cmp.Diff(want, caps); diff != "" {
+		t.Errorf("wd.Capabilities() returned diff (-want/+got):\n%s", diff)
+	}
+
+	// The returned map is a copy.
+	caps["browserName"] = "chrome"
+	if caps, _ := wd.Capabilities(); caps["browserName"] != "firefox" {
+		t.Errorf("Modifying the result of wd.Capabilities() changed the session's capabilities")
+	}
+}
+
+func TestActionsPerform(t *testing.T) {
+	var got map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": null}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	err := wd.NewActions().
+		KeyDown(ShiftKey).
+		PointerMove(ViewportOrigin, 10, 20, 100*time.Millisecond).
+		PointerDown(LeftButton).
+		Pause(time.Second).
+		PointerUp(LeftButton).
+		KeyUp(ShiftKey).
+		Perform()
+	if err != nil {
+		t.Fatalf("Perform() returned error: %v", err)
+	}
+
+	pause := func(ms float64) map[string]interface{} {
+		return map[string]interface{}{"type": "pause", "duration": ms}
+	}
+	want := map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{
+				"type": "key",
+				"id":   "default keyboard",
+				"actions": []interface{}{
+					map[string]interface{}{"type": "keyDown", "value": ShiftKey},
+					pause(0),
+					pause(0),
+					pause(1000),
+					pause(0),
+					map[string]interface{}{"type": "keyUp", "value": ShiftKey},
+				},
+			},
+			map[string]interface{}{
+				"type":       "pointer",
+				"id":         "default mouse",
+				"parameters": map[string]interface{}{"pointerType": "mouse"},
+				"actions": []interface{}{
+					pause(0),
+					map[string]interface{}{"type": "pointerMove", "duration": float64(100), "origin": "viewport", "x": float64(10), "y": float64(20)},
+					map[string]interface{}{"type": "pointerDown", "button": float64(LeftButton)},
+					pause(1000),
+					map[string]interface{}{"type": "pointerUp", "button": float64(LeftButton)},
+					pause(0),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Perform() sent a request with diff (-want/+got):\n%s", diff)
+	}
+
+	if err := wd.NewActions().PointerMove(3, 0, 0, 0).Perform(); err == nil {
+		t.Errorf("Perform() with an invalid origin returned no error")
+	}
+}
+
+func TestGetElementRetriesStale(t *testing.T) {
+	var tagNames int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		if !strings.HasSuffix(r.URL.Path, "/name") {
+			fmt.Fprint(w, `{"value": {"element-6066-11e4-a52e-4f735466cecf": "abc"}}`)
+			return
+		}
+		// The first reference goes stale right after it is found.
+		if tagNames++; tagNames == 1 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"value": {"error": "stale element reference", "message": "stale", "stacktrace": ""}}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": "div"}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	if _, err := wd.GetElement(ByID, "id", time.Second); err != nil {
+		t.Fatalf("wd.GetElement() returned error: %v", err)
+	}
+	if tagNames != 2 {
+		t.Errorf("wd.GetElement() checked %d references, want 2", tagNames)
+	}
+}
+
+func TestFindRelativeElementsStaleAnchor(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		switch r.URL.Path {
+		case "/session/test-session/elements":
+			fmt.Fprint(w, `{"value": []}`)
+		case "/session/test-session/execute/sync":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"value": {"error": "stale element reference", "message": "stale", "stacktrace": ""}}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	anchor := wd.newElement("stale-anchor")
+	elems, err := wd.FindRelativeElements(RelativeBy(ByCSSSelector, "input").Below(anchor))
+	if err == nil || !strings.Contains(err.Error(), "a reference element is stale") {
+		t.Errorf("wd.FindRelativeElements() without candidates and with a stale anchor returned (%v, %v), want a stale reference error", elems, err)
+	}
+}
+
+func TestDefaultStrategy(t *testing.T) {
+	var using string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := new(struct{ Using string })
+		if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		using = params.Using
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {"element-6066-11e4-a52e-4f735466cecf": "abc"}}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	for _, tc := range []struct {
+		strategy string
+		want     string
+	}{
+		{"", ByCSSSelector},
+		{ByXPATH, ByXPATH},
+	} {
+		wd.SetDefaultStrategy(tc.strategy)
+		if _, err := wd.Find("selector"); err != nil {
+			t.Fatalf("wd.Find() returned error: %v", err)
+		}
+		if using != tc.want {
+			t.Errorf("With the default strategy set to %q, wd.Find() used %q, want %q", tc.strategy, using, tc.want)
+		}
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	cm := func(v float64) *float64 { return &v }
+	for _, tc := range []struct {
+		opts PrintOptions
+		want string
+	}{
+		{PrintOptions{}, `{}`},
+		{
+			PrintOptions{
+				Orientation: PrintLandscape,
+				Scale:       0.5,
+				Background:  true,
+				Page:        &PrintPage{Width: cm(21), Height: cm(29.7)},
+				Margin:      &PrintMargin{Top: cm(2)},
+				PageRanges:  []string{"1-2", "4"},
+			},
+			`{"orientation":"landscape","scale":0.5,"background":true,"page":{"width":21,"height":29.7},"margin":{"top":2},"pageRanges":["1-2","4"]}`,
+		},
+		{
+			PrintOptions{Margin: &PrintMargin{Top: cm(0), Left: cm(1.5)}},
+			`{"margin":{"top":0,"left":1.5}}`,
+		},
+	} {
+		got, err := json.Marshal(tc.opts)
+		if err != nil {
+			t.Fatalf("json.Marshal(%+v) returned error: %v", tc.opts, err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("json.Marshal(%+v) = %s, want %s", tc.opts, got, tc.want)
+		}
+	}
+}
+
+func TestPrintHeadlessHint(t *testing.T) {
+	for _, tc := range []struct {
+		code     string
+		wantHint bool
+	}{
+		{"unsupported operation", true},
+		{"unknown error", true},
+		{"no such window", false},
+	} {
+		wd, done := newTestRemote(t, http.StatusInternalServerError, `{"value": {"error": "`+tc.code+`", "message": "m", "stacktrace": ""}}`)
+		_, err := wd.Print(PrintOptions{})
+		done()
+		if err == nil {
+			t.Fatalf("Print() with a %q error returned nil error", tc.code)
+		}
+		if got := strings.Contains(err.Error(), "headless"); got != tc.wantHint {
+			t.Errorf("Print() with a %q error returned %q; hint included = %t, want %t", tc.code, err, got, tc.wantHint)
+		}
+		if !tc.wantHint && !errorIs(err, tc.code) {
+			t.Errorf("Print() with a %q error returned %#v, want the *Error unchanged", tc.code, err)
+		}
+	}
+}
+
+func TestFullPageScreenshotCDPKeepsViewport(t *testing.T) {
+	var methods []string
+	var clip map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Cmd    string
+			Params struct{ Clip map[string]interface{} }
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		methods = append(methods, req.Cmd)
+		w.Header().Set("Content-Type", jsonContentType)
+		switch req.Cmd {
+		case "Page.getLayoutMetrics":
+			fmt.Fprint(w, `{"value": {"contentSize": {"width": 1600, "height": 6000}, "cssContentSize": {"width": 800, "height": 2999.5}}}`)
+		case "Page.captureScreenshot":
+			clip = req.Params.Clip
+			fmt.Fprint(w, `{"value": {"data": "cG5n"}}`)
+		default:
+			fmt.Fprint(w, `{"value": {}}`)
+		}
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "chrome"}
+
+	data, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("wd.FullPageScreenshot() returned error: %v", err)
+	}
+	if string(data) != "png" {
+		t.Errorf("wd.FullPageScreenshot() = %q, want %q", data, "png")
+	}
+	if diff := cmp.Diff([]string{"Page.getLayoutMetrics", "Page.captureScreenshot"}, methods); diff != "" {
+		t.Errorf("wd.FullPageScreenshot() sent unexpected DevTools commands (-want +got):\n%s", diff)
+	}
+	want := map[string]interface{}{"x": 0.0, "y": 0.0, "width": 800.0, "height": 3000.0, "scale": 1.0}
+	if diff := cmp.Diff(want, clip); diff != "" {
+		t.Errorf("Page.captureScreenshot clip differs (-want +got):\n%s", diff)
+	}
+}
+
+func TestExecuteCDPCmd(t *testing.T) {
+	var got map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/session/test-session/goog/cdp/execute"; r.URL.Path != want {
+			t.Errorf("Request path = %q, want %q", r.URL.Path, want)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {"product": "Chrome/99.0"}}`)
+	}))
+	defer s.Close()
+	var cwd ChromeWebDriver = &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "chrome"}
+
+	params := map[string]interface{}{"latitude": 1.5}
+	result, err := cwd.ExecuteCDPCmd("Emulation.setGeolocationOverride", params)
+	if err != nil {
+		t.Fatalf("cwd.ExecuteCDPCmd() returned error: %v", err)
+	}
+	want := map[string]interface{}{
+		"cmd":    "Emulation.setGeolocationOverride",
+		"params": params,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("cwd.ExecuteCDPCmd() sent a different request body (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"product": "Chrome/99.0"}, result); diff != "" {
+		t.Errorf("cwd.ExecuteCDPCmd() returned a different result (-want +got):\n%s", diff)
+	}
+
+	cwd = &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "firefox"}
+	if _, err := cwd.ExecuteCDPCmd("Browser.getVersion", nil); err == nil {
+		t.Errorf("cwd.ExecuteCDPCmd() in Firefox returned nil error")
+	} else if _, ok := err.(*UnsupportedError); !ok {
+		t.Errorf("cwd.ExecuteCDPCmd() in Firefox returned error %v of type %T, want *UnsupportedError", err, err)
+	}
+}
+
+func TestSetGeolocation(t *testing.T) {
+	var got []map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Decoding the request body returned error: %v", err)
+		}
+		got = append(got, body)
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {}}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "chrome"}
+
+	if err := wd.SetGeolocation(48.8584, 2.2945, 10); err != nil {
+		t.Fatalf("wd.SetGeolocation() returned error: %v", err)
+	}
+	if err := wd.ClearGeolocation(); err != nil {
+		t.Fatalf("wd.ClearGeolocation() returned error: %v", err)
+	}
+	want := []map[string]interface{}{
+		{
+			"cmd":    "Emulation.setGeolocationOverride",
+			"params": map[string]interface{}{"latitude": 48.8584, "longitude": 2.2945, "accuracy": float64(10)},
+		},
+		{
+			"cmd":    "Emulation.clearGeolocationOverride",
+			"params": map[string]interface{}{},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("The geolocation methods sent different request bodies (-want +got):\n%s", diff)
+	}
+
+	wd.browser = "firefox"
+	if err := wd.SetGeolocation(0, 0, 0); err == nil {
+		t.Errorf("wd.SetGeolocation() in Firefox returned nil error")
+	} else if _, ok := err.(*UnsupportedError); !ok {
+		t.Errorf("wd.SetGeolocation() in Firefox returned error %v of type %T, want *UnsupportedError", err, err)
+	}
+}
+
+func TestGetWithContext(t *testing.T) {
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": null}`)
+	}))
+	defer s.Close()
+	defer close(release)
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := wd.GetWithContext(ctx, "https://example.com/")
+	if ce, ok := err.(*ContextError); !ok {
+		t.Fatalf("wd.GetWithContext() returned error %v of type %T, want *ContextError", err, err)
+	} else if ce.Err != context.DeadlineExceeded {
+		t.Errorf("wd.GetWithContext() returned error %v, want one wrapping context.DeadlineExceeded", err)
+	}
+
+	if err, ok := wd.GetWithContext(ctx, "https://example.com/").(*ContextError); !ok || err.Err != context.DeadlineExceeded {
+		t.Errorf("wd.GetWithContext() with an expired context returned error %v, want one wrapping context.DeadlineExceeded", err)
+	}
+}
+
+func TestWithContextIsPerCall(t *testing.T) {
+	asyncStarted := make(chan struct{})
+	titleStarted := make(chan struct{})
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/execute/async"):
+			close(asyncStarted)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		case strings.HasSuffix(r.URL.Path, "/title"):
+			close(titleStarted)
+			<-release
+		}
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": "Title"}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	// Cancelling the context of a command must not abort a command that is
+	// sent concurrently without it.
+	ctx, cancel := context.WithCancel(context.Background())
+	asyncErr := make(chan error)
+	go func() {
+		_, err := wd.ExecuteScriptAsyncWithContext(ctx, "arguments[0]()", nil)
+		asyncErr <- err
+	}()
+	<-asyncStarted
+	titleErr := make(chan error)
+	go func() {
+		_, err := wd.Title()
+		titleErr <- err
+	}()
+	<-titleStarted
+	cancel()
+	if err := <-asyncErr; err == nil {
+		t.Errorf("wd.ExecuteScriptAsyncWithContext() with a cancelled context returned nil error")
+	} else if _, ok := err.(*ContextError); !ok {
+		t.Errorf("wd.ExecuteScriptAsyncWithContext() with a cancelled context returned error %v of type %T, want *ContextError", err, err)
+	}
+	close(release)
+	if err := <-titleErr; err != nil {
+		t.Errorf("wd.Title() sent concurrently with a cancelled command returned error: %v", err)
+	}
+}
+
+func TestConsoleMessages(t *testing.T) {
+	events := []string{
+		`{"method": "Runtime.consoleAPICalled", "params": {"type": "warning", "timestamp": 1500000000000, "args": [{"type": "string", "value": "n ="}, {"type": "number", "value": 1}, {"type": "object", "description": "Array(2)"}, {"type": "undefined"}], "stackTrace": {"callFrames": [{"functionName": "f", "url": "http://x/a.js", "lineNumber": 0, "columnNumber": 4}]}}}`,
+		`{"method": "Network.requestWillBeSent", "params": {}}`,
+		`{"method": "Runtime.exceptionThrown", "params": {"timestamp": 1500000000001, "exceptionDetails": {"text": "Uncaught", "url": "http://x/b.js", "lineNumber": 2, "columnNumber": 0, "exception": {"type": "object", "description": "Error: kaboom\n    at http://x/b.js:3:1"}}}}`,
+	}
+	var entries []string
+	for _, e := range events {
+		entry, err := json.Marshal(map[string]interface{}{"timestamp": 1500000000000, "level": "INFO", "message": e})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		entries = append(entries, string(entry))
+	}
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": [`+strings.Join(entries, ",")+`]}`)
+	defer done()
+	wd.browser = "chrome"
+
+	got, err := wd.ConsoleMessages()
+	if err != nil {
+		t.Fatalf("wd.ConsoleMessages() returned error: %v", err)
+	}
+	want := []ConsoleMessage{
+		{
+			Level:      "warn",
+			Text:       "n = 1 Array(2) undefined",
+			Timestamp:  time.Unix(1500000000, 0),
+			StackTrace: []StackFrame{{Function: "f", URL: "http://x/a.js", Line: 1, Column: 5}},
+		},
+		{
+			Level:      "error",
+			Text:       "Uncaught Error: kaboom",
+			Timestamp:  time.Unix(1500000000, int64(time.Millisecond)),
+			StackTrace: []StackFrame{{URL: "http://x/b.js", Line: 3, Column: 1}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wd.ConsoleMessages() returned diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestFilterConsoleMessages(t *testing.T) {
+	msgs := []ConsoleMessage{
+		{Level: "debug", Text: "d"},
+		{Level: "log", Text: "l"},
+		{Level: "info", Text: "i"},
+		{Level: "warn", Text: "w"},
+		{Level: "error", Text: "e"},
+	}
+	for _, tc := range []struct {
+		minSeverity string
+		want        []string
+	}{
+		{"error", []string{"e"}},
+		{"warn", []string{"w", "e"}},
+		{"info", []string{"l", "i", "w", "e"}},
+		{"debug", []string{"d", "l", "i", "w", "e"}},
+	} {
+		got, err := filterConsoleMessages(msgs, tc.minSeverity)
+		if err != nil {
+			t.Fatalf("filterConsoleMessages(%q) returned error: %v", tc.minSeverity, err)
+		}
+		var texts []string
+		for _, m := range got {
+			texts = append(texts, m.Text)
+		}
+		if diff := cmp.Diff(tc.want, texts); diff != "" {
+			t.Errorf("filterConsoleMessages(%q) returned diff (-want +got):\n%s", tc.minSeverity, diff)
+		}
+	}
+	// The levels of the other console methods rank with the closest ones.
+	got, err := filterConsoleMessages([]ConsoleMessage{
+		{Level: "trace", Text: "t"},
+		{Level: "assert", Text: "a"},
+		{Level: "unknown", Text: "u"},
+	}, "error")
+	if err != nil || len(got) != 1 || got[0].Text != "a" {
+		t.Errorf("filterConsoleMessages(%q) of trace, assert and unknown messages = (%+v, %v), want only the assertion", "error", got, err)
+	}
+	if got, _ := filterConsoleMessages([]ConsoleMessage{{Level: "trace"}, {Level: "unknown"}}, "log"); len(got) != 2 {
+		t.Errorf("filterConsoleMessages(%q) of trace and unknown messages = %+v, want both", "log", got)
+	}
+
+	if _, err := filterConsoleMessages(msgs, "severe"); err == nil {
+		t.Errorf("filterConsoleMessages(%q) returned nil error", "severe")
+	}
+}
+
+func TestRecordEventsKeepsLatestUsedEvents(t *testing.T) {
+	defer func(max int) { maxRecordedEvents = max }(maxRecordedEvents)
+	maxRecordedEvents = 2
+
+	var entries []string
+	for _, method := range []string{"Network.requestWillBeSent", "Network.dataReceived", "Network.responseReceived", "Page.frameNavigated", "Network.loadingFinished"} {
+		message, err := json.Marshal(map[string]interface{}{
+			"message": map[string]interface{}{"method": method, "params": map[string]interface{}{}},
+		})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		entry, err := json.Marshal(map[string]interface{}{"timestamp": 0, "level": "INFO", "message": string(message)})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		entries = append(entries, string(entry))
+	}
+	wd, done := newTestRemote(t, http.StatusOK, `{"value": [`+strings.Join(entries, ",")+`]}`)
+	defer done()
+
+	events, err := wd.recordEvents()
+	if err != nil {
+		t.Fatalf("wd.recordEvents() returned error: %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Method)
+	}
+	if diff := cmp.Diff([]string{"Network.responseReceived", "Network.loadingFinished"}, got); diff != "" {
+		t.Errorf("wd.recordEvents() returned different events (-want +got):\n%s", diff)
+	}
+}
+
+func TestWaitForRequestReturnsEachRequestOnce(t *testing.T) {
+	var entries []string
+	for _, e := range []struct{ method, params string }{
+		{"Network.requestWillBeSent", `{"requestId": "1", "request": {"url": "https://example.com/save", "method": "POST"}}`},
+		{"Network.responseReceived", `{"requestId": "1", "response": {"status": 200}}`},
+		{"Network.loadingFinished", `{"requestId": "1"}`},
+		{"Network.requestWillBeSent", `{"requestId": "2", "request": {"url": "https://example.com/save", "method": "POST"}}`},
+		{"Network.responseReceived", `{"requestId": "2", "response": {"status": 500}}`},
+		{"Network.loadingFinished", `{"requestId": "2"}`},
+	} {
+		message, err := json.Marshal(map[string]interface{}{
+			"message": map[string]interface{}{"method": e.method, "params": json.RawMessage(e.params)},
+		})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		entry, err := json.Marshal(map[string]interface{}{"timestamp": 0, "level": "INFO", "message": string(message)})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		entries = append(entries, string(entry))
+	}
+	// The log is only read once, as the server returns the same entries for
+	// every command.
+	var logRead bool
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		if strings.HasSuffix(r.URL.Path, "/log") && !logRead {
+			logRead = true
+			fmt.Fprint(w, `{"value": [`+strings.Join(entries, ",")+`]}`)
+			return
+		}
+		fmt.Fprint(w, `{"value": []}`)
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true, browser: "chrome"}
+
+	for _, want := range []int{200, 500} {
+		r, err := wd.WaitForRequest("/save$", time.Second)
+		if err != nil {
+			t.Fatalf("wd.WaitForRequest() returned error: %v", err)
+		}
+		if r.Status != want {
+			t.Errorf("wd.WaitForRequest() returned a request with status %d, want %d", r.Status, want)
+		}
+	}
+	if r, err := wd.WaitForRequest("/save$", 100*time.Millisecond); err == nil {
+		t.Errorf("wd.WaitForRequest() without a new request returned %+v, want a timeout", r)
+	}
+}
+
+func TestSecurityIssues(t *testing.T) {
+	var events []devToolsEvent
+	for _, e := range []struct{ method, params string }{
+		{"Network.requestWillBeSent", `{"requestId": "1", "request": {"url": "https://example.com/", "method": "GET", "mixedContentType": "none"}}`},
+		{"Network.requestWillBeSent", `{"requestId": "2", "request": {"url": "http://example.com/image.png", "method": "GET", "mixedContentType": "optionally-blockable"}}`},
+		{"Network.requestWillBeSent", `{"requestId": "3", "request": {"url": "http://example.com/script.js", "method": "GET", "mixedContentType": "blockable"}}`},
+		{"Network.requestWillBeSent", `{"requestId": "4", "request": {"url": "https://other.com/script.js", "method": "GET"}}`},
+		{"Network.requestWillBeSent", `{"requestId": "5", "request": {"url": "https://example.com/ad.js", "method": "GET"}}`},
+		{"Network.loadingFinished", `{"requestId": "2"}`},
+		{"Network.loadingFailed", `{"requestId": "3", "errorText": "net::ERR_BLOCKED_BY_CLIENT", "blockedReason": "mixed-content"}`},
+		{"Network.loadingFailed", `{"requestId": "4", "errorText": "net::ERR_BLOCKED_BY_CLIENT", "blockedReason": "csp"}`},
+		{"Network.loadingFailed", `{"requestId": "5", "errorText": "net::ERR_BLOCKED_BY_CLIENT", "blockedReason": "subresource-filter"}`},
+	} {
+		events = append(events, devToolsEvent{Method: e.method, Params: json.RawMessage(e.params)})
+	}
+
+	got, err := securityIssues(events)
+	if err != nil {
+		t.Fatalf("securityIssues() returned error: %v", err)
+	}
+	want := []string{
+		"http://example.com/image.png: optionally-blockable mixed content",
+		"http://example.com/script.js: blockable mixed content, blocked (mixed-content)",
+		"https://other.com/script.js: blocked (csp)",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("securityIssues() returned a different result (-want +got):\n%s", diff)
+	}
+}
+
+func TestActionLog(t *testing.T) {
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", jsonContentType)
+		switch r.URL.Path {
+		case "/session/test-session/element":
+			fmt.Fprint(w, `{"value": {"element-6066-11e4-a52e-4f735466cecf": "form"}}`)
+		case "/session/test-session/elements":
+			fmt.Fprint(w, `{"value": [{"element-6066-11e4-a52e-4f735466cecf": "form"}]}`)
+		case "/session/test-session/element/form/elements":
+			fmt.Fprint(w, `{"value": [{"element-6066-11e4-a52e-4f735466cecf": "a"}, {"element-6066-11e4-a52e-4f735466cecf": "b"}]}`)
+		default:
+			fmt.Fprint(w, `{"value": null}`)
+		}
+	}))
+	defer s.Close()
+	wd := &remoteWD{id: "test-session", urlPrefix: s.URL, w3cCompatible: true}
+
+	var log ActionLog
+	wd.SetActionLog(&log)
+	if err := wd.Get("http://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	form, err := wd.FindElement(ByCSSSelector, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+	inputs, err := form.FindElements(ByTagName, "input")
+	if err != nil {
+		t.Fatalf("form.FindElements() returned error: %v", err)
+	}
+	if err := inputs[1].Click(); err != nil {
+		t.Fatalf("inputs[1].Click() returned error: %v", err)
+	}
+	if err := inputs[1].SendKeys("text"); err != nil {
+		t.Fatalf("inputs[1].SendKeys() returned error: %v", err)
+	}
+	wd.SetActionLog(nil)
+
+	locator := []ElementLocator{
+		{By: ByCSSSelector, Value: "form"},
+		{By: ByTagName, Value: "input", Index: 1},
+	}
+	want := ActionLog{
+		{Type: ActionNavigate, URL: "http://example.com/"},
+		{Type: ActionClick, Element: locator},
+		{Type: ActionSendKeys, Element: locator, Keys: "text"},
+	}
+	if diff := cmp.Diff(want, log); diff != "" {
+		t.Fatalf("The action log has a diff (-want/+got):\n%s", diff)
+	}
+
+	requests = nil
+	if err := wd.Replay(log); err != nil {
+		t.Fatalf("wd.Replay() returned error: %v", err)
+	}
+	wantRequests := []string{
+		"POST /session/test-session/url",
+		"POST /session/test-session/elements",
+		"POST /session/test-session/element/form/elements",
+		"POST /session/test-session/element/b/click",
+		"POST /session/test-session/elements",
+		"POST /session/test-session/element/form/elements",
+		"POST /session/test-session/element/b/value",
+	}
+	if diff := cmp.Diff(wantRequests, requests); diff != "" {
+		t.Errorf("wd.Replay() sent different requests (-want/+got):\n%s", diff)
+	}
+
+	// An element that was not found by a locator cannot be found again.
+	if err := wd.Replay(ActionLog{{Type: ActionClick}}); err == nil {
+		t.Errorf("wd.Replay() of a click on an unknown element returned nil error")
+	}
+}
+
+func TestNewRemoteWithBasePath(t *testing.T) {
+	var got string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Method + " " + r.URL.Path
+		w.Header().Set("Content-Type", jsonContentType)
+		fmt.Fprint(w, `{"value": {"sessionId": "test-session", "capabilities": {"browserName": "chrome"}}}`)
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		serverURL, basePath string
+		want                string
+	}{
+		{s.URL, GridBasePath, "POST /wd/hub/session"},
+		{s.URL + "/", "wd/hub/", "POST /wd/hub/session"},
+		{s.URL, DriverBasePath, "POST /session"},
+		{s.URL + "/", "/", "POST /session"},
+	} {
+		got = ""
+		if _, err := NewRemoteWithBasePath(Capabilities{"browserName": "chrome"}, tc.serverURL, tc.basePath); err != nil {
+			t.Errorf("NewRemoteWithBasePath(_, %q, %q) returned error: %v", tc.serverURL, tc.basePath, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("NewRemoteWithBasePath(_, %q, %q) sent %q, want %q", tc.serverURL, tc.basePath, got, tc.want)
+		}
+	}
+
+	// A trailing slash in the URL prefix does not double the slash before
+	// the command path.
+	got = ""
+	if _, err := NewRemote(Capabilities{"browserName": "chrome"}, s.URL+DriverBasePath+"/"); err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	if want := "POST /session"; got != want {
+		t.Errorf("NewRemote(_, %q) sent %q, want %q", s.URL+"/", got, want)
+	}
+}
+
+func TestNewSessionWrongBasePath(t *testing.T) {
+	// ChromeDriver replies with an "unknown command" error to the requests
+	// that are not under its base path.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonContentType)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"value": {"error": "unknown command", "message": "unknown command: unknown command: %s", "stacktrace": ""}}`, r.URL.Path)
+	}))
+	defer s.Close()
+
+	for _, tc := range []struct {
+		urlPrefix string
+		want      string
+	}{
+		{s.URL + GridBasePath, `the base path "/wd/hub" of the URL prefix may be wrong`},
+		{s.URL, "the URL prefix has no base path"},
+	} {
+		_, err := NewRemote(Capabilities{"browserName": "chrome"}, tc.urlPrefix)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("NewRemote(_, %q) returned error %v, want an error that contains %q", tc.urlPrefix, err, tc.want)
+		}
+	}
+
+	wd, done := newTestRemote(t, http.StatusInternalServerError, `{"value": {"error": "session not created", "message": "no such browser", "stacktrace": ""}}`)
+	defer done()
+	if _, err := wd.NewSession(); err == nil || strings.Contains(err.Error(), "base path") {
+		t.Errorf("wd.NewSession() returned error %v, want an error without a base path hint", err)
+	}
+}
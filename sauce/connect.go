@@ -1,13 +1,16 @@
 package sauce
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -27,6 +30,10 @@ type Connect struct {
 	// SeleniumPort is the port number that the Proxy binary should listen on for
 	// new Selenium WebDriver connections.
 	SeleniumPort int
+	// TunnelIdentifier names the tunnel, allowing several tunnels to run under
+	// the same account. Sessions select the tunnel via the "tunnelIdentifier"
+	// capability.
+	TunnelIdentifier string
 	// Verbose and ExtraVerbose control the verbosity level of logging from the
 	// Proxy binary.
 	Verbose, ExtraVerbose bool
@@ -40,7 +47,8 @@ type Connect struct {
 	// proxy process when this parent process exits.
 	QuitProcessUponExit bool
 
-	cmd *exec.Cmd
+	cmd    *exec.Cmd
+	exited chan struct{}
 }
 
 // Start starts the Sauce Connect Proxy.
@@ -55,15 +63,23 @@ func (c *Connect) Start() error {
 	if c.SeleniumPort > 0 {
 		c.cmd.Args = append(c.cmd.Args, "--se-port", strconv.Itoa(c.SeleniumPort))
 	}
+	if c.TunnelIdentifier != "" {
+		c.cmd.Args = append(c.cmd.Args, "--tunnel-identifier", c.TunnelIdentifier)
+	}
 	if c.ExtraVerbose {
 		c.cmd.Args = append(c.cmd.Args, "-vv")
 	} else if c.Verbose {
 		c.cmd.Args = append(c.cmd.Args, "-v")
 	}
+	output := ioutil.Discard
 	if c.ExtraVerbose || c.Verbose {
-		c.cmd.Stdout = os.Stdout
+		output = os.Stdout
 		c.cmd.Stderr = os.Stderr
 	}
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
 	if c.LogFile != "" {
 		c.cmd.Args = append(c.cmd.Args, "--logfile", c.LogFile)
 	}
@@ -107,21 +123,56 @@ func (c *Connect) Start() error {
 	if err := c.cmd.Start(); err != nil {
 		return err
 	}
+	c.exited = make(chan struct{})
+	ready := make(chan struct{})
+	go func() {
+		scanReady(stdout, output, ready)
+		c.cmd.Wait() // ignore error.
+		close(c.exited)
+	}()
 
-	// Wait for the Proxy to accept connections.
-	var started bool
-	for i := 0; i < 60; i++ {
-		time.Sleep(time.Second)
-		if _, err := os.Stat(readyPath); err == nil {
-			started = true
-			break
+	// Wait for the Proxy to accept connections, which is signalled either by
+	// the ready file or by the message the Proxy prints once the tunnel is up.
+	timeout := time.After(60 * time.Second)
+	for {
+		select {
+		case <-ready:
+			return nil
+		case <-c.exited:
+			return fmt.Errorf("proxy process exited before becoming ready")
+		case <-timeout:
+			c.Stop() // ignore error.
+			return fmt.Errorf("proxy process did not become ready before the timeout")
+		case <-time.After(time.Second):
+			if _, err := os.Stat(readyPath); err == nil {
+				return nil
+			}
 		}
 	}
-	if !started {
-		c.Stop() // ignore error.
-		return fmt.Errorf("proxy process did not become ready before the timeout")
+}
+
+// readyMessage is printed by the Proxy once the tunnel has been established.
+const readyMessage = "you may start your tests"
+
+// scanReady copies the Proxy's output from r to w, closing ready once the
+// message indicating that the tunnel is up has been read. If scanning stops
+// early, e.g. on a line longer than the scanner's buffer, the remainder is
+// still copied so that the Proxy never blocks writing to a full pipe.
+func scanReady(r io.Reader, w io.Writer, ready chan<- struct{}) {
+	var isReady bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Fprintln(w, line)
+		if !isReady && strings.Contains(line, readyMessage) {
+			isReady = true
+			close(ready)
+		}
 	}
-	return nil
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(w, "sauce: error reading the proxy output: %v\n", err)
+	}
+	io.Copy(w, r) // ignore error.
 }
 
 // Addr returns the URL of the WebDriver endpoint to use for driving the
@@ -130,7 +181,14 @@ func (c *Connect) Addr() string {
 	return fmt.Sprintf("http://%s:%s@localhost:%d/wd/hub", c.UserName, c.AccessKey, c.SeleniumPort)
 }
 
-// Stop terminates the Proxy process.
+// Stop terminates the Proxy process and waits for it to exit.
 func (c *Connect) Stop() error {
-	return c.cmd.Process.Kill()
+	if c.cmd == nil || c.cmd.Process == nil {
+		return fmt.Errorf("proxy process was not started")
+	}
+	if err := c.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-c.exited
+	return nil
 }
@@ -0,0 +1,54 @@
+package sauce
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScanReady(t *testing.T) {
+	const output = `Sauce Connect 4.5.4, build 4739
+Starting up; pid 12345
+Establishing secure TLS connection to tunnel...
+Sauce Connect is up, you may start your tests.
+Goodbye.
+`
+	var buf bytes.Buffer
+	ready := make(chan struct{})
+	scanReady(strings.NewReader(output), &buf, ready)
+
+	select {
+	case <-ready:
+	default:
+		t.Fatalf("scanReady did not signal readiness for output:\n%s", output)
+	}
+	if got := buf.String(); got != output {
+		t.Fatalf("scanReady copied %q, want %q", got, output)
+	}
+}
+
+func TestScanReadyNotReady(t *testing.T) {
+	ready := make(chan struct{})
+	scanReady(strings.NewReader("Starting up; pid 12345\n"), &bytes.Buffer{}, ready)
+
+	select {
+	case <-ready:
+		t.Fatalf("scanReady signalled readiness before the tunnel was up")
+	default:
+	}
+}
+
+func TestScanReadyLongLine(t *testing.T) {
+	long := strings.Repeat("x", bufio.MaxScanTokenSize+1)
+	const rest = "Sauce Connect is up, you may start your tests.\n"
+	var buf bytes.Buffer
+	scanReady(strings.NewReader(long+"\n"+rest), &buf, make(chan struct{}))
+
+	if got := buf.String(); !strings.HasSuffix(got, rest) {
+		t.Fatalf("scanReady did not copy the output following a long line; got suffix %q", got[len(got)-len(rest):])
+	}
+	if !strings.Contains(buf.String(), "error reading the proxy output") {
+		t.Errorf("scanReady did not report the scanner error")
+	}
+}
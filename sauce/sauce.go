@@ -129,6 +129,37 @@ type PreRun struct {
 	Timeout int `json:"timeout,omitempty"`
 }
 
+// OptionsKey is the capability under which Sauce Labs expects its options in
+// W3C sessions.
+const OptionsKey = "sauce:options"
+
+// W3C returns the capabilities in the layout that Sauce Labs expects for W3C
+// sessions: the browser, its version and the platform as the standard
+// "browserName", "browserVersion" and "platformName" capabilities, and the
+// remaining options under OptionsKey. The result can be passed to
+// selenium.Capabilities.AddFirstMatch, once per requested platform.
+func (c *Capabilities) W3C() (map[string]interface{}, error) {
+	opts, err := c.ToMap()
+	if err != nil {
+		return nil, err
+	}
+	w3c := make(map[string]interface{})
+	for legacy, standard := range map[string]string{
+		"browser":  "browserName",
+		"version":  "browserVersion",
+		"platform": "platformName",
+	} {
+		if v, ok := opts[legacy]; ok {
+			w3c[standard] = v
+			delete(opts, legacy)
+		}
+	}
+	if len(opts) > 0 {
+		w3c[OptionsKey] = opts
+	}
+	return w3c, nil
+}
+
 // ToMap returns the capabilities in a key/value structure.
 func (c *Capabilities) ToMap() (map[string]interface{}, error) {
 	buf, err := json.Marshal(c)
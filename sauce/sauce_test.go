@@ -2,6 +2,7 @@ package sauce
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -14,3 +15,25 @@ func TestEmptyCapabilities(t *testing.T) {
 		t.Fatalf("json.Marshal(&Capabilities{}) returned %q, expected '{}'", buf)
 	}
 }
+
+func TestW3C(t *testing.T) {
+	c := &Capabilities{
+		Browser:  "chrome",
+		Version:  "80",
+		Platform: "Windows 10",
+		TestName: "test",
+	}
+	got, err := c.W3C()
+	if err != nil {
+		t.Fatalf("c.W3C() returned error: %v", err)
+	}
+	want := map[string]interface{}{
+		"browserName":    "chrome",
+		"browserVersion": "80",
+		"platformName":   "Windows 10",
+		OptionsKey:       map[string]interface{}{"name": "test"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("c.W3C() = %v, want %v", got, want)
+	}
+}
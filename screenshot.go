@@ -0,0 +1,199 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+func (wd *remoteWD) ScreenshotRegion(rect Rect) ([]byte, error) {
+	if rect.Width <= 0 || rect.Height <= 0 {
+		return nil, fmt.Errorf("invalid screenshot region %+v", rect)
+	}
+	if wd.browser == "chrome" {
+		reply := new(struct{ Data string })
+		if err := wd.executeCDP("Page.captureScreenshot", map[string]interface{}{
+			"format": "png",
+			"clip": map[string]interface{}{
+				"x":      rect.X,
+				"y":      rect.Y,
+				"width":  rect.Width,
+				"height": rect.Height,
+				"scale":  1,
+			},
+			"captureBeyondViewport": true,
+		}, reply); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(reply.Data)
+	}
+
+	// Other browsers only capture the viewport, so crop the region out of a
+	// screenshot of it if the region is in view, or of the whole page if not.
+	var g pageGeometry
+	if err := wd.execScriptInto(pageGeometryScript, nil, &g); err != nil {
+		return nil, err
+	}
+	x, y := float64(rect.X), float64(rect.Y)
+	w, h := float64(rect.Width), float64(rect.Height)
+	var (
+		data []byte
+		err  error
+	)
+	if x >= g.X && y >= g.Y && x+w <= g.X+g.ViewWidth && y+h <= g.Y+g.ViewHeight {
+		data, err = wd.Screenshot()
+		x, y = x-g.X, y-g.Y
+	} else {
+		data, err = wd.stitchedScreenshot()
+	}
+	if err != nil {
+		return nil, err
+	}
+	region := image.Rect(
+		round(x*g.Ratio), round(y*g.Ratio),
+		round((x+w)*g.Ratio), round((y+h)*g.Ratio))
+	return cropPNG(data, region)
+}
+
+// cropPNG returns the rectangle r of the PNG image data, which must lie
+// within the image.
+func cropPNG(data []byte, r image.Rectangle) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if !r.In(src.Bounds()) {
+		return nil, fmt.Errorf("region %v is outside of the screenshot bounds %v", r, src.Bounds())
+	}
+	sub, ok := src.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, errors.New("screenshot image cannot be cropped")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub.SubImage(r)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (wd *remoteWD) FullPageScreenshot() ([]byte, error) {
+	if wd.browser == "chrome" {
+		return wd.fullPageScreenshotCDP()
+	}
+	return wd.stitchedScreenshot()
+}
+
+// fullPageScreenshotCDP captures the whole page in one screenshot by clipping
+// it to the size of the content. The viewport is left as is, as resizing it
+// with Emulation.setDeviceMetricsOverride would discard any emulated device
+// metrics that cannot be read back to restore them.
+func (wd *remoteWD) fullPageScreenshotCDP() ([]byte, error) {
+	metrics := new(struct {
+		ContentSize    struct{ Width, Height float64 }
+		CSSContentSize *struct{ Width, Height float64 }
+	})
+	if err := wd.executeCDP("Page.getLayoutMetrics", nil, metrics); err != nil {
+		return nil, err
+	}
+	// Newer versions of Chrome report the content size in device pixels and
+	// add its size in CSS pixels.
+	size := metrics.ContentSize
+	if metrics.CSSContentSize != nil {
+		size = *metrics.CSSContentSize
+	}
+
+	reply := new(struct{ Data string })
+	if err := wd.executeCDP("Page.captureScreenshot", map[string]interface{}{
+		"format": "png",
+		"clip": map[string]interface{}{
+			"x":      0,
+			"y":      0,
+			"width":  math.Ceil(size.Width),
+			"height": math.Ceil(size.Height),
+			"scale":  1,
+		},
+		"captureBeyondViewport": true,
+	}, reply); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(reply.Data)
+}
+
+// pageGeometryScript returns the size of the document and of the viewport,
+// the scroll position and the device pixel ratio.
+const pageGeometryScript = `
+var d = document.documentElement;
+return {
+  width: Math.max(d.scrollWidth, document.body ? document.body.scrollWidth : 0),
+  height: Math.max(d.scrollHeight, document.body ? document.body.scrollHeight : 0),
+  viewWidth: d.clientWidth || window.innerWidth,
+  viewHeight: d.clientHeight || window.innerHeight,
+  x: window.pageXOffset,
+  y: window.pageYOffset,
+  ratio: window.devicePixelRatio || 1
+};
+`
+
+// pageGeometry is the result of pageGeometryScript.
+type pageGeometry struct {
+	Width, Height         float64
+	ViewWidth, ViewHeight float64
+	X, Y                  float64
+	Ratio                 float64
+}
+
+// stitchedScreenshot captures the whole page by scrolling through it and
+// combining screenshots of the viewport. The original scroll position is
+// restored afterwards.
+func (wd *remoteWD) stitchedScreenshot() (data []byte, err error) {
+	var g pageGeometry
+	if err := wd.execScriptInto(pageGeometryScript, nil, &g); err != nil {
+		return nil, err
+	}
+	if g.ViewWidth <= 0 || g.ViewHeight <= 0 {
+		return nil, fmt.Errorf("invalid viewport size %vx%v", g.ViewWidth, g.ViewHeight)
+	}
+	const scrollScript = "window.scrollTo(arguments[0], arguments[1]);"
+	defer func() {
+		if _, serr := wd.ExecuteScript(scrollScript, []interface{}{g.X, g.Y}); serr != nil && err == nil {
+			data, err = nil, serr
+		}
+	}()
+
+	page := image.NewRGBA(image.Rect(0, 0, round(g.Width*g.Ratio), round(g.Height*g.Ratio)))
+	for y := 0.0; y < g.Height; y += g.ViewHeight {
+		for x := 0.0; x < g.Width; x += g.ViewWidth {
+			// The browser may not scroll as far as requested at the edges of the
+			// page, so use the actual position.
+			var pos struct{ X, Y float64 }
+			const script = scrollScript + "return {x: window.pageXOffset, y: window.pageYOffset};"
+			if err := wd.execScriptInto(script, []interface{}{x, y}, &pos); err != nil {
+				return nil, err
+			}
+			shot, err := wd.Screenshot()
+			if err != nil {
+				return nil, err
+			}
+			img, err := png.Decode(bytes.NewReader(shot))
+			if err != nil {
+				return nil, err
+			}
+			at := image.Pt(round(pos.X*g.Ratio), round(pos.Y*g.Ratio))
+			draw.Draw(page, img.Bounds().Sub(img.Bounds().Min).Add(at), img, img.Bounds().Min, draw.Src)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,792 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// This file contains convenience methods that are implemented by executing
+// JavaScript in the browser.
+
+// visibleTextScript returns the visible text of each element in the array
+// provided as the first argument, normalized like the "Get Element Text"
+// command: elements that are not rendered have no text, non-breaking spaces
+// are converted to spaces, and each line and the whole text are trimmed.
+const visibleTextScript = `
+return arguments[0].map(function(e) {
+  if (!e.getClientRects().length) {
+    return '';
+  }
+  var text = (e.innerText || '').replace(/\u00a0/g, ' ');
+  var lines = text.split(/\r\n|\r|\n/);
+  for (var i = 0; i < lines.length; i++) {
+    lines[i] = lines[i].replace(/^[ \t]+|[ \t]+$/g, '').replace(/[ \t]{2,}/g, ' ');
+  }
+  return lines.join('\n').replace(/^\n+|\n+$/g, '');
+});
+`
+
+func (wd *remoteWD) FindElementsText(by, value string) ([]string, error) {
+	elems, err := wd.FindElements(by, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		return nil, nil
+	}
+	var texts []string
+	if err := wd.execScriptInto(visibleTextScript, []interface{}{elems}, &texts); err != nil {
+		return nil, err
+	}
+	return texts, nil
+}
+
+// pendingImagesScript returns the sources of the images in the document that
+// have not finished loading successfully.
+const pendingImagesScript = `
+var pending = [];
+var images = document.images;
+for (var i = 0; i < images.length; i++) {
+  if (!images[i].complete || images[i].naturalWidth === 0) {
+    pending.push(images[i].currentSrc || images[i].src);
+  }
+}
+return pending;
+`
+
+func (wd *remoteWD) WaitForImages(timeout time.Duration) error {
+	var pending []string
+	err := wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		pending = nil
+		if err := wd.execScriptInto(pendingImagesScript, nil, &pending); err != nil {
+			return false, err
+		}
+		return len(pending) == 0, nil
+	}, timeout)
+	if err != nil && len(pending) > 0 {
+		return fmt.Errorf("waiting for images: %v; images not loaded: %s", err, strings.Join(pending, ", "))
+	}
+	return err
+}
+
+// inViewportScript reports whether the bounding box of the element provided as
+// the first argument overlaps the viewport.
+const inViewportScript = `
+var r = arguments[0].getBoundingClientRect();
+var width = window.innerWidth || document.documentElement.clientWidth;
+var height = window.innerHeight || document.documentElement.clientHeight;
+return r.width > 0 && r.height > 0 &&
+  r.bottom > 0 && r.right > 0 && r.top < height && r.left < width;
+`
+
+func (elem *remoteWE) IsInViewport() (bool, error) {
+	var in bool
+	if err := elem.parent.execScriptInto(inViewportScript, []interface{}{elem}, &in); err != nil {
+		return false, err
+	}
+	return in, nil
+}
+
+// VisibilityInfo details why an element is or is not visible, as returned by
+// WebElement.VisibilityDetails.
+type VisibilityInfo struct {
+	// Opacity is the effective opacity of the element, i.e. the product of its
+	// opacity and that of its ancestors.
+	Opacity float64
+	// InViewport reports whether any part of the element is within the
+	// viewport. See WebElement.IsInViewport.
+	InViewport bool
+	// Occluded reports whether another element is on top of the center of the
+	// element. It is false if the center of the element is outside of the
+	// viewport or if the element is hidden, where it cannot be checked.
+	Occluded bool
+	// DisplayNone reports whether the element or one of its ancestors has
+	// "display: none".
+	DisplayNone bool
+	// VisibilityHidden reports whether the computed visibility of the element
+	// is "hidden" or "collapse".
+	VisibilityHidden bool
+}
+
+// visibilityScript returns the VisibilityInfo of the element provided as the
+// first argument.
+const visibilityScript = `
+var e = arguments[0];
+var info = {opacity: 1, displayNone: false};
+for (var n = e; n && n.nodeType === Node.ELEMENT_NODE;
+     n = n.parentElement || (n.getRootNode().host || null)) {
+  var style = window.getComputedStyle(n);
+  info.opacity *= parseFloat(style.opacity);
+  if (style.display === 'none') {
+    info.displayNone = true;
+  }
+}
+var visibility = window.getComputedStyle(e).visibility;
+info.visibilityHidden = visibility === 'hidden' || visibility === 'collapse';
+
+var r = e.getBoundingClientRect();
+var width = window.innerWidth || document.documentElement.clientWidth;
+var height = window.innerHeight || document.documentElement.clientHeight;
+info.inViewport = r.width > 0 && r.height > 0 &&
+  r.bottom > 0 && r.right > 0 && r.top < height && r.left < width;
+
+info.occluded = false;
+var x = r.left + r.width / 2, y = r.top + r.height / 2;
+if (info.inViewport && !info.visibilityHidden && x >= 0 && y >= 0 && x < width && y < height) {
+  // Elements in a shadow tree are only found from their own root.
+  var root = e.getRootNode();
+  var top = (root.elementFromPoint ? root : document).elementFromPoint(x, y);
+  info.occluded = top !== null && !e.contains(top);
+}
+return info;
+`
+
+func (elem *remoteWE) VisibilityDetails() (VisibilityInfo, error) {
+	var info VisibilityInfo
+	if err := elem.parent.execScriptInto(visibilityScript, []interface{}{elem}, &info); err != nil {
+		return VisibilityInfo{}, err
+	}
+	return info, nil
+}
+
+// ValidityState holds the flags of the HTML5 constraint validation state of a
+// form field, as returned by WebElement.ValidityState. See
+// https://developer.mozilla.org/en-US/docs/Web/API/ValidityState.
+type ValidityState struct {
+	// Valid reports whether the field satisfies all of its constraints.
+	Valid bool
+	// ValueMissing reports whether a required field has no value.
+	ValueMissing bool
+	// TypeMismatch reports whether the value does not match the type of the
+	// field, such as "email" or "url".
+	TypeMismatch bool
+	// PatternMismatch reports whether the value does not match the pattern
+	// attribute.
+	PatternMismatch bool
+	// TooLong and TooShort report whether the value is longer than the
+	// maxlength attribute or shorter than the minlength attribute.
+	TooLong, TooShort bool
+	// RangeUnderflow and RangeOverflow report whether the value is less than
+	// the min attribute or greater than the max attribute.
+	RangeUnderflow, RangeOverflow bool
+	// StepMismatch reports whether the value does not fit the step attribute.
+	StepMismatch bool
+	// BadInput reports whether the browser cannot convert the input to a
+	// value, such as letters in a number field.
+	BadInput bool
+	// CustomError reports whether a message was set with setCustomValidity.
+	CustomError bool
+}
+
+// validityScript returns the validity flags of the form field provided as the
+// first argument, or null if the element has no validity state.
+const validityScript = `
+var v = arguments[0].validity;
+if (!v) {
+  return null;
+}
+return {
+  valid: v.valid,
+  valueMissing: v.valueMissing,
+  typeMismatch: v.typeMismatch,
+  patternMismatch: v.patternMismatch,
+  tooLong: v.tooLong,
+  tooShort: v.tooShort,
+  rangeUnderflow: v.rangeUnderflow,
+  rangeOverflow: v.rangeOverflow,
+  stepMismatch: v.stepMismatch,
+  badInput: v.badInput,
+  customError: v.customError
+};
+`
+
+func (elem *remoteWE) ValidityState() (ValidityState, error) {
+	var state *ValidityState
+	if err := elem.parent.execScriptInto(validityScript, []interface{}{elem}, &state); err != nil {
+		return ValidityState{}, err
+	}
+	if state == nil {
+		return ValidityState{}, errors.New("element is not a form field with a validity state")
+	}
+	return *state, nil
+}
+
+func (elem *remoteWE) ValidationMessage() (string, error) {
+	var msg *string
+	if err := elem.parent.execScriptInto("return arguments[0].validationMessage;", []interface{}{elem}, &msg); err != nil {
+		return "", err
+	}
+	if msg == nil {
+		return "", errors.New("element is not a form field with a validation message")
+	}
+	return *msg, nil
+}
+
+func (wd *remoteWD) UserAgent() (string, error) {
+	var ua string
+	if err := wd.execScriptInto("return navigator.userAgent;", nil, &ua); err != nil {
+		return "", err
+	}
+	return ua, nil
+}
+
+func (wd *remoteWD) ViewportSize() (width, height int, err error) {
+	size := new(struct{ Width, Height int })
+	const script = "return {width: window.innerWidth, height: window.innerHeight};"
+	if err := wd.execScriptInto(script, nil, size); err != nil {
+		return 0, 0, err
+	}
+	return size.Width, size.Height, nil
+}
+
+func (wd *remoteWD) DocumentCharset() (string, error) {
+	var charset string
+	if err := wd.execScriptInto("return document.characterSet;", nil, &charset); err != nil {
+		return "", err
+	}
+	return charset, nil
+}
+
+func (wd *remoteWD) ContentType() (string, error) {
+	var contentType string
+	if err := wd.execScriptInto("return document.contentType;", nil, &contentType); err != nil {
+		return "", err
+	}
+	return contentType, nil
+}
+
+// selectedTextScript returns the text selected in the current document. The
+// selection within text fields is not part of the document selection in some
+// browsers, such as Firefox, so it is read from the focused field instead.
+const selectedTextScript = `
+var e = document.activeElement;
+if (e && (e.tagName === 'INPUT' || e.tagName === 'TEXTAREA')) {
+  try {
+    if (e.selectionStart !== e.selectionEnd) {
+      return e.value.substring(e.selectionStart, e.selectionEnd);
+    }
+  } catch (err) {
+    // Some input types, such as email, do not support selection.
+  }
+}
+var selection = window.getSelection();
+return selection ? selection.toString() : '';
+`
+
+func (wd *remoteWD) SelectedText() (string, error) {
+	var text string
+	if err := wd.execScriptInto(selectedTextScript, nil, &text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// highlightScript outlines the element provided as the first argument and
+// returns its previous inline outline style.
+const highlightScript = `
+var s = arguments[0].style;
+var previous = {outline: s.outline, outlineOffset: s.outlineOffset};
+s.outline = '3px solid red';
+s.outlineOffset = '-1px';
+return previous;
+`
+
+// unhighlightScript restores the inline outline style of the element provided
+// as the first argument to the value provided as the second argument.
+const unhighlightScript = `
+arguments[0].style.outline = arguments[1].outline;
+arguments[0].style.outlineOffset = arguments[1].outlineOffset;
+`
+
+func (elem *remoteWE) Highlight() error {
+	var previous map[string]string
+	if err := elem.parent.execScriptInto(highlightScript, []interface{}{elem}, &previous); err != nil {
+		return err
+	}
+	time.Sleep(highlightDuration)
+	_, err := elem.parent.ExecuteScript(unhighlightScript, []interface{}{elem, previous})
+	return err
+}
+
+// setValueScript sets the value of the element provided as the first argument
+// to the second argument and dispatches the events that typing would. The
+// value is set through the prototype's setter, as frameworks like React
+// override the element's own value property to track changes.
+const setValueScript = `
+var e = arguments[0];
+var proto = Object.getPrototypeOf(e);
+var desc = Object.getOwnPropertyDescriptor(proto, 'value');
+if (desc && desc.set) {
+  desc.set.call(e, arguments[1]);
+} else {
+  e.value = arguments[1];
+}
+e.dispatchEvent(new Event('input', {bubbles: true}));
+e.dispatchEvent(new Event('change', {bubbles: true}));
+`
+
+func (elem *remoteWE) SetValueJS(value string) error {
+	_, err := elem.parent.ExecuteScript(setValueScript, []interface{}{elem, value})
+	return err
+}
+
+// setTypedValueScript sets the value of the element provided as the first
+// argument like setValueScript, unless it is not an input of the type provided
+// as the third argument, in which case it is left untouched. It returns the
+// type and resulting value of the element.
+const setTypedValueScript = `
+if (arguments[0].type === arguments[2]) {
+  (function() {` + setValueScript + `}).apply(null, arguments);
+}
+return {type: arguments[0].type, value: arguments[0].value};
+`
+
+// setTypedValue sets the value of the element, which must be an input of the
+// given type, like SetValueJS, and checks that the input accepted it, as
+// inputs reset values in the wrong format to the empty string. Inputs of
+// another type are not modified.
+func (elem *remoteWE) setTypedValue(inputType, value string) error {
+	var got struct{ Type, Value string }
+	if err := elem.parent.execScriptInto(setTypedValueScript, []interface{}{elem, value, inputType}, &got); err != nil {
+		return err
+	}
+	if got.Type != inputType {
+		return fmt.Errorf("element is an input of type %q, want %q", got.Type, inputType)
+	}
+	if got.Value != value {
+		return fmt.Errorf("input did not accept the value %q; its value is %q", value, got.Value)
+	}
+	return nil
+}
+
+func (elem *remoteWE) SetDate(t time.Time) error {
+	return elem.setTypedValue("date", t.Format("2006-01-02"))
+}
+
+func (elem *remoteWE) SetTime(t time.Time) error {
+	layout := "15:04"
+	if t.Second() != 0 {
+		layout = "15:04:05"
+	}
+	return elem.setTypedValue("time", t.Format(layout))
+}
+
+// labelTextScript returns the label of the element provided as the first
+// argument, following the precedence of the accessible name computation:
+// aria-labelledby, then aria-label, then the associated <label> elements.
+const labelTextScript = `
+var e = arguments[0];
+var text = function(n) {
+  return (n.innerText || n.textContent || '').replace(/\s+/g, ' ').trim();
+};
+var ids = (e.getAttribute('aria-labelledby') || '').split(/\s+/);
+var parts = [];
+for (var i = 0; i < ids.length; i++) {
+  var ref = ids[i] && e.ownerDocument.getElementById(ids[i]);
+  if (ref) {
+    parts.push(text(ref));
+  }
+}
+if (parts.length) {
+  return parts.join(' ').trim();
+}
+var aria = (e.getAttribute('aria-label') || '').trim();
+if (aria) {
+  return aria;
+}
+var labels = e.labels ? Array.prototype.slice.call(e.labels) : [];
+if (!e.labels) {
+  if (e.id) {
+    labels = Array.prototype.slice.call(e.ownerDocument.querySelectorAll('label[for="' + CSS.escape(e.id) + '"]'));
+  }
+  var wrapping = e.closest && e.closest('label');
+  if (wrapping && labels.indexOf(wrapping) < 0) {
+    labels.push(wrapping);
+  }
+}
+return labels.map(text).filter(function(t) { return t; }).join(' ');
+`
+
+func (elem *remoteWE) LabelText() (string, error) {
+	var label string
+	if err := elem.parent.execScriptInto(labelTextScript, []interface{}{elem}, &label); err != nil {
+		return "", err
+	}
+	return label, nil
+}
+
+// horizontalScrollScript reports whether the document is wider than the
+// viewport.
+const horizontalScrollScript = `
+var root = document.documentElement;
+return root.scrollWidth > root.clientWidth;
+`
+
+func (wd *remoteWD) HasHorizontalScroll() (bool, error) {
+	var scroll bool
+	if err := wd.execScriptInto(horizontalScrollScript, nil, &scroll); err != nil {
+		return false, err
+	}
+	return scroll, nil
+}
+
+// overflowingElementsScript returns the outermost elements that extend past
+// the left or right edge of the viewport, ignoring those that are clipped by
+// a scrolling or hidden-overflow ancestor, or fixed in place, as they do not
+// widen the document.
+const overflowingElementsScript = `
+var width = document.documentElement.clientWidth;
+var overflowing = function(e) {
+  var r = e.getBoundingClientRect();
+  if (r.width === 0 && r.height === 0) {
+    return false;
+  }
+  var left = r.left + window.scrollX, right = r.right + window.scrollX;
+  return left < -0.5 || right > width + 0.5;
+};
+var clipped = function(e) {
+  for (var p = e; p && p !== document.body && p !== document.documentElement; p = p.parentElement) {
+    var style = getComputedStyle(p);
+    if (style.position === 'fixed') {
+      return true;
+    }
+    if (p !== e && style.overflowX !== 'visible') {
+      return true;
+    }
+  }
+  return false;
+};
+var culprits = [];
+var all = document.body.getElementsByTagName('*');
+for (var i = 0; i < all.length; i++) {
+  var e = all[i];
+  if (!overflowing(e) || clipped(e)) {
+    continue;
+  }
+  var parent = e.parentElement;
+  if (parent && parent !== document.body && overflowing(parent) && !clipped(parent)) {
+    continue;
+  }
+  culprits.push(e);
+}
+return culprits;
+`
+
+func (wd *remoteWD) OverflowingElements() ([]WebElement, error) {
+	response, err := wd.ExecuteScriptRaw(overflowingElementsScript, nil)
+	if err != nil {
+		return nil, err
+	}
+	return wd.DecodeElements(response)
+}
+
+// scrollByScript scrolls the content of the element provided as the first
+// argument by the second and third arguments, in pixels.
+const scrollByScript = `
+arguments[0].scrollLeft += arguments[1];
+arguments[0].scrollTop += arguments[2];
+`
+
+func (elem *remoteWE) ScrollBy(dx, dy int) error {
+	_, err := elem.parent.ExecuteScript(scrollByScript, []interface{}{elem, dx, dy})
+	return err
+}
+
+// dropFileScript dispatches the events of dropping a file, whose name, MIME
+// type and base64-encoded contents are the second to fourth arguments, on the
+// element provided as the first argument.
+const dropFileScript = `
+var target = arguments[0];
+var bytes = atob(arguments[3]);
+var data = new Uint8Array(bytes.length);
+for (var i = 0; i < bytes.length; i++) {
+  data[i] = bytes.charCodeAt(i);
+}
+var transfer = new DataTransfer();
+transfer.items.add(new File([data], arguments[1], {type: arguments[2]}));
+var r = target.getBoundingClientRect();
+var x = r.left + r.width / 2, y = r.top + r.height / 2;
+['dragenter', 'dragover', 'drop'].forEach(function(type) {
+  target.dispatchEvent(new DragEvent(type, {
+    bubbles: true, cancelable: true, clientX: x, clientY: y, dataTransfer: transfer
+  }));
+});
+`
+
+func (elem *remoteWE) DropFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	mimeType, _, err := mime.ParseMediaType(mime.TypeByExtension(filepath.Ext(path)))
+	if err != nil {
+		mimeType = "application/octet-stream"
+	}
+	args := []interface{}{elem, filepath.Base(path), mimeType, base64.StdEncoding.EncodeToString(data)}
+	_, err = elem.parent.ExecuteScript(dropFileScript, args)
+	return err
+}
+
+// documentRectScript returns the bounding box of the element provided as the
+// first argument in document coordinates, so that scrolling the window is not
+// mistaken for movement of the element.
+const documentRectScript = `
+var r = arguments[0].getBoundingClientRect();
+return {x: r.left + window.pageXOffset, y: r.top + window.pageYOffset, width: r.width, height: r.height};
+`
+
+func (elem *remoteWE) WaitUntilStable(timeout time.Duration) error {
+	var last *rect
+	return elem.parent.WaitWithTimeout(func(WebDriver) (bool, error) {
+		r := new(rect)
+		if err := elem.parent.execScriptInto(documentRectScript, []interface{}{elem}, r); err != nil {
+			return false, err
+		}
+		stable := last != nil && *r == *last
+		last = r
+		return stable, nil
+	}, timeout)
+}
+
+// runningAnimationsScript returns a description of each animation that is
+// running or about to run on the element provided as the first argument or on
+// its descendants, or null if the browser does not support getAnimations.
+const runningAnimationsScript = `
+var e = arguments[0];
+if (!e.getAnimations) {
+  return null;
+}
+return e.getAnimations({subtree: true}).filter(function(a) {
+  return a.playState === 'running' || a.pending;
+}).map(function(a) {
+  var name = a.animationName || a.transitionProperty || a.id || 'animation';
+  if (a.effect && a.effect.target && a.effect.target !== e) {
+    name += ' on <' + a.effect.target.tagName.toLowerCase() + '>';
+  }
+  return name;
+});
+`
+
+func (elem *remoteWE) WaitForAnimations(timeout time.Duration) error {
+	var running []string
+	err := elem.parent.WaitWithTimeout(func(WebDriver) (bool, error) {
+		var names *[]string
+		if err := elem.parent.execScriptInto(runningAnimationsScript, []interface{}{elem}, &names); err != nil {
+			return false, err
+		}
+		if names == nil {
+			return false, &UnsupportedError{Method: "WaitForAnimations", Browser: elem.parent.browser}
+		}
+		running = *names
+		return len(running) == 0, nil
+	}, timeout)
+	if err != nil && len(running) > 0 {
+		return fmt.Errorf("waiting for animations: %v; still running: %s", err, strings.Join(running, ", "))
+	}
+	return err
+}
+
+// The bits of the bitmask returned by Node.compareDocumentPosition.
+const (
+	documentPositionDisconnected = 0x01
+	documentPositionPreceding    = 0x02
+	documentPositionFollowing    = 0x04
+)
+
+func (wd *remoteWD) ElementsInDocumentOrder(a, b WebElement) (bool, error) {
+	var position int
+	const script = "return arguments[0].compareDocumentPosition(arguments[1]);"
+	if err := wd.execScriptInto(script, []interface{}{a, b}, &position); err != nil {
+		return false, err
+	}
+	if position&documentPositionDisconnected != 0 {
+		return false, errors.New("the elements are not in the same document")
+	}
+	// A descendant is also reported as following its ancestor, which is the
+	// ancestor's position in document order.
+	return position&documentPositionFollowing != 0, nil
+}
+
+func (wd *remoteWD) ElementsInVisualOrder(a, b WebElement) (bool, error) {
+	var rects [2]struct{ Top, Bottom, Left float64 }
+	const script = `
+return [arguments[0], arguments[1]].map(function(e) {
+  var r = e.getBoundingClientRect();
+  return {top: r.top, bottom: r.bottom, left: r.left};
+});`
+	if err := wd.execScriptInto(script, []interface{}{a, b}, &rects); err != nil {
+		return false, err
+	}
+	ra, rb := rects[0], rects[1]
+	switch {
+	case ra.Bottom <= rb.Top:
+		return true, nil
+	case rb.Bottom <= ra.Top:
+		return false, nil
+	}
+	// The elements overlap vertically, so they are on the same line.
+	return ra.Left < rb.Left, nil
+}
+
+// tabOrderStartScript focuses a temporary element at the start of the
+// document, so that the next Tab key press focuses the first element in
+// sequential focus order, and resets the elements visited by tabOrderStepScript.
+const tabOrderStartScript = `
+var start = document.createElement('span');
+start.id = 'selenium-tab-order-start';
+start.tabIndex = -1;
+document.body.insertBefore(start, document.body.firstChild);
+start.focus();
+window.__seleniumTabOrder = [];
+`
+
+// tabOrderStepScript returns the element that has the focus and whether it is
+// within the element provided as the first argument, or null once the focus
+// leaves the document or returns to an element that was already visited.
+const tabOrderStepScript = `
+var within = arguments[0], e = document.activeElement;
+var visited = window.__seleniumTabOrder;
+if (!e || e === document.body || e === document.documentElement || visited.indexOf(e) >= 0) {
+  return null;
+}
+visited.push(e);
+return {element: e, inside: e !== within && within.contains(e)};
+`
+
+// tabOrderEndScript removes the element added by tabOrderStartScript.
+const tabOrderEndScript = `
+var start = document.getElementById('selenium-tab-order-start');
+if (start) {
+  start.parentNode.removeChild(start);
+}
+delete window.__seleniumTabOrder;
+`
+
+// pressTab presses the Tab key in the element that has the focus.
+func (wd *remoteWD) pressTab() error {
+	if wd.w3cCompatible {
+		return wd.NewActions().KeyDown(TabKey).KeyUp(TabKey).Perform()
+	}
+	active, err := wd.ActiveElement()
+	if err != nil {
+		return err
+	}
+	return active.SendKeys(TabKey)
+}
+
+func (wd *remoteWD) TabOrder(within WebElement) (elems []WebElement, err error) {
+	if _, err := wd.ExecuteScript(tabOrderStartScript, nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, cerr := wd.ExecuteScript(tabOrderEndScript, nil); cerr != nil && err == nil {
+			elems, err = nil, cerr
+		}
+	}()
+
+	// Every focusable element is visited at most once, so bound the number of
+	// steps by the number of elements in the document.
+	var max int
+	if err := wd.execScriptInto("return document.getElementsByTagName('*').length;", nil, &max); err != nil {
+		return nil, err
+	}
+	for i := 0; i < max; i++ {
+		if err := wd.pressTab(); err != nil {
+			return nil, err
+		}
+		response, err := wd.ExecuteScriptRaw(tabOrderStepScript, []interface{}{within})
+		if err != nil {
+			return nil, err
+		}
+		step := new(struct {
+			Value *struct {
+				Element map[string]interface{}
+				Inside  bool
+			}
+		})
+		if err := json.Unmarshal(response, step); err != nil {
+			return nil, err
+		}
+		if step.Value == nil {
+			break
+		}
+		if !step.Value.Inside {
+			continue
+		}
+		id := elementIDFromValue(step.Value.Element)
+		if id == "" {
+			return nil, fmt.Errorf("invalid element returned: %+v", step.Value.Element)
+		}
+		elems = append(elems, wd.newElement(id))
+	}
+	return elems, nil
+}
+
+// addStyleScript appends a <style> element with the rules provided as the
+// first argument to the document and returns the element's ID.
+const addStyleScript = `
+window.__seleniumStyleSheets = (window.__seleniumStyleSheets || 0) + 1;
+var style = document.createElement('style');
+style.id = 'selenium-style-' + window.__seleniumStyleSheets;
+style.textContent = arguments[0];
+(document.head || document.documentElement).appendChild(style);
+return style.id;
+`
+
+// removeStyleScript removes the <style> element whose ID is provided as the
+// first argument and returns whether it existed.
+const removeStyleScript = `
+var style = document.getElementById(arguments[0]);
+if (!style || style.tagName.toLowerCase() !== 'style') {
+  return false;
+}
+style.parentNode.removeChild(style);
+return true;
+`
+
+func (wd *remoteWD) AddStyleSheet(css string) (string, error) {
+	// In Chrome, style sheets added through the DevTools Protocol are not
+	// subject to the page's Content Security Policy.
+	if wd.browser == "chrome" {
+		return wd.createStyleSheet(css)
+	}
+	var id string
+	if err := wd.execScriptInto(addStyleScript, []interface{}{css}, &id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (wd *remoteWD) RemoveStyleSheet(id string) error {
+	if wd.browser == "chrome" {
+		return wd.setStyleSheetText(id, "")
+	}
+	var removed bool
+	if err := wd.execScriptInto(removeStyleScript, []interface{}{id}, &removed); err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("no style sheet with ID %q in the current page", id)
+	}
+	return nil
+}
+
+func (elem *remoteWE) CSSVariable(name string) (string, error) {
+	if !strings.HasPrefix(name, "--") {
+		name = "--" + name
+	}
+	var value string
+	const script = "return window.getComputedStyle(arguments[0]).getPropertyValue(arguments[1]);"
+	if err := elem.parent.execScriptInto(script, []interface{}{elem, name}, &value); err != nil {
+		return "", err
+	}
+	// Custom properties keep the whitespace of their declaration.
+	return strings.TrimSpace(value), nil
+}
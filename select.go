@@ -0,0 +1,185 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Select wraps a <select> element to choose among its options. Create one
+// with WebDriver.NewSelect.
+type Select struct {
+	elem     WebElement
+	multiple bool
+}
+
+func (wd *remoteWD) NewSelect(elem WebElement) (*Select, error) {
+	tag, err := elem.TagName()
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(tag) != "select" {
+		return nil, fmt.Errorf("element is a <%s>, not a <select>", tag)
+	}
+	multiple, err := elem.GetAttribute("multiple")
+	if err != nil {
+		// GetAttribute returns an error when the attribute is absent.
+		multiple = ""
+	}
+	return &Select{
+		elem:     elem,
+		multiple: multiple != "" && multiple != "false",
+	}, nil
+}
+
+// Element returns the underlying <select> element.
+func (s *Select) Element() WebElement {
+	return s.elem
+}
+
+// IsMultiple returns true if more than one option can be selected at once.
+func (s *Select) IsMultiple() bool {
+	return s.multiple
+}
+
+// Options returns all of the options of the select, in document order.
+func (s *Select) Options() ([]WebElement, error) {
+	return s.elem.FindElements(ByCSSSelector, "option")
+}
+
+// SelectedOptions returns the options that are currently selected.
+func (s *Select) SelectedOptions() ([]WebElement, error) {
+	opts, err := s.Options()
+	if err != nil {
+		return nil, err
+	}
+	var selected []WebElement
+	for _, opt := range opts {
+		ok, err := opt.IsSelected()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected = append(selected, opt)
+		}
+	}
+	return selected, nil
+}
+
+// SelectByVisibleText selects the options whose visible text is text. Only
+// the first matching option is selected unless the select is multiple.
+func (s *Select) SelectByVisibleText(text string) error {
+	return s.setMatching(true, fmt.Sprintf("visible text %q", text), func(_ int, opt WebElement) (bool, error) {
+		t, err := opt.Text()
+		return strings.TrimSpace(t) == strings.TrimSpace(text), err
+	})
+}
+
+// SelectByValue selects the options whose value attribute is value. Only the
+// first matching option is selected unless the select is multiple.
+func (s *Select) SelectByValue(value string) error {
+	return s.setMatching(true, fmt.Sprintf("value %q", value), valueMatcher(value))
+}
+
+// SelectByIndex selects the option at index i, counting from zero in document
+// order.
+func (s *Select) SelectByIndex(i int) error {
+	return s.setMatching(true, fmt.Sprintf("index %d", i), indexMatcher(i))
+}
+
+// DeselectAll deselects all of the options. It is only supported by multiple
+// selects.
+func (s *Select) DeselectAll() error {
+	return s.setMatching(false, "", func(int, WebElement) (bool, error) { return true, nil })
+}
+
+// DeselectByVisibleText deselects the options whose visible text is text. It
+// is only supported by multiple selects.
+func (s *Select) DeselectByVisibleText(text string) error {
+	return s.setMatching(false, fmt.Sprintf("visible text %q", text), func(_ int, opt WebElement) (bool, error) {
+		t, err := opt.Text()
+		return strings.TrimSpace(t) == strings.TrimSpace(text), err
+	})
+}
+
+// DeselectByValue deselects the options whose value attribute is value. It is
+// only supported by multiple selects.
+func (s *Select) DeselectByValue(value string) error {
+	return s.setMatching(false, fmt.Sprintf("value %q", value), valueMatcher(value))
+}
+
+// DeselectByIndex deselects the option at index i. It is only supported by
+// multiple selects.
+func (s *Select) DeselectByIndex(i int) error {
+	return s.setMatching(false, fmt.Sprintf("index %d", i), indexMatcher(i))
+}
+
+type optionMatcher func(i int, opt WebElement) (bool, error)
+
+func valueMatcher(value string) optionMatcher {
+	return func(_ int, opt WebElement) (bool, error) {
+		v, err := opt.GetAttribute("value")
+		return v == value, err
+	}
+}
+
+func indexMatcher(index int) optionMatcher {
+	return func(i int, _ WebElement) (bool, error) {
+		return i == index, nil
+	}
+}
+
+// setMatching selects or deselects the options for which match returns true.
+// An error is returned if no option matches, unless desc is empty.
+func (s *Select) setMatching(selected bool, desc string, match optionMatcher) error {
+	if !selected && !s.multiple {
+		return errors.New("options can only be deselected in a multiple select")
+	}
+	opts, err := s.Options()
+	if err != nil {
+		return err
+	}
+	matched := false
+	for i, opt := range opts {
+		ok, err := match(i, opt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		matched = true
+		if err := setOptionSelected(opt, selected); err != nil {
+			return err
+		}
+		if !s.multiple {
+			return nil
+		}
+	}
+	if !matched && desc != "" {
+		return fmt.Errorf("no option with %s", desc)
+	}
+	return nil
+}
+
+// setOptionSelected clicks the option if its selectedness differs from
+// selected, which toggles it in a multiple select.
+func setOptionSelected(opt WebElement, selected bool) error {
+	current, err := opt.IsSelected()
+	if err != nil {
+		return err
+	}
+	if current == selected {
+		return nil
+	}
+	if selected {
+		enabled, err := opt.IsEnabled()
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return errors.New("cannot select a disabled option")
+		}
+	}
+	return opt.Click()
+}
@@ -1,6 +1,9 @@
 package selenium
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/tebeka/selenium/chrome"
@@ -92,6 +95,36 @@ const (
 // with standard and browser-specific options.
 type Capabilities map[string]interface{}
 
+// firstMatchKey is the key under which AddFirstMatch stores the alternative
+// capabilities.
+const firstMatchKey = "firstMatch"
+
+// AddFirstMatch adds an alternative set of capabilities. When creating a W3C
+// session, the remote end uses the first alternative that it can satisfy,
+// merged with the other capabilities. For example, a cloud grid can be asked
+// for whichever of several platforms becomes available first. Legacy sessions
+// ignore the alternatives.
+//
+// Each alternative may have its own vendor-specific options, such as
+// "sauce:options". The W3C specification forbids the other capabilities from
+// also setting a key that an alternative sets, so such keys are moved into
+// every alternative. Where both values are objects, they are merged, with the
+// alternative's fields taking precedence.
+func (c Capabilities) AddFirstMatch(alt Capabilities) {
+	alts, _ := c[firstMatchKey].([]Capabilities)
+	c[firstMatchKey] = append(alts, alt)
+}
+
+// CapabilitiesRequest is the "capabilities" parameter of the W3C New Session
+// command.
+type CapabilitiesRequest struct {
+	// AlwaysMatch are the capabilities that every session must satisfy.
+	AlwaysMatch Capabilities `json:"alwaysMatch"`
+	// FirstMatch are alternative capabilities, of which the first that can be
+	// satisfied is used.
+	FirstMatch []Capabilities `json:"firstMatch,omitempty"`
+}
+
 // AddChrome adds Chrome-specific capabilities.
 func (c Capabilities) AddChrome(f chrome.Capabilities) {
 	c[chrome.CapabilitiesKey] = f
@@ -103,6 +136,20 @@ func (c Capabilities) AddFirefox(f firefox.Capabilities) {
 	c[firefox.CapabilitiesKey] = f
 }
 
+// MozProcessID returns the ID of the browser process, which geckodriver
+// reports in the "moz:processID" capability of a Firefox session, as returned
+// by WebDriver.Capabilities. The second result is false if it is not set.
+func (c Capabilities) MozProcessID() (int, bool) {
+	// Numbers are decoded from JSON as float64.
+	switch pid := c["moz:processID"].(type) {
+	case float64:
+		return int(pid), true
+	case int:
+		return pid, true
+	}
+	return 0, false
+}
+
 // AddProxy adds proxy configuration to the capabilities.
 func (c Capabilities) AddProxy(p Proxy) {
 	c["proxy"] = p
@@ -123,6 +170,79 @@ func (c Capabilities) SetLogLevel(typ log.Type, level log.Level) {
 	m[typ] = level
 }
 
+// PromptBehavior is how the browser handles user prompts, such as alerts, that
+// are open when a command is sent.
+type PromptBehavior string
+
+// The prompt behaviors defined by the W3C specification.
+const (
+	DismissPrompt          PromptBehavior = "dismiss"
+	AcceptPrompt           PromptBehavior = "accept"
+	DismissAndNotifyPrompt PromptBehavior = "dismiss and notify"
+	AcceptAndNotifyPrompt  PromptBehavior = "accept and notify"
+	IgnorePrompt           PromptBehavior = "ignore"
+)
+
+// SetUnhandledPromptBehavior sets how the browser handles unexpected user
+// prompts. Both the W3C "unhandledPromptBehavior" capability and the legacy
+// "unexpectedAlertBehaviour" one, used by Selenium 3 grids, are set; only the
+// former is sent to W3C-compatible remote ends. The legacy capability does not
+// support notifying, so the "and notify" behaviors fall back to plain accept
+// or dismiss for it.
+func (c Capabilities) SetUnhandledPromptBehavior(b PromptBehavior) {
+	c["unhandledPromptBehavior"] = string(b)
+	legacy := b
+	switch b {
+	case DismissAndNotifyPrompt:
+		legacy = DismissPrompt
+	case AcceptAndNotifyPrompt:
+		legacy = AcceptPrompt
+	}
+	c["unexpectedAlertBehaviour"] = string(legacy)
+}
+
+// SeleniumGridOptions configures features specific to Selenium 4 grids, which
+// are passed as "se:"-prefixed capabilities. Fields with zero values are
+// omitted, leaving the grid's defaults in place.
+type SeleniumGridOptions struct {
+	// RecordVideo enables video recording of the session on grids that
+	// support it.
+	RecordVideo bool
+	// ScreenResolution is the resolution of the screen of the node running
+	// the browser, e.g. "1920x1080".
+	ScreenResolution string
+	// TimeZone is the time zone of the node running the browser, e.g.
+	// "US/Pacific".
+	TimeZone string
+	// TestName names the session in the grid's user interface and the
+	// recorded video.
+	TestName string
+	// BuildName associates the session with a build.
+	BuildName string
+}
+
+// SetSeleniumOptions sets the capabilities that configure Selenium 4 grid
+// features, such as video recording.
+func (c Capabilities) SetSeleniumOptions(o SeleniumGridOptions) {
+	for key, value := range map[string]interface{}{
+		"se:screenResolution": o.ScreenResolution,
+		"se:timeZone":         o.TimeZone,
+		"se:name":             o.TestName,
+		"se:build":            o.BuildName,
+	} {
+		if value == "" {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+	}
+	if o.RecordVideo {
+		c["se:recordVideo"] = true
+	} else {
+		delete(c, "se:recordVideo")
+	}
+}
+
 // Proxy specifies configuration for proxies in the browser. Set the key
 // "proxy" in Capabilities to an instance of this type.
 type Proxy struct {
@@ -172,7 +292,7 @@ const (
 	PAC = "pac"
 )
 
-// Status contains information returned by the Status method.
+// Status contains information returned by the Status method and ProbeGrid.
 type Status struct {
 	// The following fields are used by Selenium and ChromeDriver.
 	Java struct {
@@ -201,18 +321,47 @@ type Size struct {
 	Width, Height int
 }
 
+// Rect is the position and size of a rectangle, such as a window, in CSS
+// pixels.
+type Rect struct {
+	X, Y, Width, Height int
+}
+
 // Cookie represents an HTTP cookie.
 type Cookie struct {
-	Name     string   `json:"name"`
-	Value    string   `json:"value"`
-	Path     string   `json:"path"`
-	Domain   string   `json:"domain"`
-	Secure   bool     `json:"secure"`
-	Expiry   uint     `json:"expiry"`
-	HTTPOnly bool     `json:"httpOnly"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Path   string `json:"path"`
+	Domain string `json:"domain"`
+	Secure bool   `json:"secure"`
+	// Expiry is when the cookie expires, in seconds since the Unix epoch, as
+	// set by SetExpiry.
+	Expiry   uint `json:"expiry"`
+	HTTPOnly bool `json:"httpOnly"`
+	// SameSite is the SameSite attribute of the cookie, which is omitted if
+	// empty, leaving the browser's default in place.
 	SameSite SameSite `json:"sameSite,omitempty"`
 }
 
+// SetExpiry sets the expiry of the cookie to t, truncated to the second.
+// Times before the Unix epoch are clamped to it.
+func (c *Cookie) SetExpiry(t time.Time) {
+	if t.Unix() < 0 {
+		c.Expiry = 0
+		return
+	}
+	c.Expiry = uint(t.Unix())
+}
+
+// ExpiryTime returns the expiry of the cookie, or the zero time if it is not
+// set, as for session cookies.
+func (c Cookie) ExpiryTime() time.Time {
+	if c.Expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(c.Expiry), 0)
+}
+
 // SameSite is the type for the SameSite field in Cookie.
 type SameSite string
 
@@ -243,7 +392,15 @@ type WebDriver interface {
 	// SwitchSession switches to the given session ID.
 	SwitchSession(sessionID string) error
 
-	// Capabilities returns the current session's capabilities.
+	// MarshalSession serializes the session, i.e. its ID, the URL of the
+	// remote end, the protocol in use and the negotiated capabilities, so
+	// that it can be resumed with UnmarshalSession, e.g. in another process.
+	MarshalSession() ([]byte, error)
+	// Capabilities returns the current session's capabilities. For
+	// W3C-compatible sessions, these are the capabilities that the remote end
+	// granted when the session was created, i.e. the "capabilities" object of
+	// the New Session response, which may differ from the requested ones,
+	// e.g. in the resolved browser version.
 	Capabilities() (Capabilities, error)
 
 	// SetAsyncScriptTimeout sets the amount of time that asynchronous scripts
@@ -259,6 +416,11 @@ type WebDriver interface {
 
 	// Quit ends the current session. The browser instance will be closed.
 	Quit() error
+	// PauseOnFailure makes Quit keep the browser open, so that it can be
+	// inspected, if failed returns true, e.g. when passed a test's t.Failed.
+	// Quit waits for d or, if d is zero, until Enter is pressed. If d is zero
+	// and standard input is not a terminal, e.g. in CI, Quit does not wait.
+	PauseOnFailure(failed func() bool, d time.Duration)
 
 	// CurrentWindowHandle returns the ID of current window handle.
 	CurrentWindowHandle() (string, error)
@@ -274,21 +436,80 @@ type WebDriver interface {
 	Close() error
 	// SwitchFrame switches to the given frame. The frame parameter can be the
 	// frame's ID as a string, its WebElement instance as returned by
-	// GetElement, or nil to switch to the current top-level browsing context.
+	// FindElement, the int index of the frame among the frames of the current
+	// browsing context, or nil or the empty string to switch to the current
+	// top-level browsing context.
 	SwitchFrame(frame interface{}) error
+	// SwitchToParentFrame switches to the parent of the current frame, or
+	// stays in the top-level browsing context if it is current.
+	SwitchToParentFrame() error
+	// SetFrameDetachedRecovery sets whether the session is switched to the
+	// top-level browsing context when a command fails with a
+	// FrameDetachedError, so that later commands do not fail the same way.
+	// The error is returned either way, with SwitchedToTop set on recovery.
+	SetFrameDetachedRecovery(enabled bool)
+	// WithinFrame switches to frame, calls fn and switches back to the parent
+	// of frame, which should be the current frame, even if fn returns an
+	// error or panics.
+	WithinFrame(frame WebElement, fn func() error) error
 	// SwitchWindow switches the context to the specified window.
 	SwitchWindow(name string) error
 	// CloseWindow closes the specified window.
 	CloseWindow(name string) error
+	// NewWindow opens a new top-level browsing context and returns its handle.
+	// typ is "tab" or "window", which the browser may treat as a hint only.
+	// The session does not switch to the new window; call SwitchWindow with
+	// the returned handle to drive it.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	NewWindow(typ string) (string, error)
 	// MaximizeWindow maximizes a window. If the name is empty, the current
 	// window will be maximized.
 	MaximizeWindow(name string) error
+	// Maximize maximizes the current window and returns its resulting rect,
+	// which shows whether the window manager honored the request.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	Maximize() (Rect, error)
+	// Minimize minimizes, i.e. iconifies, the current window and returns its
+	// resulting rect.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	Minimize() (Rect, error)
+	// Fullscreen makes the current window fullscreen, like pressing F11 in
+	// most browsers, and returns its resulting rect.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	Fullscreen() (Rect, error)
 	// ResizeWindow changes the dimensions of a window. If the name is empty, the
 	// current window will be maximized.
 	ResizeWindow(name string, width, height int) error
+	// GetWindowRect returns the position and size of the current window.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	GetWindowRect() (Rect, error)
+	// SetWindowRect sets the position and size of the current window and
+	// returns the rect that the window manager granted, which may differ from
+	// the requested one. See SetWindowRectStable to retry until they match.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	SetWindowRect(rect Rect) (Rect, error)
+	// SetWindowRectStable sets the position and size of the current window,
+	// then reads them back and sets them again until the window reports the
+	// requested rect, to within a couple of pixels, or a timeout of a few
+	// seconds elapses. This guards against window managers that apply the
+	// request late, e.g. while a maximize is settling, or adjust it. The last
+	// reported rect is returned, along with an error if it never matched.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	SetWindowRectStable(rect Rect) (Rect, error)
 
 	// Get navigates the browser to the provided URL.
 	Get(url string) error
+	// GetWithContext is like Get, but aborts the pending request and returns
+	// a *ContextError wrapping ctx.Err() when ctx is canceled or its deadline
+	// passes.
+	GetWithContext(ctx context.Context, url string) error
 	// Forward moves forward in history.
 	Forward() error
 	// Back moves backward in history.
@@ -300,8 +521,64 @@ type WebDriver interface {
 	FindElement(by, value string) (WebElement, error)
 	// FindElement finds potentially many elements in the current page's DOM.
 	FindElements(by, value string) ([]WebElement, error)
+	// FindElementsWithContext is like FindElements, but aborts the pending
+	// request and returns a *ContextError when ctx is done.
+	FindElementsWithContext(ctx context.Context, by, value string) ([]WebElement, error)
+	// Find finds exactly one element in the current page's DOM using the
+	// default strategy, which is ByCSSSelector unless set by
+	// SetDefaultStrategy.
+	Find(value string) (WebElement, error)
+	// FindAll finds potentially many elements in the current page's DOM using
+	// the default strategy.
+	FindAll(value string) ([]WebElement, error)
+	// SetDefaultStrategy sets the strategy, e.g. ByXPATH, used by Find and
+	// FindAll. An empty string restores ByCSSSelector.
+	SetDefaultStrategy(by string)
+	// FindElementsText returns the visible text of all elements in the current
+	// page's DOM that match the query. Unlike calling Text on each element
+	// returned by FindElements, the text of all elements is fetched in one
+	// request.
+	FindElementsText(by, value string) ([]string, error)
+	// FindRelativeElement finds the element that matches the relative locator
+	// and is closest to the reference element of its first constraint.
+	FindRelativeElement(l *RelativeLocator) (WebElement, error)
+	// FindRelativeElements finds the elements that match the relative
+	// locator, sorted by their distance to the reference element of its first
+	// constraint. An error is returned if a reference element is stale.
+	FindRelativeElements(l *RelativeLocator) ([]WebElement, error)
+	// ClickIfPresent clicks the first displayed element that matches the
+	// query, if any, and returns whether it clicked one. This is meant for
+	// elements that may or may not appear, such as consent banners.
+	ClickIfPresent(by, value string) (bool, error)
+	// NewSelect returns a Select to choose among the options of elem, which
+	// must be a <select> element.
+	NewSelect(elem WebElement) (*Select, error)
+
+	// ElementsInDocumentOrder reports whether a comes before b in document
+	// order, which an ancestor does before its descendants. An error is
+	// returned if the elements are not in the same document.
+	ElementsInDocumentOrder(a, b WebElement) (bool, error)
+	// TabOrder returns the elements within the element within that receive the
+	// focus when pressing the Tab key repeatedly from the start of the
+	// document, in that order. The focus is moved in the process. Elements
+	// in iframes and shadow trees are represented by the iframe and the
+	// shadow host.
+	TabOrder(within WebElement) ([]WebElement, error)
+	// ElementsInVisualOrder reports whether a comes before b in the
+	// left-to-right, top-to-bottom reading order of the rendered page: a is
+	// entirely above b, or they overlap vertically and a starts to the left of
+	// b.
+	ElementsInVisualOrder(a, b WebElement) (bool, error)
+
 	// ActiveElement returns the currently active element on the page.
 	ActiveElement() (WebElement, error)
+	// DeepActiveElement returns the element that has the focus, following
+	// the focus into nested frames, starting from the current frame. The
+	// frames it descended into are returned too, outermost first, and the
+	// session is left switched to the innermost one so that the returned
+	// element can be used; call SwitchFrame(nil) to return to the top-level
+	// document.
+	DeepActiveElement() (WebElement, []WebElement, error)
 
 	// DecodeElement decodes a single element response.
 	DecodeElement([]byte) (WebElement, error)
@@ -310,8 +587,9 @@ type WebDriver interface {
 
 	// GetCookies returns all of the cookies in the browser's jar.
 	GetCookies() ([]Cookie, error)
-	// GetCookie returns the named cookie in the jar, if present. This method is
-	// only implemented for Firefox.
+	// GetCookie returns the named cookie in the jar with the W3C "Get Named
+	// Cookie" command, or a *NoSuchCookieError if there is none. Use
+	// GetCookies to get all of the cookies.
 	GetCookie(name string) (Cookie, error)
 	// AddCookie adds a cookie to the browser's jar.
 	AddCookie(cookie *Cookie) error
@@ -319,6 +597,17 @@ type WebDriver interface {
 	DeleteAllCookies() error
 	// DeleteCookie deletes a cookie to the browser's jar.
 	DeleteCookie(name string) error
+	// ExportCookies returns all cookies, with all of their attributes, encoded
+	// as a JSON array of Cookie values, to be restored later with
+	// ImportCookies. With Chrome, the cookies of all domains are exported;
+	// otherwise only those visible to the current page are.
+	ExportCookies() ([]byte, error)
+	// ImportCookies adds the cookies in data, as returned by ExportCookies.
+	// Cookies that have expired since they were exported are skipped. Unless
+	// the browser is Chrome, which can set cookies for any domain, the browser
+	// is navigated to the domain of each cookie as needed and then back to the
+	// current page.
+	ImportCookies(data []byte) error
 
 	// Click clicks a mouse button. The button should be one of RightButton,
 	// MiddleButton or LeftButton.
@@ -329,6 +618,14 @@ type WebDriver interface {
 	ButtonDown() error
 	// ButtonUp causes the left mouse button to be released.
 	ButtonUp() error
+	// NewActions returns a builder of a sequence of mouse and keyboard
+	// actions. See Actions.
+	NewActions() *Actions
+	// ReleaseActions releases all keys and mouse buttons that are held down
+	// and clears the state of the input sources.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	ReleaseActions() error
 
 	// SendModifier sends the modifier key to the active element. The modifier
 	// can be one of ShiftKey, ControlKey, AltKey, MetaKey.
@@ -342,14 +639,250 @@ type WebDriver interface {
 	// KeyUp indicates that a previous keystroke sent by KeyDown should be
 	// released.
 	KeyUp(keys string) error
+	// HasHorizontalScroll returns true if the document is wider than the
+	// viewport, which usually indicates a layout bug.
+	HasHorizontalScroll() (bool, error)
+	// OverflowingElements returns the outermost elements that extend past the
+	// left or right edge of the viewport and so cause horizontal scrolling.
+	// Elements clipped by an ancestor with an overflow style, or fixed in
+	// place, are ignored.
+	OverflowingElements() ([]WebElement, error)
+	// DocumentCharset returns the character encoding that the browser used to
+	// decode the current document, i.e. document.characterSet, e.g. "UTF-8".
+	DocumentCharset() (string, error)
+	// ContentType returns the MIME type of the current document, i.e.
+	// document.contentType, e.g. "text/html".
+	ContentType() (string, error)
+	// SelectedText returns the text that is selected in the current frame,
+	// including within the focused text field, or an empty string if nothing
+	// is selected. A selection within an iframe is only visible after
+	// switching to that frame with SwitchFrame.
+	SelectedText() (string, error)
+	// AddStyleSheet adds a style sheet with the provided CSS rules to the
+	// current page, e.g. to hide dynamic content or disable animations before
+	// taking a screenshot, and returns an ID for RemoveStyleSheet. The style
+	// sheet is lost when the page is navigated away from. In Chrome, it is
+	// added through the DevTools Protocol, which bypasses the page's Content
+	// Security Policy; other browsers get a <style> element.
+	AddStyleSheet(css string) (string, error)
+	// RemoveStyleSheet removes the rules of a style sheet added by
+	// AddStyleSheet.
+	RemoveStyleSheet(id string) error
+	// ViewportSize returns the size of the viewport of the current page in
+	// CSS pixels, i.e. window.innerWidth and window.innerHeight, which is the
+	// size that media queries are evaluated against. Unlike the window size,
+	// it excludes the browser's toolbars and borders.
+	ViewportSize() (width, height int, err error)
+	// UserAgent returns the user agent reported by the browser to the current
+	// page, i.e. navigator.userAgent, which reflects any overrides.
+	UserAgent() (string, error)
 	// Screenshot takes a screenshot of the browser window.
 	Screenshot() ([]byte, error)
+	// LocalStorage returns the local storage area of the origin of the current
+	// page. Its methods act on the page that is current when they are called.
+	LocalStorage() Storage
+	// SessionStorage returns the session storage area of the current page.
+	// Its methods act on the page that is current when they are called.
+	SessionStorage() Storage
+	// Print renders the current page as a PDF document, with the W3C "Print
+	// Page" command, and returns it. Chrome and Firefox only support printing
+	// in headless mode.
+	Print(opts PrintOptions) ([]byte, error)
+	// FullPageScreenshot takes a PNG screenshot of the whole page, including
+	// the content outside of the viewport. Chrome captures the page directly,
+	// without resizing the viewport; other browsers scroll through the page
+	// and the screenshots of the viewport are combined, in which case elements
+	// with a fixed position appear repeatedly.
+	FullPageScreenshot() ([]byte, error)
+	// ScreenshotRegion takes a PNG screenshot of the region rect of the page,
+	// in CSS pixels relative to the top-left corner of the document. Chrome
+	// captures the region directly; other browsers crop it out of a
+	// screenshot of the viewport, or of FullPageScreenshot if the region is
+	// not entirely in view.
+	ScreenshotRegion(rect Rect) ([]byte, error)
+	// SetInteractionRecorder enables recording of element interactions into
+	// the directory dir, which is created if needed. While enabled, a
+	// screenshot is saved before and after each WebElement Click and SendKeys
+	// call, with the element outlined in the former, and the steps are listed
+	// in the JSON file "manifest.json" in dir. An empty dir disables the
+	// recorder.
+	SetInteractionRecorder(dir string) error
+	// SetCommandRecorder sets the recorder of the commands sent to the remote
+	// end, or disables recording if r is nil.
+	SetCommandRecorder(r *CommandRecorder)
+	// SetHTTPClient sets the client used to send the following commands to
+	// the WebDriver server, or restores HTTPClient if client is nil. It only
+	// affects the connection to the server, not the traffic of the browser.
+	// Use NewRemoteWithHTTPClient to also create the session with client.
+	SetHTTPClient(client *http.Client)
+	// SetActionLog starts appending the navigations made with Get and the
+	// successful WebElement Click and SendKeys calls to log, or stops if log
+	// is nil. Elements are identified by the locators that found them, so
+	// interactions with elements returned by scripts cannot be replayed, and
+	// locators are relative to the frame that was current when the element
+	// was found.
+	SetActionLog(log *ActionLog)
+	// Replay re-executes the actions of log in the current session, finding
+	// the elements again by their original locators. It stops at the first
+	// action that fails. The replayed actions are not recorded.
+	Replay(log ActionLog) error
+	// Timed calls fn and returns how long it took, along with its error, e.g.
+	// to measure a user action against a performance budget. If a command
+	// recorder is set, the block is recorded as a span named name, and the
+	// commands sent within it are tagged with the name.
+	Timed(name string, fn func() error) (time.Duration, error)
 	// Log fetches the logs. Log types must be previously configured in the
 	// capabilities.
 	//
 	// NOTE: will return an error (not implemented) on IE11 or Edge drivers.
 	Log(typ log.Type) ([]log.Message, error)
 
+	// StartEventRecording starts a new window for recording the Chrome
+	// DevTools events that back methods such as Downloads, discarding all
+	// events recorded so far. Events are read from the performance log, which
+	// must be enabled when creating the session, e.g. via
+	// caps.SetLogLevel(log.Performance, log.All). Fetching the performance log
+	// via the Log method consumes the events it returns. Only the events used
+	// by these methods are kept, and only the latest 10000 of them, so
+	// long-running sessions should start new windows regularly.
+	//
+	// This method is only supported by Chrome.
+	StartEventRecording() error
+	// Downloads returns the downloads started by the browser during the
+	// current event recording window. See StartEventRecording. Recent versions
+	// of Chrome only report the progress of downloads once download events are
+	// enabled, e.g. with the ExecuteCDPCmd "Browser.setDownloadBehavior" and
+	// the parameters {"behavior": "allow", "downloadPath": dir,
+	// "eventsEnabled": true}. This is left to the caller, as it replaces the
+	// download behavior of the browser.
+	//
+	// This method is only supported by Chrome.
+	Downloads() ([]Download, error)
+	// AssertNoRequestsMatching returns an error that lists the network
+	// requests sent during the current event recording window whose URL
+	// matches the regular expression pattern, if there are any. See
+	// StartEventRecording.
+	//
+	// This method is only supported by Chrome.
+	AssertNoRequestsMatching(pattern string) error
+	// MixedContentWarnings returns a description of each network request sent
+	// during the current event recording window that loaded insecure content
+	// into a secure page, or that the browser blocked for security reasons,
+	// e.g. because of the Content Security Policy. Each description starts
+	// with the URL of the request. See StartEventRecording.
+	//
+	// This method is only supported by Chrome.
+	MixedContentWarnings() ([]string, error)
+	// WaitForRequest waits until a network request whose URL matches the
+	// regular expression urlPattern completes, successfully or not, and
+	// returns its details. Only the requests sent during the current event
+	// recording window are considered, so start one before triggering the
+	// request, and each request is only returned once, so that calling it
+	// again waits for the next matching request. See StartEventRecording.
+	//
+	// This method is only supported by Chrome.
+	WaitForRequest(urlPattern string, timeout time.Duration) (NetworkRequest, error)
+
+	// FreezeTime makes Date, Date.now and performance.now report the time t,
+	// which does not advance, in the current page and every page loaded
+	// afterwards, before their own scripts run. Timers are not affected.
+	//
+	// This method is only supported by Chrome.
+	FreezeTime(t time.Time) error
+	// SeedRandom replaces Math.random with a pseudo-random generator seeded
+	// with seed in the current page and every page loaded afterwards, before
+	// their own scripts run, so that each page gets the same sequence of
+	// numbers.
+	//
+	// This method is only supported by Chrome.
+	SeedRandom(seed int64) error
+
+	// StartLayoutShiftCapture starts recording the layout shifts of the
+	// current page and of every page loaded afterwards, for
+	// CumulativeLayoutShift. It must be called before loading the page to
+	// measure, so that the shifts that happen while it loads are included.
+	//
+	// This method is only supported by Chrome.
+	StartLayoutShiftCapture() error
+	// CumulativeLayoutShift returns the Cumulative Layout Shift (CLS) of the
+	// current page so far, i.e. the sum of the scores of its layout shifts
+	// that were not caused by recent user input. See StartLayoutShiftCapture.
+	//
+	// This method is only supported by Chrome.
+	CumulativeLayoutShift() (float64, error)
+
+	// StartConsoleCapture starts a new window for recording the calls that
+	// pages make to the console API and their uncaught exceptions, with their
+	// stack traces, discarding the messages recorded so far. The messages are
+	// recorded by ChromeDriver, so they are kept across navigations, but only
+	// if the session was created with ConsoleEvents as the
+	// DevToolsEventsToLog of its chrome.Capabilities. Fetching the
+	// log.DevTools log via the Log method consumes the messages it returns.
+	//
+	// This method is only supported by Chrome.
+	StartConsoleCapture() error
+	// ConsoleMessages returns and clears the console messages recorded since
+	// StartConsoleCapture or ConsoleMessages was last called, in all pages.
+	//
+	// This method is only supported by Chrome.
+	ConsoleMessages() ([]ConsoleMessage, error)
+	// AssertNoConsoleErrors reads and clears the console messages like
+	// ConsoleMessages, including those of pages navigated away from and
+	// uncaught exceptions, which have the level "error", and returns a
+	// *ConsoleMessagesError listing those whose level is at or above
+	// minSeverity, one of "debug", "log", "info", "warn" or "error". For
+	// example, "error" ignores warnings but not failed assertions. Console
+	// capture must have been started with StartConsoleCapture.
+	//
+	// This method is only supported by Chrome.
+	AssertNoConsoleErrors(minSeverity string) error
+	// EmulatePrintMedia sets whether pages are rendered with the "print"
+	// media type, as when printing, instead of "screen", so that print
+	// stylesheets can be inspected or screenshotted without printing.
+	//
+	// This method is only supported by Chrome.
+	EmulatePrintMedia(enabled bool) error
+	// SetCacheDisabled sets whether the browser's HTTP cache is bypassed for
+	// all requests, e.g. to test cold loads, until it is enabled again.
+	//
+	// This method is only supported by Chrome.
+	SetCacheDisabled(disabled bool) error
+	// SetGeolocation overrides the position reported by the Geolocation API
+	// to latitude lat and longitude lon, in degrees, with the given accuracy,
+	// in meters, until ClearGeolocation is called. Pages still need the
+	// geolocation permission to read it.
+	//
+	// This method is only supported by Chrome.
+	SetGeolocation(lat, lon, accuracy float64) error
+	// ClearGeolocation removes the override set by SetGeolocation.
+	//
+	// This method is only supported by Chrome.
+	ClearGeolocation() error
+	// JSHeapSize returns the used and total size, in bytes, of the JavaScript
+	// heap of the current page, e.g. to detect memory leaks over repeated
+	// actions.
+	//
+	// This method is only supported by Chrome.
+	JSHeapSize() (used, total int64, err error)
+	// CreateIncognitoContext creates a new incognito browser context and
+	// returns its identifier. Browser contexts do not share cookies or storage
+	// with each other, allowing for isolated scenarios within one browser.
+	//
+	// This method is only supported by Chrome.
+	CreateIncognitoContext() (string, error)
+	// NewWindowInContext opens a new window in the browser context with the
+	// given identifier, navigates it to url and returns its handle. An empty
+	// url opens a blank page. The current window is not changed; use
+	// SwitchWindow to drive the new window.
+	//
+	// This method is only supported by Chrome.
+	NewWindowInContext(contextID, url string) (string, error)
+	// DisposeBrowserContext closes the browser context with the given
+	// identifier and all of its windows.
+	//
+	// This method is only supported by Chrome.
+	DisposeBrowserContext(contextID string) error
+
 	// DismissAlert dismisses current alert.
 	DismissAlert() error
 	// AcceptAlert accepts the current alert.
@@ -359,18 +892,49 @@ type WebDriver interface {
 	// SetAlertText sets the current alert text.
 	SetAlertText(text string) error
 
+	// SetElementFactory sets a function that is called with every element
+	// constructed by the client, e.g. by FindElement, FindElements,
+	// ActiveElement or DecodeElement, and whose result is returned in its
+	// place. This allows decorating elements, e.g. for page objects. A nil
+	// factory removes it.
+	//
+	// While a factory is set, element references in the results of
+	// ExecuteScript and ExecuteScriptAsync are also converted to elements.
+	//
+	// Elements are passed to the browser, e.g. as script arguments, by
+	// encoding them as JSON, so the returned element must encode like base. A
+	// wrapper can implement json.Marshaler by returning json.Marshal(base).
+	SetElementFactory(factory func(base WebElement) WebElement)
+
 	// ExecuteScript executes a script.
 	ExecuteScript(script string, args []interface{}) (interface{}, error)
 	// ExecuteScriptAsync asynchronously executes a script.
 	ExecuteScriptAsync(script string, args []interface{}) (interface{}, error)
+	// ExecuteScriptAsyncWithContext is like ExecuteScriptAsync, but aborts the
+	// pending request and returns a *ContextError when ctx is done.
+	ExecuteScriptAsyncWithContext(ctx context.Context, script string, args []interface{}) (interface{}, error)
 
 	// ExecuteScriptRaw executes a script but does not perform JSON decoding.
 	ExecuteScriptRaw(script string, args []interface{}) ([]byte, error)
 	// ExecuteScriptAsyncRaw asynchronously executes a script but does not
 	// perform JSON decoding.
 	ExecuteScriptAsyncRaw(script string, args []interface{}) ([]byte, error)
+	// ExecuteScriptRawValue executes a script and returns its result, the
+	// "value" field of the reply, without decoding it, e.g. to unmarshal it
+	// into a struct without the float64 conversion of numbers done by
+	// ExecuteScript.
+	ExecuteScriptRawValue(script string, args []interface{}) (json.RawMessage, error)
+	// ExecuteScriptAsyncRawValue is like ExecuteScriptRawValue for an
+	// asynchronous script.
+	ExecuteScriptAsyncRawValue(script string, args []interface{}) (json.RawMessage, error)
 
 	// WaitWithTimeoutAndInterval waits for the condition to evaluate to true.
+	// If the condition returns a "no such element" or "stale element
+	// reference" error, it is treated as not yet satisfied and polling
+	// continues; the last such error is reported on timeout. The conditions
+	// of this package, such as ElementIsVisible or URLContains, likewise
+	// report the state they last observed, e.g. the current URL. Any other
+	// error aborts the wait.
 	WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error
 
 	// WaitWithTimeout works like WaitWithTimeoutAndInterval, but with default polling interval.
@@ -378,18 +942,122 @@ type WebDriver interface {
 
 	//Wait works like WaitWithTimeoutAndInterval, but using the default timeout and polling interval.
 	Wait(condition Condition) error
+
+	// WaitForImages waits until all images in the current document have
+	// finished loading, i.e. they are complete and have a non-zero natural
+	// width. Images that fail to load cause the wait to time out; the returned
+	// error lists the images that did not load.
+	WaitForImages(timeout time.Duration) error
+
+	// GetElement waits until an element that matches the query is found and
+	// can be used, i.e. its reference does not go stale right away, and
+	// returns it. Elements that are replaced while the page renders, as in
+	// single-page applications, are looked up again until the timeout.
+	GetElement(by, value string, timeout time.Duration) (WebElement, error)
+
+	// WaitForTitle waits until the current page's title is equal to title. If
+	// the timeout expires, the returned error includes the actual title.
+	WaitForTitle(title string, timeout time.Duration) error
+	// WaitForTitleContains waits until the current page's title contains
+	// substr. If the timeout expires, the returned error includes the actual
+	// title.
+	WaitForTitleContains(substr string, timeout time.Duration) error
+}
+
+// ChromeWebDriver extends WebDriver with methods that are specific to
+// ChromeDriver. The WebDriver returned by NewRemote implements it, e.g.:
+//
+//	cwd := wd.(selenium.ChromeWebDriver)
+//	result, err := cwd.ExecuteCDPCmd("Browser.getVersion", nil)
+type ChromeWebDriver interface {
+	WebDriver
+
+	// ExecuteCDPCmd executes a Chrome DevTools Protocol command, such as
+	// "Network.clearBrowserCache", with the provided parameters and returns
+	// its result. See https://chromedevtools.github.io/devtools-protocol/ for
+	// the available commands.
+	//
+	// This method only works against ChromeDriver sessions and returns an
+	// *UnsupportedError otherwise.
+	ExecuteCDPCmd(command string, params map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Storage is a web storage area, as returned by WebDriver.LocalStorage and
+// WebDriver.SessionStorage. Its methods return an error if the page cannot
+// access the area, such as a SecurityError for pages loaded from file:// URLs
+// in some browsers.
+type Storage interface {
+	// GetItem returns the value stored under key, and whether there is one.
+	GetItem(key string) (string, bool, error)
+	// SetItem stores value under key.
+	SetItem(key, value string) error
+	// RemoveItem removes the value stored under key, if any.
+	RemoveItem(key string) error
+	// Clear removes all values.
+	Clear() error
+	// Keys returns the keys of the stored values, in the browser's order.
+	Keys() ([]string, error)
+	// Len returns the number of stored values.
+	Len() (int, error)
+}
+
+// ShadowRoot is the shadow root of an element, as returned by
+// WebElement.GetShadowRoot. Its elements can only be found by the CSS
+// selector, ID and name strategies in some browsers, such as Chrome.
+type ShadowRoot interface {
+	// FindElement finds a child element of the shadow root.
+	FindElement(by, value string) (WebElement, error)
+	// FindElements finds multiple children elements of the shadow root.
+	FindElements(by, value string) ([]WebElement, error)
 }
 
 // WebElement defines method supported by web elements.
 type WebElement interface {
 	// Click clicks on the element.
 	Click() error
+	// ClickNoScroll clicks the center of the element at its current position
+	// in the viewport, without first scrolling it into view. This avoids the
+	// element being covered by e.g. sticky headers after the implicit scroll
+	// that Click performs. The element must already be visible in the viewport.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	ClickNoScroll() error
 	// SendKeys types into the element.
 	SendKeys(keys string) error
+	// ReplaceText replaces the text of the focusable element by selecting all
+	// of it and typing text over the selection, in a single sequence of key
+	// actions. Unlike Clear followed by SendKeys, the field never becomes
+	// empty in between, which would trigger validation on some pages.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	ReplaceText(text string) error
+	// DropFile simulates dropping the local file at path onto the element, as
+	// expected by upload widgets that only accept dragged files. The file is
+	// sent to the browser, which dispatches "dragenter", "dragover" and "drop"
+	// events carrying it. This requires a browser that supports constructing
+	// DataTransfer objects, such as Chrome.
+	DropFile(path string) error
+	// SetValueJS sets the value property of the element with JavaScript and
+	// dispatches "input" and "change" events, so that the page's listeners
+	// see the change. This is a fallback for inputs that do not accept
+	// SendKeys, such as some masked or custom fields.
+	SetValueJS(value string) error
+	// SetDate sets the value of an <input type="date"> to the date of t, in
+	// the "yyyy-mm-dd" format of the HTML specification, like SetValueJS.
+	// Unlike SendKeys, this does not depend on the locale of the browser.
+	SetDate(t time.Time) error
+	// SetTime sets the value of an <input type="time"> to the time of day of
+	// t, in the "HH:MM" format of the HTML specification, or "HH:MM:SS" if t
+	// has seconds, like SetValueJS.
+	SetTime(t time.Time) error
 	// Submit submits the button.
 	Submit() error
 	// Clear clears the element.
 	Clear() error
+	// ScrollBy scrolls the content of the element, which must be a scrollable
+	// container, by dx and dy pixels, e.g. to make a virtualized list render
+	// more rows. Scrolling stops at the edges of the content.
+	ScrollBy(dx, dy int) error
 	// MoveTo moves the mouse to relative coordinates from center of element, If
 	// the element is not visible, it will be scrolled into view.
 	MoveTo(xOffset, yOffset int) error
@@ -409,6 +1077,30 @@ type WebElement interface {
 	IsEnabled() (bool, error)
 	// IsDisplayed returns true if the element is displayed.
 	IsDisplayed() (bool, error)
+	// IsInViewport returns true if any part of the element is within the
+	// current viewport. Unlike LocationInView, the element is not scrolled into
+	// view. Elements without a size are never in the viewport.
+	IsInViewport() (bool, error)
+	// VisibilityDetails returns the properties of the element that determine
+	// whether it can be seen, such as its effective opacity and whether
+	// another element covers it, in a single round trip. It helps to find out
+	// why an element that IsDisplayed reports as displayed cannot be seen.
+	VisibilityDetails() (VisibilityInfo, error)
+	// LabelText returns the label of the element, such as a form field, as
+	// the accessible name computation resolves it: the text of the elements
+	// referenced by aria-labelledby, else the aria-label attribute, else the
+	// text of the <label> elements associated through their "for" attribute
+	// or by wrapping the element. Whitespace is collapsed. The empty string
+	// is returned if the element has no label.
+	LabelText() (string, error)
+	// ValidityState returns the HTML5 constraint validation state of the
+	// element, which must be a form field such as an input, select or
+	// textarea.
+	ValidityState() (ValidityState, error)
+	// ValidationMessage returns the message that the browser shows when the
+	// form field does not satisfy its constraints, or the empty string if it
+	// does. The message is localized by the browser.
+	ValidationMessage() (string, error)
 	// GetAttribute returns the named HTML attribute of the element.
 	GetAttribute(name string) (string, error)
 	// GetProperty returns the DOM property of the element. The DOM property
@@ -421,9 +1113,44 @@ type WebElement interface {
 	LocationInView() (*Point, error)
 	// Size returns the element's size.
 	Size() (*Size, error)
+	// Center returns the center of the element in viewport coordinates,
+	// rounded to the nearest pixel, as used by pointer actions with a
+	// "viewport" origin. The element is not scrolled into view; call
+	// LocationInView first if needed.
+	Center() (*Point, error)
+	// CSSVariable returns the computed value of the CSS custom property name,
+	// with or without its leading "--", e.g. "primary-color", as inherited by
+	// the element. An empty string is returned if the property is not set.
+	CSSVariable(name string) (string, error)
+	// GetShadowRoot returns the open shadow root attached to the element,
+	// whose elements can be found like those of the document. Elements found
+	// in a shadow root have GetShadowRoot too, so nested shadow roots can be
+	// traversed to any depth. A *NoSuchShadowRootError is returned if the
+	// element has no open shadow root.
+	//
+	// This method is only supported by W3C-compatible sessions.
+	GetShadowRoot() (ShadowRoot, error)
 	// CSSProperty returns the value of the specified CSS property of the
 	// element.
 	CSSProperty(name string) (string, error)
+	// WaitUntilStable waits until the element has stopped moving, i.e. until
+	// its rect is the same in two consecutive samples taken DefaultWaitInterval
+	// apart, for example before clicking an element that animates into place.
+	WaitUntilStable(timeout time.Duration) error
+	// WaitForAnimations waits until no CSS animations or transitions, nor
+	// animations started with the Web Animations API, are running on the
+	// element or its descendants. Unlike WaitUntilStable, it also waits for
+	// animations that do not move the element, such as those of its opacity
+	// or color. Animations that run forever never finish. If the timeout
+	// expires, the returned error names the animations still running.
+	//
+	// An *UnsupportedError is returned if the browser does not implement
+	// Element.getAnimations.
+	WaitForAnimations(timeout time.Duration) error
 	// Screenshot takes a screenshot of the attribute scroll'ing if necessary.
 	Screenshot(scroll bool) ([]byte, error)
+	// Highlight outlines the element in the page for a short while, as set by
+	// SetHighlight, to show which element a test interacts with. The element's
+	// style is restored afterwards.
+	Highlight() error
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/golang/glog"
+	"github.com/google/go-cmp/cmp"
 	"github.com/tebeka/selenium"
 	"github.com/tebeka/selenium/internal/seleniumtest"
 )
@@ -107,6 +108,69 @@ func pickUnusedPort() (int, error) {
 	return port, nil
 }
 
+func TestSetSeleniumOptions(t *testing.T) {
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	caps.SetSeleniumOptions(selenium.SeleniumGridOptions{
+		RecordVideo: true,
+		TestName:    "login flow",
+		BuildName:   "build-123",
+	})
+	want := selenium.Capabilities{
+		"browserName":    "chrome",
+		"se:recordVideo": true,
+		"se:name":        "login flow",
+		"se:build":       "build-123",
+	}
+	if diff := cmp.Diff(want, caps); diff != "" {
+		t.Fatalf("caps.SetSeleniumOptions() returned diff (-want/+got):\n%s", diff)
+	}
+
+	// Resetting the options removes the capabilities.
+	caps.SetSeleniumOptions(selenium.SeleniumGridOptions{})
+	if diff := cmp.Diff(selenium.Capabilities{"browserName": "chrome"}, caps); diff != "" {
+		t.Fatalf("caps.SetSeleniumOptions() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func TestSetUnhandledPromptBehavior(t *testing.T) {
+	for _, tc := range []struct {
+		in         selenium.PromptBehavior
+		wantLegacy string
+	}{
+		{selenium.AcceptPrompt, "accept"},
+		{selenium.DismissAndNotifyPrompt, "dismiss"},
+		{selenium.IgnorePrompt, "ignore"},
+	} {
+		caps := selenium.Capabilities{}
+		caps.SetUnhandledPromptBehavior(tc.in)
+		want := selenium.Capabilities{
+			"unhandledPromptBehavior":  string(tc.in),
+			"unexpectedAlertBehaviour": tc.wantLegacy,
+		}
+		if diff := cmp.Diff(want, caps); diff != "" {
+			t.Errorf("caps.SetUnhandledPromptBehavior(%q) returned diff (-want/+got):\n%s", tc.in, diff)
+		}
+	}
+}
+
+func TestMozProcessID(t *testing.T) {
+	for _, tc := range []struct {
+		caps    selenium.Capabilities
+		wantPID int
+		wantOK  bool
+	}{
+		{selenium.Capabilities{"moz:processID": float64(1234)}, 1234, true},
+		{selenium.Capabilities{"moz:processID": 1234}, 1234, true},
+		{selenium.Capabilities{"moz:processID": "1234"}, 0, false},
+		{selenium.Capabilities{}, 0, false},
+	} {
+		pid, ok := tc.caps.MozProcessID()
+		if pid != tc.wantPID || ok != tc.wantOK {
+			t.Errorf("%v.MozProcessID() = %d, %t, want %d, %t", tc.caps, pid, ok, tc.wantPID, tc.wantOK)
+		}
+	}
+}
+
 func TestChrome(t *testing.T) {
 	if *useDocker {
 		t.Skip("Skipping Chrome tests because they will be run under a Docker container")
@@ -196,12 +196,37 @@ func NewSeleniumService(jarPath string, port int, opts ...ServiceOption) (*Servi
 
 // NewChromeDriverService starts a ChromeDriver instance in the background.
 func NewChromeDriverService(path string, port int, opts ...ServiceOption) (*Service, error) {
+	return startChromiumDriverService(path, port, opts...)
+}
+
+// NewEdgeDriverService starts a Microsoft Edge WebDriver (msedgedriver)
+// instance in the background. Like ChromeDriver, on which it is based, it
+// serves the WebDriver API under "/wd/hub" and logs verbosely to the writer
+// set by Output.
+func NewEdgeDriverService(path string, port int, opts ...ServiceOption) (*Service, error) {
+	return startChromiumDriverService(path, port, opts...)
+}
+
+// newChromiumDriverService returns a service that runs the driver binary at
+// path, which must be ChromeDriver or a driver derived from it, such as
+// msedgedriver, as they take the same flags. The service is not started.
+func newChromiumDriverService(path string, port int, opts ...ServiceOption) (*Service, error) {
 	cmd := exec.Command(path, "--port="+strconv.Itoa(port), "--url-base=wd/hub", "--verbose")
 	s, err := newService(cmd, "/wd/hub", port, opts...)
 	if err != nil {
 		return nil, err
 	}
 	s.shutdownURLPath = "/shutdown"
+	return s, nil
+}
+
+// startChromiumDriverService is like newChromiumDriverService, but starts the
+// service.
+func startChromiumDriverService(path string, port int, opts ...ServiceOption) (*Service, error) {
+	s, err := newChromiumDriverService(path, port, opts...)
+	if err != nil {
+		return nil, err
+	}
 	if err := s.start(port); err != nil {
 		return nil, err
 	}
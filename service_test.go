@@ -145,3 +145,22 @@ func TestFrameBuffer(t *testing.T) {
 		}
 	})
 }
+
+func TestChromiumDriverService(t *testing.T) {
+	for _, path := range []string{"/usr/bin/chromedriver", "/usr/bin/msedgedriver"} {
+		s, err := newChromiumDriverService(path, 9515)
+		if err != nil {
+			t.Fatalf("newChromiumDriverService(%q, 9515) returned error: %v", path, err)
+		}
+		want := []string{path, "--port=9515", "--url-base=wd/hub", "--verbose"}
+		if diff := cmp.Diff(want, s.cmd.Args); diff != "" {
+			t.Errorf("newChromiumDriverService(%q, 9515) built a different command line (-want +got):\n%s", path, diff)
+		}
+		if got, want := s.addr, "http://localhost:9515/wd/hub"; got != want {
+			t.Errorf("newChromiumDriverService(%q, 9515) serves at %q, want %q", path, got, want)
+		}
+		if got, want := s.shutdownURLPath, "/shutdown"; got != want {
+			t.Errorf("newChromiumDriverService(%q, 9515) has shutdown path %q, want %q", path, got, want)
+		}
+	}
+}
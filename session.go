@@ -0,0 +1,132 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// sessionState is the JSON representation of a session produced by
+// MarshalSession.
+type sessionState struct {
+	ID             string       `json:"id"`
+	URLPrefix      string       `json:"urlPrefix"`
+	W3C            bool         `json:"w3c"`
+	Browser        string       `json:"browser,omitempty"`
+	BrowserVersion string       `json:"browserVersion,omitempty"`
+	Capabilities   Capabilities `json:"capabilities,omitempty"`
+}
+
+func (wd *remoteWD) MarshalSession() ([]byte, error) {
+	state := sessionState{
+		ID:           wd.id,
+		URLPrefix:    wd.urlPrefix,
+		W3C:          wd.w3cCompatible,
+		Browser:      wd.browser,
+		Capabilities: wd.negotiated,
+	}
+	if wd.browserVersion.Major != 0 || wd.browserVersion.Minor != 0 || wd.browserVersion.Patch != 0 {
+		state.BrowserVersion = wd.browserVersion.String()
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalSession returns a WebDriver that drives the session described by
+// data, as returned by MarshalSession, e.g. in another process. The session is
+// not contacted, so an error is only returned for malformed data; a session
+// that has since ended will cause the WebDriver's methods to fail.
+//
+// The serialized session includes the URL of the remote end, along with any
+// credentials that it contains.
+func UnmarshalSession(data []byte) (WebDriver, error) {
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.ID == "" || state.URLPrefix == "" {
+		return nil, errors.New("serialized session is missing the session ID or URL")
+	}
+	wd := &remoteWD{
+		id:            state.ID,
+		urlPrefix:     state.URLPrefix,
+		w3cCompatible: state.W3C,
+		browser:       state.Browser,
+		negotiated:    state.Capabilities,
+	}
+	if state.BrowserVersion != "" {
+		v, err := parseVersion(state.BrowserVersion)
+		if err != nil {
+			return nil, err
+		}
+		wd.browserVersion = v
+	}
+	return wd, nil
+}
+
+// ConnectToSession returns a WebDriver that drives the existing session with
+// the given ID at the remote end at urlPrefix, instead of creating a new one,
+// e.g. to quit the browser of a test that crashed or to reuse a browser
+// across test binaries. caps should be the capabilities of the session, from
+// which the browser name and version are taken; they may be nil. If urlPrefix
+// is empty, DefaultURLPrefix is used.
+//
+// The session is contacted to check that it exists and whether it follows the
+// W3C specification. caps are not taken as the capabilities that the remote end
+// granted, so the Capabilities method asks the remote end for them, which not
+// all of them support.
+func ConnectToSession(urlPrefix, sessionID string, caps Capabilities) (WebDriver, error) {
+	return ConnectToSessionWithHTTPClient(urlPrefix, sessionID, caps, nil)
+}
+
+// ConnectToSessionWithHTTPClient is like ConnectToSession, but sends the
+// commands of the session, starting with the check that it exists, with
+// client instead of HTTPClient. If client is nil, HTTPClient is used.
+func ConnectToSessionWithHTTPClient(urlPrefix, sessionID string, caps Capabilities, client *http.Client) (WebDriver, error) {
+	if urlPrefix == "" {
+		urlPrefix = DefaultURLPrefix
+	}
+	if sessionID == "" {
+		return nil, errors.New("session ID is empty")
+	}
+	wd := &remoteWD{
+		id:           sessionID,
+		urlPrefix:    strings.TrimSuffix(urlPrefix, "/"),
+		capabilities: caps,
+		httpClient:   client,
+	}
+	if b, ok := caps["browserName"].(string); ok {
+		wd.browser = b
+	}
+	for _, key := range []string{"browserVersion", "version"} {
+		if s, ok := caps[key].(string); ok && s != "" {
+			if v, err := parseVersion(s); err == nil {
+				wd.browserVersion = v
+			}
+		}
+	}
+
+	// The "Get Window Handle" command is only at this path in the W3C
+	// specification. It fails in live sessions whose window was closed, but
+	// with an error in the W3C format.
+	_, err := wd.execute("GET", wd.requestURL("/session/%s/window", wd.id), nil)
+	switch {
+	case errorIs(err, "invalid session id") || errorIs(err, "invalid session ID"):
+		return nil, fmt.Errorf("connecting to session %q: %v", sessionID, err)
+	case err == nil || isW3CError(err):
+		wd.w3cCompatible = true
+	default:
+		if _, lerr := wd.execute("GET", wd.requestURL("/session/%s/window_handle", wd.id), nil); lerr != nil {
+			return nil, fmt.Errorf("connecting to session %q: %v", sessionID, err)
+		}
+	}
+	return wd, nil
+}
+
+// isW3CError reports whether err was reported by the remote end in the format
+// of the W3C specification, for a command that the remote end implements.
+func isW3CError(err error) bool {
+	e, ok := asError(err)
+	return ok && e.LegacyCode == 0 && e.Err != "unknown command" && e.Err != "unknown method"
+}
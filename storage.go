@@ -0,0 +1,121 @@
+package selenium
+
+import "fmt"
+
+// storageScript performs an operation on the web storage area of the current
+// page named by the first argument, "localStorage" or "sessionStorage". The
+// second argument is the operation and the others are its key and value. It
+// returns an object with either the result, as "value", or the error thrown
+// by the browser, as "exception", e.g. a SecurityError for file:// URLs. If
+// the area cannot be used in the page at all, "unavailable" is set as well.
+// The field is not named "error", as the reply would then be taken for a W3C
+// error reply.
+const storageScript = `
+var area = arguments[0], op = arguments[1], key = arguments[2], value = arguments[3];
+try {
+  var s = window[area];
+  if (!s) {
+    return {exception: area + ' is not supported', unavailable: true};
+  }
+  switch (op) {
+  case 'get':
+    return {value: s.getItem(key)};
+  case 'set':
+    s.setItem(key, value);
+    return {};
+  case 'remove':
+    s.removeItem(key);
+    return {};
+  case 'clear':
+    s.clear();
+    return {};
+  case 'keys':
+    var keys = [];
+    for (var i = 0; i < s.length; i++) {
+      keys.push(s.key(i));
+    }
+    return {value: keys};
+  case 'len':
+    return {value: s.length};
+  }
+  return {exception: 'unknown operation ' + op};
+} catch (e) {
+  return {exception: e.name + ': ' + e.message, unavailable: e.name === 'SecurityError'};
+}
+`
+
+// remoteStorage is a web storage area of the current page of a remote
+// session.
+type remoteStorage struct {
+	wd *remoteWD
+	// area is the name of the window property of the area.
+	area string
+}
+
+func (wd *remoteWD) LocalStorage() Storage {
+	return &remoteStorage{wd: wd, area: "localStorage"}
+}
+
+func (wd *remoteWD) SessionStorage() Storage {
+	return &remoteStorage{wd: wd, area: "sessionStorage"}
+}
+
+// do runs the operation op of storageScript and decodes its result into v, if
+// not nil.
+func (s *remoteStorage) do(op string, args []interface{}, v interface{}) error {
+	reply := struct {
+		Value       interface{}
+		Exception   string
+		Unavailable bool
+	}{Value: v}
+	if err := s.wd.execScriptInto(storageScript, append([]interface{}{s.area, op}, args...), &reply); err != nil {
+		return err
+	}
+	switch {
+	case reply.Unavailable:
+		return fmt.Errorf("%s is not available in the current page: %s", s.area, reply.Exception)
+	case reply.Exception != "":
+		// E.g. a QuotaExceededError when setting an item.
+		return fmt.Errorf("%s: %s", s.area, reply.Exception)
+	}
+	return nil
+}
+
+func (s *remoteStorage) GetItem(key string) (string, bool, error) {
+	var value *string
+	if err := s.do("get", []interface{}{key}, &value); err != nil {
+		return "", false, err
+	}
+	if value == nil {
+		return "", false, nil
+	}
+	return *value, true, nil
+}
+
+func (s *remoteStorage) SetItem(key, value string) error {
+	return s.do("set", []interface{}{key, value}, nil)
+}
+
+func (s *remoteStorage) RemoveItem(key string) error {
+	return s.do("remove", []interface{}{key}, nil)
+}
+
+func (s *remoteStorage) Clear() error {
+	return s.do("clear", nil, nil)
+}
+
+func (s *remoteStorage) Keys() ([]string, error) {
+	var keys []string
+	if err := s.do("keys", nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *remoteStorage) Len() (int, error) {
+	var n int
+	if err := s.do("len", nil, &n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}